@@ -0,0 +1,210 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logFile = "audit.jsonl"
+	// maxSize of the log file, it is rotated once a write would grow it past, see Log.maxSize
+	maxSize = 10 << 20 // 10 MB
+	// backups kept by the rotation, audit.1.jsonl being the latest, the oldest is deleted
+	backups = 5
+)
+
+var (
+	mu     sync.Mutex
+	opened *Log
+)
+
+// Entry is a request served by any of the shares, as recorded in the audit log.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Instance string    `json:"instance,omitempty"`
+	IP       string    `json:"ip"`
+	// ReqBy is the X-Requested-By header value, empty for browsers
+	ReqBy  string `json:"reqBy,omitempty"`
+	Method string `json:"method"`
+	// Route is the pattern the request matched, e.g. "GET /{id}", Path is the one requested
+	Route string `json:"route"`
+	Path  string `json:"path"`
+	// File downloaded, uploaded or previewed, if any
+	File       string `json:"file,omitempty"`
+	Bytes      int64  `json:"bytes"`
+	Status     int    `json:"status"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Log is an append-only log of JSON lines, rotated once it grows past maxSize, it is shared by all the
+// shares, entries are written as they are served, so nothing is dropped or lost once the app exits.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	size int64
+	// maxSize is the size the log is rotated at, the const maxSize, except in the tests
+	maxSize int64
+}
+
+// Open returns the audit log under config.GetDir(), it is opened once & shared by all the callers.
+func Open() (*Log, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if opened != nil {
+		return opened, nil
+	}
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	if opened, err = open(p); err != nil {
+		return nil, err
+	}
+	return opened, nil
+}
+
+// Path returns the path of the audit log under config.GetDir(), the backups sit next to it.
+func Path() (string, error) {
+	dir, err := config.GetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logFile), nil
+}
+
+func open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("stat audit log: %w", err)
+	}
+	l := &Log{path: path, f: f, size: stat.Size(), maxSize: maxSize}
+	if l.size > 0 && !endsWithNewline(path, l.size) {
+		// the last line was cut short by a crash, the next entry must not be glued to it
+		n, _ := f.WriteString("\n")
+		l.size += int64(n)
+	}
+	return l, nil
+}
+
+func endsWithNewline(path string, size int64) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	b := make([]byte, 1)
+	if _, err = f.ReadAt(b, size-1); err != nil {
+		return true
+	}
+	return b[0] == '\n'
+}
+
+// Append writes the entry as a single line, rotating the log first, if it would grow past maxSize.
+func (l *Log) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshalling audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err = l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing audit entry: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts the backups by one, audit.jsonl becomes audit.1.jsonl, the oldest one is overwritten.
+func (l *Log) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("closing audit log: %w", err)
+	}
+	for i := backups - 1; i >= 1; i-- {
+		err := os.Rename(backupPath(l.path, i), backupPath(l.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotating audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, backupPath(l.path, 1)); err != nil {
+		return fmt.Errorf("rotating audit log: %w", err)
+	}
+	nl, err := open(l.path)
+	if err != nil {
+		return err
+	}
+	l.f, l.size = nl.f, 0
+	return nil
+}
+
+// backupPath returns the path of the i-th backup, e.g. audit.1.jsonl.
+func backupPath(path string, i int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), i, ext)
+}
+
+// Tail returns the last n entries of the audit log at path, the latest first, reading into the backups
+// if the log has fewer, lines that are not entries, e.g. cut short by a crash, are skipped.
+func Tail(path string, n int) ([]Entry, error) {
+	var entries []Entry
+	for i := 0; i <= backups && len(entries) < n; i++ {
+		p := path
+		if i > 0 {
+			p = backupPath(path, i)
+		}
+		es, err := readAll(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return entries, err
+		}
+		slices.Reverse(es)
+		entries = append(entries, es[:min(len(es), n-len(entries))]...)
+	}
+	return entries, nil
+}
+
+func readAll(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if err = json.Unmarshal(sc.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return entries, fmt.Errorf("reading audit log: %w", err)
+	}
+	return entries, nil
+}
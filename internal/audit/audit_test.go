@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), logFile)
+	l, err := open(path)
+	require.NoError(t, err)
+	l.maxSize = 300 // a couple of entries per file
+
+	for i := range 20 {
+		require.NoError(t, l.Append(Entry{IP: "198.51.100.1", File: "handout-" + strconv.Itoa(i) + ".pdf", Status: 200}))
+	}
+	_, err = os.Stat(backupPath(path, backups))
+	assert.NoError(t, err, "the backups must be kept")
+	_, err = os.Stat(backupPath(path, backups+1))
+	assert.ErrorIs(t, err, os.ErrNotExist, "the oldest backup must be overwritten")
+
+	entries, err := Tail(path, 3)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		assert.Equal(t, "handout-"+strconv.Itoa(19-i)+".pdf", e.File, "the latest must come first, across the backups")
+	}
+
+	// a line cut short by a crash is skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"ip":"198.51`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	entries, err = Tail(path, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "handout-19.pdf", entries[0].File)
+
+	// & the entries appended after it, once reopened, are not glued to it
+	require.NoError(t, l.f.Close())
+	l, err = open(path)
+	require.NoError(t, err)
+	defer l.f.Close()
+	require.NoError(t, l.Append(Entry{File: "handout-20.pdf"}))
+	entries, err = Tail(path, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "handout-20.pdf", entries[0].File)
+}
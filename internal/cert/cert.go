@@ -0,0 +1,151 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	certFile = "cert.pem"
+	keyFile  = "key.pem"
+	validFor = 10 * 365 * 24 * time.Hour
+)
+
+var (
+	mu     sync.Mutex
+	loaded *Cert
+)
+
+// Cert is the per-device self-signed certificate used to serve over HTTPS.
+type Cert struct {
+	TLS tls.Certificate
+	// Fingerprint is the hex encoded SHA-256 of the DER certificate,
+	// it is published in the mDNS TXT record so clients can pin against it.
+	Fingerprint string
+}
+
+// Load returns the device certificate persisted under config.GetDir(),
+// if it does not exist or has expired, a new one is generated and persisted.
+// The certificate is cached after the first call, so it is cheap to call repeatedly.
+func Load() (*Cert, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if loaded != nil && time.Now().Before(loaded.TLS.Leaf.NotAfter) {
+		return loaded, nil
+	}
+
+	dir, err := config.GetDir()
+	if err != nil {
+		return nil, err
+	}
+	certPath, keyPath := filepath.Join(dir, certFile), filepath.Join(dir, keyFile)
+
+	c, err := read(certPath, keyPath)
+	// a missing, corrupt or expired pair is of no use, so we'll (over)write it
+	if err != nil || time.Now().After(c.TLS.Leaf.NotAfter) {
+		if c, err = generate(certPath, keyPath); err != nil {
+			return nil, fmt.Errorf("generating self-signed certificate: %w", err)
+		}
+	}
+	loaded = c
+	return loaded, nil
+}
+
+// Fingerprint returns the hex encoded SHA-256 of the DER encoded certificate.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyPinned returns a tls.Config.VerifyPeerCertificate func, which only trusts the
+// leaf certificate matching the fingerprint, the chain is never verified against any CA.
+func VerifyPinned(fingerprint string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server presented no certificate")
+		}
+		if !strings.EqualFold(Fingerprint(rawCerts[0]), fingerprint) {
+			return errors.New("server certificate does not match the published fingerprint")
+		}
+		return nil
+	}
+}
+
+func read(certPath, keyPath string) (*Cert, error) {
+	cp, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	kp, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := tls.X509KeyPair(cp, kp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate key pair: %w", err)
+	}
+	return &Cert{TLS: tc, Fingerprint: Fingerprint(tc.Certificate[0])}, nil
+}
+
+func generate(certPath, keyPath string) (*Cert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating private key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Letshare"}, CommonName: hostname},
+		NotBefore:             now.Add(-time.Hour), // tolerate slightly skewed clocks
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost", "*.local"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling private key: %w", err)
+	}
+
+	cp := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	kp := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	if err = os.WriteFile(keyPath, kp, 0o600); err != nil {
+		return nil, fmt.Errorf("writing private key: %w", err)
+	}
+	if err = os.WriteFile(certPath, cp, 0o644); err != nil {
+		return nil, fmt.Errorf("writing certificate: %w", err)
+	}
+
+	tc, err := tls.X509KeyPair(cp, kp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing generated key pair: %w", err)
+	}
+	return &Cert{TLS: tc, Fingerprint: Fingerprint(der)}, nil
+}
@@ -0,0 +1,26 @@
+package cert
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAndVerifyPinned(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, certFile), filepath.Join(dir, keyFile)
+
+	c, err := generate(certPath, keyPath)
+	assert.NoError(t, err, "generating certificate")
+
+	r, err := read(certPath, keyPath)
+	assert.NoError(t, err, "reading persisted certificate")
+	assert.Equal(t, c.Fingerprint, r.Fingerprint, "persisted certificate must have the same fingerprint")
+
+	raw := [][]byte{c.TLS.Certificate[0]}
+	assert.NoError(t, VerifyPinned(c.Fingerprint)(raw, nil), "matching fingerprint must be trusted")
+	assert.NoError(t, VerifyPinned(strings.ToUpper(c.Fingerprint))(raw, nil), "fingerprint is case-insensitive")
+	assert.Error(t, VerifyPinned(strings.Repeat("0", 64))(raw, nil), "other fingerprints must be rejected")
+	assert.Error(t, VerifyPinned(c.Fingerprint)(nil, nil), "no certificate must be rejected")
+}
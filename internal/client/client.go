@@ -1,18 +1,29 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/cert"
 	"github.com/MuhamedUsman/letshare/internal/config"
 	"github.com/MuhamedUsman/letshare/internal/domain"
+	"github.com/MuhamedUsman/letshare/internal/file"
 	"github.com/MuhamedUsman/letshare/internal/mdns"
+	"github.com/MuhamedUsman/letshare/internal/throttle"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -23,6 +34,14 @@ const IncompleteDownloadKey = ".incd"
 
 var ErrConnClosed = errors.New("server sent GOAWAY and closed the connection")
 
+// ErrChecksumMismatch is returned by DownloadTracker.Close if the downloaded file is corrupted,
+// i.e. its SHA-256 doesn't match the one sent by the host, the corrupted file is removed.
+var ErrChecksumMismatch = errors.New("downloaded file checksum mismatch")
+
+// ErrIndexVersion is returned while indexing files, if the host serves a different domain.IndexVersion,
+// its access ids can't be used to download the files, letshare must be updated on both ends.
+var ErrIndexVersion = errors.New("host runs an incompatible version of letshare")
+
 var (
 	once   sync.Once
 	client *Client
@@ -31,6 +50,8 @@ var (
 type Progress struct {
 	// D: downloaded bytes, T: total bytes, S speed in bytes per second
 	D, T, S int64
+	// Restarted is set, if the file changed on the host since the download was paused, so it is downloaded afresh
+	Restarted bool
 }
 
 type ProgressMsg struct {
@@ -48,6 +69,13 @@ type DownloadTracker struct {
 	finalName string
 	// d: downloaded bytes, t: total bytes, s: speed per second in byte
 	d, t, s atomic.Int64
+	// digest is the SHA-256 of the file sent by the host, nil if unknown,
+	// the downloaded file is verified against it, see DownloadTracker.Close
+	digest []byte
+	// validators of the file on the host, persisted next to the incomplete download, so resuming it
+	// only appends to it, if the file is still the same, see Client.downloadFile
+	validators validators
+	restarted  atomic.Bool
 	// this chan lifecycle is managed by the DownloadManager
 	// so don't close it in the DownloadTracker.Close method
 	pch                   chan ProgressMsg
@@ -74,6 +102,9 @@ func NewDownloadTracker(id int, f string, ch chan ProgressMsg) (*DownloadTracker
 		cancel:    cancel,
 	}
 	dt.d.Store(size) // how much is it already downloaded
+	if size > 0 {
+		dt.validators = readValidators(validatorsPath(file.Name()))
+	}
 	return dt, nil
 }
 
@@ -114,6 +145,9 @@ func (dt *DownloadTracker) Write(p []byte) (n int, err error) {
 	return
 }
 
+// Close closes the underlying file, if the file is fully downloaded, it is verified against
+// the host's checksum, if known, and the incomplete download key is removed from its name,
+// ErrChecksumMismatch is returned if the file is corrupted.
 func (dt *DownloadTracker) Close() error {
 	dt.cancel()
 	// if total size is set, send the last progress update
@@ -128,12 +162,16 @@ func (dt *DownloadTracker) Close() error {
 	// if file is fully downloaded
 	total := dt.t.Load()
 	if dt.d.Load() == total && total > 0 {
+		_ = os.Remove(validatorsPath(dt.f.Name())) // nothing is left to resume
+		if err := dt.verify(); err != nil {
+			return err
+		}
 		// rename it to remove the incomplete download key
 		final := strings.TrimSuffix(dt.f.Name(), IncompleteDownloadKey)
 		// check if the final file already exists, then generate a unique name
 		if _, err := os.Stat(final); err == nil { // is nil
 			// if such file exists, generate a unique name
-			final = generateUniqueFileName(final)
+			final = file.UniqueName(final)
 		}
 		if err := os.Rename(dt.f.Name(), final); err != nil {
 			return fmt.Errorf("removing %q suffix from dowloaded file: %w", IncompleteDownloadKey, err)
@@ -143,6 +181,101 @@ func (dt *DownloadTracker) Close() error {
 	return nil
 }
 
+// verify compares the SHA-256 of the downloaded file with the digest, the whole file is
+// hashed, so the parts downloaded before a resume are verified too, a corrupted file is removed.
+func (dt *DownloadTracker) verify() error {
+	if dt.digest == nil {
+		return nil
+	}
+	f, err := os.Open(dt.f.Name())
+	if err != nil {
+		return fmt.Errorf("opening downloaded file for verification: %w", err)
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("hashing downloaded file: %w", err)
+	}
+	if !bytes.Equal(h.Sum(nil), dt.digest) {
+		if err = os.Remove(dt.f.Name()); err != nil {
+			return errors.Join(ErrChecksumMismatch, err)
+		}
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// restart discards the downloaded bytes, as the file changed on the host since the download was paused,
+// size is the size of the changed file, -1 if unknown.
+func (dt *DownloadTracker) restart(size int64) error {
+	if err := dt.f.Truncate(0); err != nil {
+		return fmt.Errorf("discarding outdated partial download: %w", err)
+	}
+	dt.d.Store(0)
+	if size >= 0 {
+		dt.t.Store(size)
+	}
+	dt.restarted.Store(true)
+	return nil
+}
+
+// saveValidators persists the validators of the file next to the incomplete download, see validatorsPath.
+func (dt *DownloadTracker) saveValidators(v validators) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling file validators: %w", err)
+	}
+	if err = os.WriteFile(validatorsPath(dt.f.Name()), b, 0o644); err != nil {
+		return fmt.Errorf("saving file validators: %w", err)
+	}
+	dt.validators = v
+	return nil
+}
+
+// validators identify the version of the file on the host, as sent in the ETag & Last-Modified headers.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func validatorsOf(h http.Header) validators {
+	v := validators{LastModified: h.Get("Last-Modified")}
+	if etag := h.Get("ETag"); !strings.HasPrefix(etag, "W/") { // If-Range only matches strong ETags
+		v.ETag = etag
+	}
+	return v
+}
+
+// ifRange returns the If-Range header value, the ETag if the host sent one, empty if it sent neither.
+func (v validators) ifRange() string {
+	return cmp.Or(v.ETag, v.LastModified)
+}
+
+// readValidators returns the persisted validators, the zero validators if there are none,
+// e.g. the download was paused by an older version, it is resumed without If-Range then.
+func readValidators(path string) validators {
+	var v validators
+	if b, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(b, &v)
+	}
+	return v
+}
+
+// RemoveDownload removes the file, downloaded or incomplete, along with the validators persisted to resume it.
+func RemoveDownload(name string) error {
+	if strings.HasSuffix(name, IncompleteDownloadKey) {
+		_ = os.Remove(validatorsPath(name))
+	}
+	return os.Remove(name)
+}
+
+// validatorsPath returns the path the validators of the incomplete download are persisted at, a hidden file
+// next to it, ending with IncompleteDownloadKey as well, so it is deleted along with the partial downloads.
+func validatorsPath(incomplete string) string {
+	return filepath.Join(filepath.Dir(incomplete), "."+filepath.Base(incomplete))
+}
+
 func (dt *DownloadTracker) trackPerSec() {
 	t := time.NewTicker(time.Second)
 	for {
@@ -162,9 +295,10 @@ func (dt *DownloadTracker) trySend(force bool) bool {
 	p := ProgressMsg{
 		ID: dt.id,
 		P: Progress{
-			D: dt.d.Load(),
-			T: dt.t.Load(),
-			S: dt.s.Load(),
+			D:         dt.d.Load(),
+			T:         dt.t.Load(),
+			S:         dt.s.Load(),
+			Restarted: dt.restarted.Load(),
 		},
 	}
 	if force {
@@ -188,12 +322,23 @@ func (dt *DownloadTracker) trySend(force bool) bool {
 type Client struct {
 	mdns *mdns.MDNS
 	c    http.Client
+	mu   sync.Mutex
+	// share secrets per instance, sent as bearer token, [K: instance, V: secret]
+	secrets map[string]string
+	// stop tokens per instance, entered by the user, sent when stopping it, [K: instance, V: token]
+	stopTokens map[string]string
+	// clients for instances served over HTTPS, each only trusts the certificate
+	// published over mDNS, [K: certificate fingerprint, V: pinned client]
+	pinned map[string]*http.Client
+	// limiter caps the speed of all downloads combined, see Client.SetRateLimit
+	limiter *throttle.Limiter
 }
 
 func Get() *Client {
 	once.Do(func() {
 		var proto http.Protocols
 		proto.SetUnencryptedHTTP2(true)
+		cfg, _ := config.Get() // no config, no rate limit
 		client = &Client{
 			mdns: mdns.Get(),
 			c: http.Client{Transport: &http.Transport{
@@ -201,20 +346,114 @@ func Get() *Client {
 				ForceAttemptHTTP2:  true,
 				Protocols:          &proto,
 			}},
+			secrets:    make(map[string]string),
+			stopTokens: make(map[string]string),
+			pinned:     make(map[string]*http.Client),
+			limiter:    throttle.NewLimiter(int64(cfg.Receive.RateLimit) << 10),
 		}
 	})
 	return client
 }
 
-func (c *Client) IndexFiles(instance string) ([]*domain.FileInfo, int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+// SetRateLimit caps the speed of all downloads combined to kb KB/s, 0 for no limit,
+// downloads already in progress are slowed down (or sped up) right away.
+func (c *Client) SetRateLimit(kb int) {
+	c.limiter.SetRate(int64(kb) << 10)
+}
+
+// pinnedKey is the request context key holding the certificate fingerprint, set by Client.newRequest.
+type pinnedKey struct{}
+
+// do sends the request with the client pinned to the certificate fingerprint of the instance,
+// or with the plain HTTP client, if the instance is not served over HTTPS.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	fp, _ := req.Context().Value(pinnedKey{}).(string)
+	if fp == "" {
+		return c.c.Do(req)
+	}
+	c.mu.Lock()
+	hc, ok := c.pinned[fp]
+	if !ok {
+		var proto http.Protocols
+		proto.SetHTTP1(true)
+		proto.SetHTTP2(true)
+		hc = &http.Client{Transport: &http.Transport{
+			DisableCompression: true,
+			ForceAttemptHTTP2:  true,
+			Protocols:          &proto,
+			TLSClientConfig: &tls.Config{
+				// the certificate is self-signed, there is no CA to verify the chain against,
+				// instead VerifyPeerCertificate pins it against the published fingerprint
+				InsecureSkipVerify:    true,
+				VerifyPeerCertificate: cert.VerifyPinned(fp),
+				MinVersion:            tls.VersionTLS12,
+			},
+		}}
+		c.pinned[fp] = hc
+	}
+	c.mu.Unlock()
+	return hc.Do(req)
+}
+
+// SetSecret sets the share secret for the instance, all further requests
+// to the instance will be authenticated with it, an empty secret removes it.
+func (c *Client) SetSecret(instance, secret string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if secret == "" {
+		delete(c.secrets, instance)
+		return
+	}
+	c.secrets[instance] = secret
+}
+
+// HasSecret reports whether a share secret is set for the instance.
+func (c *Client) HasSecret(instance string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.secrets[instance]
+	return ok
+}
+
+// SetStopToken sets the stop token of the instance, given by its owner, it is sent when
+// stopping the instance, see Client.StopServer, an empty token removes it.
+func (c *Client) SetStopToken(instance, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if token == "" {
+		delete(c.stopTokens, instance)
+		return
+	}
+	c.stopTokens[instance] = token
+}
+
+// IndexFiles lists the shared files, sorted, filtered & paginated as per the query, it is usually the first
+// request to the instance, so it waits long enough for the host to approve us, if the host asks before sharing.
+func (c *Client) IndexFiles(instance string, q domain.IndexQuery) ([]*domain.FileInfo, int, error) {
+	return c.indexFiles(instance, withQuery("/", q.Values()), 65*time.Second)
+}
+
+// IndexDir lists the directory at path, inside the shared directory identified by accessID, as per the query,
+// if recursive, all the entries beneath it are listed, with their paths relative to the shared directory.
+func (c *Client) IndexDir(instance string, accessID string, path string, recursive bool, q domain.IndexQuery) ([]*domain.FileInfo, int, error) {
+	v := q.Values()
+	timeout := 2 * time.Second
+	if recursive {
+		v.Set("recursive", "")
+		timeout = 30 * time.Second // walking a large tree takes a while
+	}
+	return c.indexFiles(instance, withQuery(FilePath(accessID, path)+"/", v), timeout)
+}
+
+func (c *Client) indexFiles(instance, path string, timeout time.Duration) ([]*domain.FileInfo, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	req, err := client.newRequest(ctx, instance, http.MethodGet, "", nil)
+	req, err := c.newRequest(ctx, instance, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, -1, fmt.Errorf("creating request: %w", err)
 	}
 
-	resp, err := c.c.Do(req)
+	resp, err := c.do(req)
 	var urlErr *url.Error
 	if err != nil {
 		if errors.As(err, &urlErr) && urlErr.Timeout() {
@@ -234,17 +473,25 @@ func (c *Client) IndexFiles(instance string) ([]*domain.FileInfo, int, error) {
 		return nil, -1, fmt.Errorf("reading directory index response: %w", unwrapErr(err))
 	}
 
-	var r map[string][]*domain.FileInfo
-	if err = json.Unmarshal(b, &r); err != nil {
+	var idx domain.Index
+	if err = json.Unmarshal(b, &idx); err != nil {
+		if v := new(struct{ Version int }); json.Unmarshal(b, v) == nil && v.Version != domain.IndexVersion {
+			return nil, -1, ErrIndexVersion // e.g. the crc32 access ids of the unversioned index
+		}
 		return nil, -1, fmt.Errorf("parsing directory index JSON: %w", err)
 	}
+	if idx.Version != domain.IndexVersion {
+		return nil, -1, ErrIndexVersion
+	}
 
-	return r["fileIndexes"], resp.StatusCode, nil
+	return idx.FileIndexes, resp.StatusCode, nil
 }
 
-func (c *Client) DownloadFile(dst *DownloadTracker, instance string, accessID uint32) (int, error) {
-	path := fmt.Sprintf("/%d", accessID)
-	statusCode, size, err := c.getFileSize(instance, path)
+// DownloadFile downloads the shared file identified by accessID, or the file at path
+// inside the shared directory identified by accessID, see FilePath.
+func (c *Client) DownloadFile(dst *DownloadTracker, instance string, accessID string, path string) (int, error) {
+	path = FilePath(accessID, path)
+	statusCode, size, digest, err := c.getFileSize(instance, path)
 	if err != nil {
 		return -1, unwrapErr(err)
 	}
@@ -252,6 +499,7 @@ func (c *Client) DownloadFile(dst *DownloadTracker, instance string, accessID ui
 		return statusCode, nil
 	}
 	dst.t.Store(size) // set total size of the file
+	dst.digest = digest
 
 	var status int
 	status, err = c.downloadFile(dst, instance, path)
@@ -261,26 +509,61 @@ func (c *Client) DownloadFile(dst *DownloadTracker, instance string, accessID ui
 		}
 		return -1, unwrapErr(err)
 	}
+	if dst.digest == nil && dst.d.Load() == dst.t.Load() && status < 400 {
+		// the host computes checksums lazily, it may have it by now
+		if statusCode, _, digest, err = c.getFileSize(instance, path); err == nil && statusCode == http.StatusOK {
+			dst.digest = digest
+		}
+	}
 	return status, nil
 }
 
-func (c *Client) getFileSize(instance, path string) (statusCode int, size int64, err error) {
+// Snippet fetches the text snippet identified by accessID, fetching it counts as a download on the host.
+func (c *Client) Snippet(instance string, accessID string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := c.newRequest(ctx, instance, http.MethodGet, FilePath(accessID, ""), nil)
+	if err != nil {
+		return "", -1, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			return "", http.StatusRequestTimeout, nil
+		}
+		return "", -1, fmt.Errorf("fetching snippet: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", resp.StatusCode, nil
+	}
+	// the host never shares more than server.MaxSnippetSize
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", -1, fmt.Errorf("reading snippet: %w", unwrapErr(err))
+	}
+	return string(b), resp.StatusCode, nil
+}
+
+// getFileSize returns the size of the file & its SHA-256 digest, nil if the host has yet to compute it.
+func (c *Client) getFileSize(instance, path string) (statusCode int, size int64, digest []byte, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	req, err := c.newRequest(ctx, instance, http.MethodHead, path, nil)
 	if err != nil {
-		return -1, -1, fmt.Errorf("creating request: %w", err)
+		return -1, -1, nil, fmt.Errorf("creating request: %w", err)
 	}
-	resp, err := c.c.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		var urlErr *url.Error
 		if errors.As(err, &urlErr) && urlErr.Timeout() {
-			return http.StatusRequestTimeout, -1, nil
+			return http.StatusRequestTimeout, -1, nil, nil
 		}
-		return -1, -1, err
+		return -1, -1, nil, err
 	}
 	defer resp.Body.Close()
-	return resp.StatusCode, resp.ContentLength, nil
+	return resp.StatusCode, resp.ContentLength, parseDigest(resp.Header.Get("Digest")), nil
 }
 
 func (c *Client) downloadFile(dst *DownloadTracker, instance, path string) (int, error) {
@@ -292,8 +575,12 @@ func (c *Client) downloadFile(dst *DownloadTracker, instance, path string) (int,
 	// in case of resume
 	startRange := dst.d.Load() // how much is already downloaded
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startRange))
+	if ifRange := dst.validators.ifRange(); startRange > 0 && ifRange != "" {
+		// the remaining bytes are only sent if the file is still the same, the whole file otherwise
+		req.Header.Set("If-Range", ifRange)
+	}
 
-	resp, err := c.c.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		var urlErr *url.Error
 		if errors.As(err, &urlErr) && urlErr.Timeout() {
@@ -307,25 +594,123 @@ func (c *Client) downloadFile(dst *DownloadTracker, instance, path string) (int,
 	if resp.StatusCode >= 400 {
 		return resp.StatusCode, nil
 	}
+	if digest := parseDigest(resp.Header.Get("Digest")); digest != nil {
+		dst.digest = digest // the most recent one, in case the file changed since the HEAD request
+	}
+	if startRange > 0 && resp.StatusCode == http.StatusOK {
+		// the whole file is sent, it changed since the download was paused, appending would splice two files
+		if err = dst.restart(resp.ContentLength); err != nil {
+			return -1, err
+		}
+	}
+	if v := validatorsOf(resp.Header); v != dst.validators {
+		if err = dst.saveValidators(v); err != nil {
+			return -1, err
+		}
+	}
 
 	b := make([]byte, 1<<20) // 1 MiB buffer
-	// Read the response body and write to the tracker
-	if _, err = io.CopyBuffer(dst, resp.Body, b); err != nil {
+	// Read the response body and write to the tracker, as fast as the rate limit allows
+	w := throttle.NewWriter(dst.ctx, dst, c.limiter)
+	if _, err = io.CopyBuffer(w, resp.Body, b); err != nil {
 		return -1, fmt.Errorf("copying resp body to file: %w", err)
 	}
 
 	return resp.StatusCode, nil
 }
 
+// Events subscribes to the events of the instance, see domain.Event, the channel is closed once the
+// stream ends, i.e. the host stopped sharing, the connection dropped or the ctx is done.
+// The status code is returned, if the instance refused the subscription, e.g. 401 Unauthorized.
+func (c *Client) Events(ctx context.Context, instance string) (<-chan domain.Event, int, error) {
+	req, err := c.newRequest(ctx, instance, http.MethodGet, "/events", nil)
+	if err != nil {
+		return nil, -1, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, -1, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, resp.StatusCode, nil
+	}
+
+	ch := make(chan domain.Event)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+		_ = readEvents(resp.Body, func(e domain.Event) bool {
+			select {
+			case ch <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch, resp.StatusCode, nil
+}
+
+// readEvents parses the text/event-stream, calling emit for every event,
+// until the stream ends or emit returns false, comments & unknown fields are ignored.
+func readEvents(r io.Reader, emit func(domain.Event) bool) error {
+	sc := bufio.NewScanner(r)
+	var typ string
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, ":") { // comment, e.g. the keep alive pings
+			continue
+		}
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				typ = value
+			case "data":
+				data = append(data, value)
+			}
+			continue
+		}
+		// a blank line dispatches the event
+		if len(data) > 0 {
+			e := domain.Event{Type: domain.EventMessage} // the default type, as per the spec
+			if typ != "" {
+				e.Type = typ
+			}
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &e); err == nil && !emit(e) {
+				return nil
+			}
+		}
+		typ, data = "", nil
+	}
+	return sc.Err()
+}
+
+// StopServer asks the instance to shut down, unless the stop token set for it, see Client.SetStopToken, or the IP
+// is trusted by its owner, they are asked, so it long-polls for their decision, for about a minute, returns:
+//   - 202 Accepted: the instance is shutting down
+//   - 403 Forbidden: the instance is not stoppable, or its owner declined
+//   - 409 Conflict: the owner did not decide in time
+//   - 408 Request Timeout: the instance did not respond
 func (c *Client) StopServer(instance string) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 70*time.Second)
 	defer cancel()
 	req, err := c.newRequest(ctx, instance, http.MethodPost, "/stop", nil)
 	if err != nil {
 		return -1, fmt.Errorf("creating request: %v", err)
 	}
+	// only ever the token given for the instance, the own one would let its receiver stop our instances
+	c.mu.Lock()
+	if token, ok := c.stopTokens[instance]; ok {
+		req.Header.Set("X-Stop-Token", token)
+	}
+	c.mu.Unlock()
 
-	resp, err := c.c.Do(req)
+	resp, err := c.do(req)
 	var urlErr *url.Error
 	if err != nil {
 		if errors.As(err, &urlErr) && urlErr.Timeout() {
@@ -349,7 +734,14 @@ func (c *Client) newRequest(ctx context.Context, instance, method, path string,
 	if !ok {
 		return nil, fmt.Errorf("instance %q is currently offline", instance)
 	}
-	addr := fmt.Sprintf("http://%s:%d%s", entry.IP, entry.Port, path)
+	scheme := "http"
+	if entry.Fingerprint != "" {
+		scheme = "https"
+		ctx = context.WithValue(ctx, pinnedKey{}, entry.Fingerprint)
+	}
+	// the zone of a link-local IPv6 address, e.g. fe80::1%eth0, must be escaped in URLs
+	host := strings.Replace(net.JoinHostPort(entry.IP, strconv.Itoa(int(entry.Port))), "%", "%25", 1)
+	addr := fmt.Sprintf("%s://%s%s", scheme, host, path)
 	uname, err := c.getClientUsername()
 	if err != nil {
 		return nil, fmt.Errorf("retrieving client username: %v", err)
@@ -362,9 +754,36 @@ func (c *Client) newRequest(ctx context.Context, instance, method, path string,
 	req = req.WithContext(ctx)
 	req.Header.Set("X-Requested-By", uname)
 	req.Header.Set("Accept", "application/json")
+	c.mu.Lock()
+	if secret, ok := c.secrets[instance]; ok {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	c.mu.Unlock()
 	return req, nil
 }
 
+// FilePath returns the URL path of the file at path inside the shared directory identified
+// by accessID, path is slash separated, if empty, it is the shared file or directory itself.
+func FilePath(accessID string, path string) string {
+	p := "/" + accessID
+	if path == "" {
+		return p
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return p + "/" + strings.Join(segments, "/")
+}
+
+// withQuery appends the query parameters to the URL path, if any.
+func withQuery(path string, v url.Values) string {
+	if len(v) == 0 {
+		return path
+	}
+	return path + "?" + v.Encode()
+}
+
 func (c *Client) getClientUsername() (string, error) {
 	cfg, err := config.Get()
 	if err != nil {
@@ -389,15 +808,6 @@ func prepareFileForDownload(f string) (*os.File, int64, error) {
 	return file, fi.Size(), nil
 }
 
-func generateUniqueFileName(name string) string {
-	ext := filepath.Ext(name)
-	if filepath.Base(name) == ext {
-		ext = ""
-	}
-	base := strings.TrimSuffix(name, ext)
-	return fmt.Sprintf("%s-%06d%s", base, time.Now().UnixNano()%1_000_000, ext)
-}
-
 func unwrapErr(err error) error {
 	for {
 		unwrapped := errors.Unwrap(err)
@@ -407,3 +817,20 @@ func unwrapErr(err error) error {
 		err = unwrapped
 	}
 }
+
+// parseDigest returns the SHA-256 from the Digest header (RFC 3230), e.g. "sha-256=<base64>",
+// nil if the header has no valid SHA-256.
+func parseDigest(header string) []byte {
+	for d := range strings.SplitSeq(header, ",") {
+		algo, v, ok := strings.Cut(strings.TrimSpace(d), "=")
+		if !ok || !strings.EqualFold(algo, "sha-256") {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(b) != sha256.Size {
+			return nil
+		}
+		return b
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"crypto/sha256"
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDigest(t *testing.T) {
+	sum := sha256.Sum256([]byte("hello"))
+	assert.Equal(t, sum[:], parseDigest("sha-256=LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="))
+	assert.Equal(t, sum[:], parseDigest("md5=XUFAKrxLKna5cZ2REBfFkg==, SHA-256=LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="))
+	assert.Nil(t, parseDigest(""))
+	assert.Nil(t, parseDigest("sha-256=not-base64"))
+	assert.Nil(t, parseDigest("md5=XUFAKrxLKna5cZ2REBfFkg=="))
+}
+
+func TestDownloadTracker_Close(t *testing.T) {
+	download := func(content string, digest [sha256.Size]byte) (*DownloadTracker, error) {
+		dt, err := NewDownloadTracker(0, filepath.Join(t.TempDir(), "notes.txt"), make(chan ProgressMsg, 10))
+		require.NoError(t, err)
+		dt.t.Store(int64(len(content)))
+		dt.digest = digest[:]
+		_, err = dt.Write([]byte(content))
+		require.NoError(t, err)
+		return dt, dt.Close()
+	}
+
+	dt, err := download("hello", sha256.Sum256([]byte("hello")))
+	require.NoError(t, err)
+	assert.FileExists(t, dt.Filename())
+	assert.Equal(t, "notes.txt", filepath.Base(dt.Filename()))
+
+	// the corrupted file is removed, so retrying downloads it afresh
+	dt, err = download("hellO", sha256.Sum256([]byte("hello")))
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = os.Stat(dt.Filename())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDownloadTracker_resume(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "notes.txt")
+	ch := make(chan ProgressMsg, 10)
+	dt, err := NewDownloadTracker(0, name, ch)
+	require.NoError(t, err)
+	dt.t.Store(10)
+	_, err = dt.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, dt.saveValidators(validatorsOf(http.Header{"Etag": {`"v1"`}, "Last-Modified": {"Mon, 12 Oct 2026 10:00:00 GMT"}})))
+	require.NoError(t, dt.Close()) // paused
+
+	dt, err = NewDownloadTracker(0, name, ch)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, dt.d.Load())
+	assert.Equal(t, `"v1"`, dt.validators.ifRange(), "the ETag is preferred for If-Range")
+
+	// the host sent the whole file, it changed since the download was paused
+	require.NoError(t, dt.restart(7))
+	_, err = dt.Write([]byte("goodbye"))
+	require.NoError(t, err)
+	require.NoError(t, dt.Close())
+	b, err := os.ReadFile(dt.Filename())
+	require.NoError(t, err)
+	assert.Equal(t, "goodbye", string(b), "the changed file must not be spliced onto the partial one")
+	assert.NoFileExists(t, validatorsPath(name+IncompleteDownloadKey))
+
+	var last ProgressMsg
+	for len(ch) > 0 {
+		last = <-ch
+	}
+	assert.True(t, last.P.Restarted)
+
+	assert.Empty(t, validatorsOf(http.Header{"Etag": {`W/"v1"`}}).ifRange(), "weak ETags never match If-Range")
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": subscribed\n\n" +
+		"event: index\ndata: {\"indexVersion\":2}\n\n" +
+		": ping\n\n" +
+		"event: message\ndata: {\"message\":\"grab the slides\"}\n\n" +
+		"event: unknown\n\n" + // no data, never dispatched
+		"event: shutdown\ndata: {\"message\":\"bye\"}\n\n"
+
+	var events []domain.Event
+	err := readEvents(strings.NewReader(stream), func(e domain.Event) bool {
+		events = append(events, e)
+		return true
+	})
+	require.NoError(t, err)
+	want := []domain.Event{
+		{Type: domain.EventIndex, IndexVersion: 2},
+		{Type: domain.EventMessage, Message: "grab the slides"},
+		{Type: domain.EventShutdown, Message: "bye"},
+	}
+	assert.Equal(t, want, events)
+}
@@ -12,6 +12,10 @@ import (
 
 const (
 	MaxConcurrentDownloads = 10
+	MaxExpiryMinutes       = 7 * 24 * 60 // a week
+	MaxDownloadsPerFile    = 1000
+	MaxRateLimit           = 1 << 20 // KB/s, i.e. 1 GB/s
+	MaxUploadMB            = 1 << 20 // MB, i.e. 1 TB
 	appConfDir             = ".letshare"
 	appConfFile            = "config.toml"
 )
@@ -27,14 +31,35 @@ type PersonalConfig struct {
 type ShareConfig struct {
 	InstanceName      string `toml:"instance_name"`
 	StoppableInstance bool   `toml:"stoppable_instance"`
-	ZipFiles          bool   `toml:"zip_files"`
-	Compression       bool   `toml:"compression"`
-	SharedZipName     string `toml:"shared_zip_name"`
+	AllowUploads      bool   `toml:"allow_uploads"`
+	MaxUploadMB       int    `toml:"max_upload_mb"` // per request, 0 means unlimited
+	Secret            string `toml:"secret"`
+	AskBeforeSharing  bool   `toml:"ask_before_sharing"`
+	HTTPS             bool   `toml:"https"`
+	Port              int    `toml:"port"`              // 0 means the default of the scheme, i.e. 80 or 443
+	ExpiryMinutes     int    `toml:"expiry_minutes"`    // 0 means the share never expires
+	MaxDownloads      int    `toml:"max_downloads"`     // per file, 0 means unlimited
+	RateLimit         int    `toml:"rate_limit"`        // KB/s for the whole server, 0 means unlimited
+	ClientRateLimit   int    `toml:"client_rate_limit"` // KB/s per client, 0 means unlimited
+	// Interfaces to share on, network interface names or addresses, empty to pick automatically
+	Interfaces []string `toml:"interfaces"`
+	// AllowList are the clients allowed to access the share, CIDRs, IPs or names (X-Requested-By), empty allows all
+	AllowList []string `toml:"allow_list"`
+	// DenyList are the clients denied access to the share, even if allowed, in the same format as AllowList
+	DenyList []string `toml:"deny_list"`
+	// TrustedPeers are the IPs that stop the idle instance without asking
+	TrustedPeers []string `toml:"trusted_peers"`
+	// StopToken lets its holders stop the idle instance without asking, it is never sent to other instances
+	StopToken     string `toml:"stop_token"`
+	ZipFiles      bool   `toml:"zip_files"`
+	Compression   bool   `toml:"compression"`
+	SharedZipName string `toml:"shared_zip_name"`
 }
 
 type ReceiveConfig struct {
 	DownloadFolder      string `toml:"download_folder"`
 	ConcurrentDownloads int    `toml:"concurrent_downloads"`
+	RateLimit           int    `toml:"rate_limit"` // KB/s for all downloads, 0 means unlimited
 }
 
 type Config struct {
@@ -37,6 +37,14 @@ func TestGet(t *testing.T) {
 		Share: ShareConfig{
 			InstanceName:      "TestInstance",
 			StoppableInstance: true,
+			AllowUploads:      true,
+			Secret:            "1234",
+			AskBeforeSharing:  true,
+			HTTPS:             true,
+			ExpiryMinutes:     45,
+			MaxDownloads:      3,
+			RateLimit:         4096,
+			ClientRateLimit:   1024,
 			ZipFiles:          true,
 			Compression:       true,
 			SharedZipName:     "Test.zip",
@@ -44,6 +52,7 @@ func TestGet(t *testing.T) {
 		Receive: ReceiveConfig{
 			DownloadFolder:      "testPath",
 			ConcurrentDownloads: 0,
+			RateLimit:           2048,
 		},
 	}
 
@@ -1,7 +1,112 @@
 package domain
 
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IndexVersion is the format of the file indexes, sent as the "version" alongside them, it is bumped whenever
+// the host & the client must agree on a change, e.g. the access ids, version 1 being the unversioned crc32 ids.
+const IndexVersion = 2
+
+// Index is the JSON file index served by the host, a page of the shared files, or of a shared directory.
+type Index struct {
+	// Version is the IndexVersion of the host, the client must not use the index, if it differs
+	Version     int         `json:"version"`
+	FileIndexes []*FileInfo `json:"fileIndexes"`
+}
+
 type FileInfo struct {
-	Name     string `json:"name,omitempty"`
-	AccessID uint32 `json:"accessId"`
-	Size     int64  `json:"size,omitempty"` // Size in bytes
+	Name string `json:"name,omitempty"`
+	// AccessID identifies the shared file, directory or snippet, it is unguessable & reveals nothing of the path,
+	// the same path is shared at the same id every time, so resumed downloads find it again
+	AccessID string `json:"accessId"`
+	// Path is the slash separated path of the file, relative to the shared directory
+	// identified by AccessID, empty for the shared file or directory itself
+	Path  string `json:"path,omitempty"`
+	Size  int64  `json:"size,omitempty"` // Size in bytes
+	IsDir bool   `json:"isDir,omitempty"`
+	// ExpiresAt is when the share stops serving the file, zero if it never expires
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	// DownloadsLeft is the number of completed downloads still allowed, 0 if unlimited
+	DownloadsLeft int `json:"downloadsLeft,omitempty"`
+	// SHA256 is the hex encoded checksum of the file, empty for directories or until the host has computed it
+	SHA256 string `json:"sha256,omitempty"`
+	// ModTime is when the file was last modified
+	ModTime time.Time `json:"modTime,omitzero"`
+	// Type of the shared item, empty for files & directories, see TypeSnippet
+	Type string `json:"type,omitempty"`
+}
+
+// TypeSnippet marks a FileInfo as a text snippet, shared alongside the files,
+// its text is served at the access id, as plain text, Size is the length of the text.
+const TypeSnippet = "snippet"
+
+// Sort keys of IndexQuery.Sort
+const (
+	SortByName = "name"
+	SortBySize = "size"
+	SortByType = "type" // i.e. the extension
+	SortByDate = "date" // i.e. the modification time
+)
+
+// IndexQuery sorts, filters & paginates the file indexes, it is sent as the query parameters
+// of the index requests, the zero value lists all the files sorted by name.
+type IndexQuery struct {
+	// Sort is one of the SortBy keys, empty sorts by name
+	Sort string
+	// Desc reverses the order
+	Desc bool
+	// Search keeps the files with names containing it, case-insensitive
+	Search string
+	// Exts keeps the files with one of the extensions, without the leading dot, case-insensitive
+	Exts []string
+	// Page is the 1-based page of PerPage files, PerPage 0 lists all the files at once
+	Page, PerPage int
+}
+
+// Values encodes the query as the query parameters understood by the server,
+// i.e. sort, order, q, ext, page & per_page, the zero fields are left out.
+func (q IndexQuery) Values() url.Values {
+	v := make(url.Values)
+	if q.Sort != "" {
+		v.Set("sort", q.Sort)
+	}
+	if q.Desc {
+		v.Set("order", "desc")
+	}
+	if q.Search != "" {
+		v.Set("q", q.Search)
+	}
+	if len(q.Exts) > 0 {
+		v.Set("ext", strings.Join(q.Exts, ","))
+	}
+	if q.Page > 0 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage > 0 {
+		v.Set("per_page", strconv.Itoa(q.PerPage))
+	}
+	return v
+}
+
+// Event types pushed through the server's event feed, see Event.
+const (
+	// EventIndex signals the shared files changed, receivers should fetch the index again
+	EventIndex = "index"
+	// EventMessage carries a message from the host to the receivers
+	EventMessage = "message"
+	// EventShutdown signals the host stopped the share, only the downloads in progress are served
+	EventShutdown = "shutdown"
+)
+
+// Event is pushed to the receivers through the server-sent events feed at /events,
+// Type is sent as the SSE event name, the rest as JSON data.
+type Event struct {
+	Type    string `json:"-"`
+	Message string `json:"message,omitempty"`
+	// IndexVersion is incremented every time the shared files change
+	IndexVersion uint64 `json:"indexVersion,omitempty"`
 }
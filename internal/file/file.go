@@ -0,0 +1,19 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UniqueName returns the name with a random-ish suffix before its extension, e.g. "report-042137.pdf",
+// for a file that would otherwise overwrite an existing one, dot files, e.g. ".env", keep their name whole.
+func UniqueName(name string) string {
+	ext := filepath.Ext(name)
+	if filepath.Base(name) == ext {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	return fmt.Sprintf("%s-%06d%s", base, time.Now().UnixNano()%1_000_000, ext)
+}
@@ -0,0 +1,19 @@
+package file
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestUniqueName(t *testing.T) {
+	tests := []struct{ name, pattern string }{
+		{filepath.Join("dl", "report.pdf"), `^dl.report-\d{6}\.pdf$`},
+		{filepath.Join("dl", "archive.tar.gz"), `^dl.archive\.tar-\d{6}\.gz$`},
+		{filepath.Join("dl", ".env"), `^dl.\.env-\d{6}$`},
+		{"README", `^README-\d{6}$`},
+	}
+	for _, tc := range tests {
+		assert.Regexp(t, tc.pattern, UniqueName(tc.name), tc.name)
+	}
+}
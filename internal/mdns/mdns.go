@@ -6,13 +6,18 @@ import (
 	"github.com/MuhamedUsman/letshare/internal/network"
 	"github.com/betamos/zeroconf"
 	"maps"
+	"net"
 	"net/netip"
+	"slices"
 	"sync"
 )
 
 const (
 	// UsernameKey used to store the username of the service owner in mDNS TXT records
-	UsernameKey     = "username"
+	UsernameKey = "username"
+	// FingerprintKey used to store the SHA-256 fingerprint of the service's TLS certificate
+	// in mDNS TXT records, only present if the service is served over HTTPS
+	FingerprintKey  = "fingerprint"
 	DefaultInstance = "letshare"
 	Domain          = "local"
 	mdnsService     = "_http._tcp"
@@ -27,7 +32,9 @@ var (
 // ServiceEntry represents a discovered mDNS service with its network details.
 type ServiceEntry struct {
 	Owner, Hostname, IP string
-	Port                uint16
+	// Fingerprint of the TLS certificate to pin against, empty if served over plain HTTP
+	Fingerprint string
+	Port        uint16
 }
 
 // ServiceEntries represents discovered mDNS services where:
@@ -38,13 +45,15 @@ type ServiceEntries map[string]ServiceEntry
 // MDNS handles multicast DNS service registration and discovery on local networks.
 // It provides methods to publish services and discover other services on the network.
 type MDNS struct {
-	// Mutex to protect access to entries & notifyCh
+	// Mutex to protect access to entries, notifyCh & pubs
 	mu sync.RWMutex
 	// Stores discovered mDNS entries
 	entries ServiceEntries
 	// channel to broadcast changes to entries
 	notifyCh chan struct{}
-	pub, bro *zeroconf.Client
+	// publishers of the instances being published, [K: instance, V: publisher]
+	pubs map[string]*zeroconf.Client
+	bro  *zeroconf.Client
 }
 
 // Get creates and returns a new MDNS instance.
@@ -57,42 +66,69 @@ func Get() *MDNS {
 		mdns = &MDNS{
 			entries:  make(ServiceEntries),
 			notifyCh: make(chan struct{}),
+			pubs:     make(map[string]*zeroconf.Client),
 		}
 	})
 	return mdns
 }
 
-// Publish registers a service instance with mDNS.
+// Publish registers a service instance with mDNS, several instances may be published at once,
+// each until its own context is done.
 //
 // Params:
 //   - ctx: Context to control the lifetime of the service registration.
 //   - instance: Unique name for the service instance. (e.g., "letshare")
 //   - hostname: Hostname of the machine hosting the service. (e.g., "my-computer.local")
 //   - username: Username of the service owner, used in TXT records. (e.g., "john_doe")
+//   - fingerprint: SHA-256 fingerprint of the TLS certificate, empty if not served over HTTPS.
 //   - port: Port on which the service is running. (e.g., 80)
+//   - ifaces: Network interfaces to publish on, each with its own addresses, see network.Select.
 //
 // Returns:
 //   - error: An error if the service registration fails, otherwise nil.
-func (r *MDNS) Publish(ctx context.Context, instance, hostname, username string, port uint16) error {
+func (r *MDNS) Publish(ctx context.Context, instance, hostname, username, fingerprint string, port uint16, ifaces []network.Interface) error {
 	s := zeroconf.NewService(typ, instance, port)
 	s.Hostname = hostname
 
 	usr := fmt.Sprintf("%s=%s", UsernameKey, username)
 	s.Text = []string{usr}
+	if fingerprint != "" {
+		s.Text = append(s.Text, fmt.Sprintf("%s=%s", FingerprintKey, fingerprint))
+	}
 
-	addr, err := network.GetOutboundIP()
-	if err != nil {
-		return fmt.Errorf("getting outbound IP address: %w", err)
+	if len(ifaces) == 0 {
+		return network.ErrNoInterface
+	}
+	// every interface announces its own addresses, so the receivers
+	// get the address reachable from their network, not the others
+	netIfaces := make([]net.Interface, len(ifaces))
+	addrs := make(map[int][]net.Addr, len(ifaces))
+	for i, iface := range ifaces {
+		netIfaces[i] = iface.Interface
+		for _, a := range iface.Addrs {
+			addrs[iface.Index] = append(addrs[iface.Index], &net.IPNet{IP: a.AsSlice(), Mask: net.CIDRMask(a.BitLen(), a.BitLen())})
+		}
 	}
-	s.Addrs = []netip.Addr{addr}
 
-	r.pub, err = zeroconf.New().Publish(s).Open()
+	pub, err := zeroconf.New().
+		Publish(s).
+		Interfaces(func() ([]net.Interface, error) { return netIfaces, nil }).
+		InterfaceAddrs(func(ni *net.Interface) ([]net.Addr, error) { return addrs[ni.Index], nil }).
+		Open()
 	if err != nil {
 		return fmt.Errorf("publishing mDNS service: %w", err)
 	}
+	r.mu.Lock()
+	r.pubs[instance] = pub
+	r.mu.Unlock()
 
 	defer func() {
-		_ = r.pub.Close()
+		r.mu.Lock()
+		if r.pubs[instance] == pub {
+			delete(r.pubs, instance)
+		}
+		r.mu.Unlock()
+		_ = pub.Close()
 	}()
 
 	<-ctx.Done()
@@ -109,10 +145,11 @@ func (r *MDNS) Browse(ctx context.Context) error {
 		switch e.Op {
 		case zeroconf.OpAdded, zeroconf.OpUpdated:
 			se := ServiceEntry{
-				Owner:    extractOwner(e.Text),
-				Hostname: e.Hostname,
-				IP:       e.Addrs[0].String(),
-				Port:     e.Port,
+				Owner:       extractTXT(UsernameKey, e.Text),
+				Hostname:    e.Hostname,
+				IP:          preferredAddr(e.Addrs),
+				Fingerprint: extractTXT(FingerprintKey, e.Text),
+				Port:        e.Port,
 			}
 			r.entries[e.Name] = se
 			close(r.notifyCh)                // Notify the change
@@ -163,22 +200,42 @@ func (r *MDNS) Entries() ServiceEntries {
 	return dst
 }
 
-func (r *MDNS) ReloadPublisher() {
-	r.pub.Reload()
+// ReloadPublisher announces the instance again, if it is being published.
+func (r *MDNS) ReloadPublisher(instance string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if pub, ok := r.pubs[instance]; ok {
+		pub.Reload()
+	}
 }
 
 func (r *MDNS) ReloadBrowser() {
 	r.bro.Reload()
 }
 
-func extractOwner(s []string) string {
-	kl := len(UsernameKey)
+// preferredAddr returns the first IPv4 address of the service, the IPv6 ones, e.g. link-local,
+// are often unreachable, so one is only returned if the service has no IPv4 address.
+func preferredAddr(addrs []netip.Addr) string {
+	i := slices.IndexFunc(addrs, func(a netip.Addr) bool { return a.Unmap().Is4() })
+	switch {
+	case i >= 0:
+		return addrs[i].Unmap().String()
+	case len(addrs) > 0:
+		return addrs[0].String()
+	default:
+		return ""
+	}
+}
+
+// extractTXT returns the value of the key from the "key=value" TXT records.
+func extractTXT(key string, s []string) string {
+	kl := len(key)
 	for _, kv := range s {
 		if kv == "" {
 			continue
 		}
 		kvl := len(kv)
-		if kvl > kl && kv[:kl] == UsernameKey && kv[kl] == '=' {
+		if kvl > kl && kv[:kl] == key && kv[kl] == '=' {
 			return kv[kl+1:]
 		}
 	}
@@ -2,7 +2,10 @@ package mdns
 
 import (
 	"context"
+	"github.com/MuhamedUsman/letshare/internal/network"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/netip"
 	"testing"
 )
 
@@ -11,14 +14,17 @@ func TestMDNSDiscoveryAndPublication(t *testing.T) {
 	instance := "TestInstance"
 	hostname := "TestHost.local"
 	username := "TestUsername"
+	fingerprint := "TestFingerprint"
 	port := uint16(8080)
+	ifaces, err := network.Select(nil)
+	require.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(t.Context())
 	defer cancel()
 
 	// Start publishing in background
 	go func() {
-		err := m.Publish(ctx, instance, hostname, username, port)
+		err := m.Publish(ctx, instance, hostname, username, fingerprint, port, ifaces)
 		assert.NoError(t, err, "Failed to publish mDNS service")
 	}()
 	go func() {
@@ -33,4 +39,13 @@ func TestMDNSDiscoveryAndPublication(t *testing.T) {
 	assert.Equal(t, entry.Hostname, hostname, "Expected hostname to match")
 	assert.Equal(t, entry.Port, port, "Expected port to match")
 	assert.Equal(t, entry.Owner, username, "Expected owner to match")
+	assert.Equal(t, entry.Fingerprint, fingerprint, "Expected fingerprint to match")
+}
+
+func TestPreferredAddr(t *testing.T) {
+	v6, v4 := netip.MustParseAddr("fe80::1%eth0"), netip.MustParseAddr("192.168.1.7")
+	assert.Equal(t, "192.168.1.7", preferredAddr([]netip.Addr{v6, v4}), "IPv4 must be preferred")
+	assert.Equal(t, "192.168.1.7", preferredAddr([]netip.Addr{netip.MustParseAddr("::ffff:192.168.1.7")}))
+	assert.Equal(t, "fe80::1%eth0", preferredAddr([]netip.Addr{v6}))
+	assert.Empty(t, preferredAddr(nil))
 }
@@ -1,12 +1,200 @@
 package network
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
-// GetOutboundIP gets the preferred outbound ip address of this machine
+// ErrNoInterface is returned if the machine has no network interface the server can be reached through.
+var ErrNoInterface = errors.New("no network interface is up, connect to a network first")
+
+// override are the interfaces picked on the command line, they take precedence over the preferences, see Override
+var override []string
+
+// Interface is a network interface the server can be reached through, along with its usable addresses.
+type Interface struct {
+	net.Interface
+	Addrs []netip.Addr
+}
+
+// String describes the interface, e.g. "wlan0 (192.168.1.7)".
+func (i Interface) String() string {
+	addrs := make([]string, len(i.Addrs))
+	for j, a := range i.Addrs {
+		addrs[j] = a.String()
+	}
+	return fmt.Sprintf("%s (%s)", i.Name, strings.Join(addrs, ", "))
+}
+
+// Override picks the interfaces for the rest of the run, regardless of the preferences,
+// it must be called at startup, before the interfaces are selected, see Select.
+func Override(specs ...string) {
+	override = specs
+}
+
+// Interfaces enumerates the network interfaces that are up, except the loopback, along with their addresses,
+// unlike GetOutboundIP, it needs no route to the internet, so it works on isolated networks as well.
+// IPv6 link-local addresses are left out, they are useless without a zone.
+func Interfaces() ([]Interface, error) {
+	netIfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("listing network interfaces: %w", err)
+	}
+	var ifaces []Interface
+	for _, ni := range netIfaces {
+		if ni.Flags&net.FlagUp == 0 || ni.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := ni.Addrs()
+		if err != nil {
+			continue
+		}
+		iface := Interface{Interface: ni}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip, ok := netip.AddrFromSlice(ipnet.IP)
+			if !ok {
+				continue
+			}
+			ip = ip.Unmap()
+			if ip.Is4() || ip.IsGlobalUnicast() {
+				iface.Addrs = append(iface.Addrs, ip)
+			}
+		}
+		if len(iface.Addrs) > 0 {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces, nil
+}
+
+// Select returns the interfaces to share on, as per the specs, each either the name of an interface, e.g. "wlan0",
+// or one of its addresses, e.g. "192.168.1.7", an interface picked by address keeps the addresses picked only.
+// Without specs, see Override, the interface of the default route is picked, or every interface, if there is no
+// default route, e.g. on a hotspot or a lab switch without a gateway.
+func Select(specs []string) ([]Interface, error) {
+	if len(override) > 0 {
+		specs = override
+	}
+	ifaces, err := Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	if len(ifaces) == 0 {
+		return nil, ErrNoInterface
+	}
+
+	specs = slices.DeleteFunc(slices.Clone(specs), func(s string) bool { return strings.TrimSpace(s) == "" })
+	if len(specs) == 0 {
+		if ip, err := GetOutboundIP(); err == nil {
+			for _, iface := range ifaces {
+				if slices.Contains(iface.Addrs, ip) {
+					return []Interface{{Interface: iface.Interface, Addrs: []netip.Addr{ip}}}, nil
+				}
+			}
+		}
+		return ifaces, nil // offline, reachable through any of them
+	}
+
+	var picked []Interface
+	add := func(iface Interface, addrs ...netip.Addr) {
+		i := slices.IndexFunc(picked, func(p Interface) bool { return p.Name == iface.Name })
+		if i < 0 {
+			picked = append(picked, Interface{Interface: iface.Interface})
+			i = len(picked) - 1
+		}
+		for _, a := range addrs {
+			if !slices.Contains(picked[i].Addrs, a) {
+				picked[i].Addrs = append(picked[i].Addrs, a)
+			}
+		}
+	}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		ip, err := netip.ParseAddr(spec)
+		i := slices.IndexFunc(ifaces, func(iface Interface) bool {
+			if err == nil {
+				return slices.Contains(iface.Addrs, ip)
+			}
+			return strings.EqualFold(iface.Name, spec)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("no network interface is up with the name or address %q", spec)
+		}
+		if err == nil {
+			add(ifaces[i], ip)
+		} else {
+			add(ifaces[i], ifaces[i].Addrs...)
+		}
+	}
+	return picked, nil
+}
+
+// Addrs returns the addresses of all the interfaces.
+func Addrs(ifaces []Interface) []netip.Addr {
+	var addrs []netip.Addr
+	for _, iface := range ifaces {
+		addrs = append(addrs, iface.Addrs...)
+	}
+	return addrs
+}
+
+// localAddrsTTL is how long the addresses of this machine are cached for, see IsLocal,
+// it is called on every request, the addresses are listed again once it passes, so a network change is caught.
+const localAddrsTTL = 5 * time.Second
+
+// localAddrs caches the addresses of this machine, see ownAddrs.
+var localAddrs struct {
+	sync.Mutex
+	addrs []netip.Addr
+	at    time.Time
+}
+
+// IsLocal reports whether the ip is one of this machine's own, the loopback included.
+func IsLocal(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return addr.IsLoopback() || slices.Contains(ownAddrs(), addr)
+}
+
+// ownAddrs returns the addresses of this machine, listed at most once every localAddrsTTL,
+// the last ones listed are kept if listing them fails.
+func ownAddrs() []netip.Addr {
+	localAddrs.Lock()
+	defer localAddrs.Unlock()
+	if time.Since(localAddrs.at) < localAddrsTTL {
+		return localAddrs.addrs
+	}
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return localAddrs.addrs
+	}
+	addrs := make([]netip.Addr, 0, len(ifaceAddrs))
+	for _, a := range ifaceAddrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			if local, ok := netip.AddrFromSlice(ipnet.IP); ok {
+				addrs = append(addrs, local.Unmap())
+			}
+		}
+	}
+	localAddrs.addrs, localAddrs.at = addrs, time.Now()
+	return addrs
+}
+
+// GetOutboundIP gets the preferred outbound ip address of this machine, i.e. the source address of the
+// default route, nothing is sent, but it fails without a default route, see Select for isolated networks.
 func GetOutboundIP() (netip.Addr, error) {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
 	if err != nil {
@@ -16,7 +204,7 @@ func GetOutboundIP() (netip.Addr, error) {
 	ip := conn.LocalAddr().(*net.UDPAddr).IP
 	addr, ok := netip.AddrFromSlice(ip)
 	if !ok {
-		return netip.Addr{}, fmt.Errorf("parsing addr: %v", err)
+		return netip.Addr{}, fmt.Errorf("parsing addr: %v", ip)
 	}
-	return addr, nil
+	return addr.Unmap(), nil
 }
@@ -0,0 +1,60 @@
+package network
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSelect(t *testing.T) {
+	ifaces, err := Interfaces()
+	require.NoError(t, err)
+	if len(ifaces) == 0 {
+		t.Skip("no network interface is up")
+	}
+	for _, iface := range ifaces {
+		assert.NotEmpty(t, iface.Addrs, iface.Name)
+	}
+
+	auto, err := Select(nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, Addrs(auto))
+
+	want := ifaces[0]
+	picked, err := Select([]string{" " + want.Name + " ", want.Addrs[0].String()})
+	require.NoError(t, err)
+	require.Len(t, picked, 1, "the same interface must be picked once")
+	assert.Equal(t, want.Addrs, picked[0].Addrs)
+
+	picked, err = Select([]string{want.Addrs[0].String()})
+	require.NoError(t, err)
+	assert.Equal(t, want.Addrs[:1], Addrs(picked), "an interface picked by address keeps that address only")
+
+	_, err = Select([]string{"no-such-iface0"})
+	assert.Error(t, err)
+
+	assert.True(t, IsLocal(want.Addrs[0].String()))
+	assert.True(t, IsLocal("127.0.0.1"))
+	assert.False(t, IsLocal("198.51.100.1"))
+	assert.False(t, IsLocal("not an ip"))
+}
+
+func TestIsLocal(t *testing.T) {
+	assert.True(t, IsLocal("127.0.0.1"))
+	assert.True(t, IsLocal("::1"))
+	assert.False(t, IsLocal("not an ip"))
+	assert.False(t, IsLocal("198.51.100.7"))
+
+	// the addresses are cached, till they go stale
+	localAddrs.Lock()
+	localAddrs.addrs = append(localAddrs.addrs, netip.MustParseAddr("198.51.100.7"))
+	localAddrs.Unlock()
+	assert.True(t, IsLocal("198.51.100.7"), "cached addresses must be used")
+
+	localAddrs.Lock()
+	localAddrs.at = time.Now().Add(-localAddrsTTL)
+	localAddrs.Unlock()
+	assert.False(t, IsLocal("198.51.100.7"), "stale addresses must be listed again")
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// accessRule matches clients by their IP, within a CIDR, or by name, i.e. the X-Requested-By header,
+// names are sent by letshare clients & can be spoofed, the IP rules are the ones to rely on.
+type accessRule struct {
+	prefix netip.Prefix // invalid for the name rules
+	name   string
+}
+
+func (ar accessRule) matches(ip netip.Addr, reqBy string) bool {
+	if ar.prefix.IsValid() {
+		return ip.IsValid() && ar.prefix.Contains(ip.Unmap())
+	}
+	return reqBy != "" && strings.EqualFold(ar.name, reqBy)
+}
+
+// parseAccessRule parses the rule, a CIDR, e.g. "10.1.0.0/16", an IP, e.g. "10.1.2.3", or a name.
+func parseAccessRule(rule string) (accessRule, error) {
+	rule = strings.TrimSpace(rule)
+	if strings.Contains(rule, "/") {
+		p, err := netip.ParsePrefix(rule)
+		if err != nil {
+			return accessRule{}, fmt.Errorf("invalid CIDR %q", rule)
+		}
+		return accessRule{prefix: p.Masked()}, nil
+	}
+	if ip, err := netip.ParseAddr(rule); err == nil {
+		ip = ip.Unmap()
+		return accessRule{prefix: netip.PrefixFrom(ip, ip.BitLen())}, nil
+	}
+	// a mistyped IP must not turn into a name, it would match no one
+	if strings.Trim(rule, "0123456789.") == "" || strings.Contains(rule, ":") {
+		return accessRule{}, fmt.Errorf("invalid IP %q", rule)
+	}
+	return accessRule{name: rule}, nil
+}
+
+func parseAccessRules(rules []string) ([]accessRule, error) {
+	var parsed []accessRule
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		ar, err := parseAccessRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, ar)
+	}
+	return parsed, nil
+}
+
+// ValidateAccessRules reports the first rule that is neither a CIDR, an IP nor a name, see config.ShareConfig.AllowList.
+func ValidateAccessRules(rules []string) error {
+	_, err := parseAccessRules(rules)
+	return err
+}
+
+// accessRules decide which clients may reach the server, the deny rules take precedence,
+// if there are allow rules, the clients matching none of them are denied as well.
+type accessRules struct {
+	allow, deny []accessRule
+	// err is why the rules are invalid, the server refuses to start with them, see Server.configureServer
+	err error
+}
+
+func newAccessRules(allow, deny []string) accessRules {
+	var ar accessRules
+	if ar.allow, ar.err = parseAccessRules(allow); ar.err != nil {
+		ar.err = fmt.Errorf("allowed clients: %w", ar.err)
+		return ar
+	}
+	if ar.deny, ar.err = parseAccessRules(deny); ar.err != nil {
+		ar.err = fmt.Errorf("blocked clients: %w", ar.err)
+	}
+	return ar
+}
+
+func (a accessRules) allows(ip netip.Addr, reqBy string) bool {
+	matches := func(ar accessRule) bool { return ar.matches(ip, reqBy) }
+	if slices.ContainsFunc(a.deny, matches) {
+		return false
+	}
+	return len(a.allow) == 0 || slices.ContainsFunc(a.allow, matches)
+}
+
+// filterClients rejects the clients the access rules do not allow, each is logged once,
+// requests from the host's own machine are never rejected.
+func (s *Server) filterClients(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.access.allow) == 0 && len(s.access.deny) == 0 || !shouldLogReq(r.RemoteAddr) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip, _ := netip.ParseAddr(remoteIP(r))
+		reqBy := r.Header.Get("X-Requested-By")
+		if s.access.allows(ip, reqBy) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		k := "rejected:" + ip.String() + ":" + reqBy
+		if s.firstLog(k) {
+			s.log.info("Rejected by the access rules", "ReqBy", requestedBy(r), "IP", ip.String())
+		}
+		s.notAllowedResponse(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateAccessRules(t *testing.T) {
+	assert.NoError(t, ValidateAccessRules([]string{"10.1.0.0/16", " 10.1.2.3 ", "fd00::/8", "::1", "alice", ""}))
+	assert.Error(t, ValidateAccessRules([]string{"10.1.0.0/33"}))
+	assert.Error(t, ValidateAccessRules([]string{"10.1.2.300"}), "a mistyped IP must not turn into a name")
+}
+
+func TestFilterClients(t *testing.T) {
+	cfg := config.ShareConfig{
+		AllowList: []string{"198.51.100.0/24", "alice"},
+		DenyList:  []string{"198.51.100.66", "mallory"},
+	}
+	logCh := make(chan Log, 10)
+	s := New(cfg, logCh, make(chan int, 10))
+	defer s.StopCtxCancel()
+	require.NoError(t, s.access.err)
+	h := s.filterClients(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(ip, reqBy string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = ip + ":50000"
+		if reqBy != "" {
+			r.Header.Set("X-Requested-By", reqBy)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("198.51.100.7", ""), "within the allowed subnet")
+	assert.Equal(t, http.StatusOK, serve("203.0.113.7", "Alice"), "allowed by name")
+	assert.Equal(t, http.StatusForbidden, serve("203.0.113.7", ""), "matches no allow rule")
+	assert.Equal(t, http.StatusForbidden, serve("198.51.100.66", ""), "denied, even though allowed")
+	assert.Equal(t, http.StatusForbidden, serve("198.51.100.7", "mallory"), "denied by name")
+	assert.Equal(t, http.StatusOK, serve("127.0.0.1", ""), "the host's own machine is never rejected")
+
+	// each rejected client is logged once
+	assert.Equal(t, http.StatusForbidden, serve("203.0.113.7", ""))
+	assert.Len(t, logCh, 3)
+
+	s = New(config.ShareConfig{DenyList: []string{"10.1.2.300"}}, logCh, make(chan int, 10))
+	defer s.StopCtxCancel()
+	_, _, err := s.configureServer()
+	assert.Error(t, err, "the server must not start with invalid rules")
+}
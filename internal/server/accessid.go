@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	accessKeyFile = "access.key"
+	accessKeySize = 32
+	// accessIDSize is the bytes of the HMAC kept in an access id, 128 bits can't be guessed
+	accessIDSize = 16
+	// snippetIDPrefix keeps a snippet from taking the access id of a file with the text as its path
+	snippetIDPrefix = "snippet:"
+)
+
+var (
+	accessKeyMu sync.Mutex
+	accessKey   []byte
+	// accessIDEncoding is lowercase, so the ids read well in URLs
+	accessIDEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+)
+
+// loadAccessKey returns the per-device key the access ids are derived with, persisted under config.GetDir(),
+// so a path gets the same id every time it is shared, & the resume URLs of its downloads keep working.
+func loadAccessKey() ([]byte, error) {
+	accessKeyMu.Lock()
+	defer accessKeyMu.Unlock()
+	if accessKey != nil {
+		return accessKey, nil
+	}
+	dir, err := config.GetDir()
+	if err != nil {
+		return nil, err
+	}
+	p := filepath.Join(dir, accessKeyFile)
+	key, err := os.ReadFile(p)
+	// a missing or corrupt key is of no use, so we'll (over)write it, the ids of the previous shares change
+	if err != nil || len(key) != accessKeySize {
+		key = newAccessKey()
+		if err = os.WriteFile(p, key, 0o600); err != nil {
+			return nil, fmt.Errorf("writing access key: %w", err)
+		}
+	}
+	accessKey = key
+	return accessKey, nil
+}
+
+// newAccessKey returns a random key, the server derives the ids with one till it loads the persisted key.
+func newAccessKey() []byte {
+	key := make([]byte, accessKeySize)
+	_, _ = rand.Read(key) // never returns an error
+	return key
+}
+
+// deriveAccessID returns the n-th access id of the name, the HMAC of it, so the id neither
+// reveals the name nor can be guessed without the key, n > 0 is only derived on a collision.
+func deriveAccessID(key []byte, name string, n int) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	if n > 0 {
+		mac.Write([]byte("\x00" + strconv.Itoa(n)))
+	}
+	return accessIDEncoding.EncodeToString(mac.Sum(nil)[:accessIDSize])
+}
+
+// accessID returns the access id of the file path, or the snippet's prefixed text, see snippetIDPrefix,
+// shared reports whether it is already shared at the id, an id taken by another file or snippet is never reused,
+// the next one of the name is derived instead, the caller must hold Server.mu.
+func (s *Server) accessID(name string) (id string, shared bool) {
+	for n := 0; ; n++ {
+		id = deriveAccessID(s.accessKey, name, n)
+		p, isFile := s.FilePaths[id]
+		sn, isSnippet := s.snippets[id]
+		switch {
+		case isFile && p == name, isSnippet && snippetIDPrefix+sn.text == name:
+			return id, true
+		case !isFile && !isSnippet:
+			return id, false
+		}
+	}
+}
+
+// setAccessKey derives the access ids with the key, the already shared files are moved to their new ids.
+func (s *Server) setAccessKey(key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths, snippets := s.FilePaths, s.snippets
+	s.accessKey = key
+	s.FilePaths, s.snippets = make(map[string]string, len(paths)), make(map[string]*snippet, len(snippets))
+	for _, p := range paths {
+		id, _ := s.accessID(p)
+		s.FilePaths[id] = p
+	}
+	for _, sn := range snippets {
+		id, _ := s.accessID(snippetIDPrefix + sn.text)
+		s.snippets[id] = sn
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestServer_accessID(t *testing.T) {
+	newServer := func() *Server {
+		s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+		t.Cleanup(s.StopCtxCancel)
+		return s
+	}
+	key := newAccessKey()
+	a, b := newServer(), newServer()
+	a.setFilePaths("/srv/report.pdf")
+	b.setFilePaths("/srv/report.pdf")
+	assert.NotEqual(t, a.sharedFiles(), b.sharedFiles(), "ids must not be guessable without the key")
+
+	a.setAccessKey(key)
+	b.setAccessKey(key)
+	require.Equal(t, a.sharedFiles(), b.sharedFiles(), "the same path must get the same id with the same key")
+	for id := range a.sharedFiles() {
+		assert.Len(t, id, 26)
+		assert.NotContains(t, id, "report")
+	}
+
+	// another path taking the id of the path, e.g. a hash collision
+	s := newServer()
+	s.setAccessKey(key)
+	taken := deriveAccessID(key, "/srv/report.pdf", 0)
+	s.FilePaths[taken] = "/srv/other.pdf"
+	s.setFilePaths("/srv/report.pdf")
+	assert.Len(t, s.sharedFiles(), 2, "a taken id must never be overwritten")
+	assert.Equal(t, "/srv/other.pdf", s.sharedFiles()[taken])
+
+	id, err := s.AddSnippet("/srv/report.pdf")
+	require.NoError(t, err)
+	assert.Len(t, s.sharedFiles(), 2)
+	assert.NotContains(t, s.sharedFiles(), id, "a snippet must not take the id of a file with the text as its path")
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// approvalTimeout is how long a request waits for the host to decide, before it is denied.
+const approvalTimeout = time.Minute
+
+// ApprovalReq asks the host to allow a client, the first time it hits the share, see Server.Approvals.
+type ApprovalReq struct {
+	// ReqBy is the X-Requested-By header value, or the IP for browsers
+	ReqBy string
+	// IP of the client, decisions apply to every request from it
+	IP string
+	a  *approval
+}
+
+// Allow lets the client access the share, if remember is false, only the held requests are allowed
+// & the host is asked again on the next request, otherwise the client is allowed for the whole session.
+func (r ApprovalReq) Allow(remember bool) {
+	r.a.decide(true, remember)
+}
+
+// Deny responds to the client with 403 Forbidden, if remember is false, only the held requests are denied
+// & the host is asked again on the next request, otherwise the client is denied for the whole session.
+func (r ApprovalReq) Deny(remember bool) {
+	r.a.decide(false, remember)
+}
+
+// Done is closed once the request is decided, by the host, or by the server if the host takes too long.
+func (r ApprovalReq) Done() <-chan struct{} {
+	return r.a.done
+}
+
+// approval is the host's decision for a single client, requests from the
+// same client share it, so the host is asked only once at a time.
+type approval struct {
+	once     sync.Once
+	done     chan struct{}
+	allowed  bool
+	remember bool
+	forget   func() // removes the approval from Server.approvals
+}
+
+func (a *approval) decide(allowed, remember bool) {
+	a.once.Do(func() {
+		a.allowed, a.remember = allowed, remember
+		if !remember {
+			a.forget()
+		}
+		close(a.done)
+	})
+}
+
+// Approvals returns the channel the approval requests are sent on, nil if the
+// server does not ask before sharing, the host must decide on every request received.
+func (s *Server) Approvals() <-chan ApprovalReq {
+	return s.approvalCh
+}
+
+// requireApproval holds the requests from unknown clients, until the host allows or denies them,
+// requests from the host's own machine are always allowed.
+func (s *Server) requireApproval(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		if s.approvalCh == nil || !shouldLogReq(r.RemoteAddr) || net.ParseIP(ip).IsLoopback() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		a, isNew := s.approvalFor(ip)
+		if isNew {
+			req := ApprovalReq{ReqBy: requestedBy(r), IP: ip, a: a}
+			s.log.info("Waiting for your approval", "ReqBy", req.ReqBy)
+			select {
+			case s.approvalCh <- req:
+			case <-r.Context().Done():
+				a.decide(false, false)
+			case <-s.StopCtx.Done():
+				a.decide(false, false)
+			}
+		}
+
+		t := time.NewTimer(approvalTimeout)
+		defer t.Stop()
+		select {
+		case <-a.done:
+		case <-t.C:
+			a.decide(false, false) // the host is away, ask again next time
+		case <-r.Context().Done():
+			return // the client gave up
+		case <-s.StopCtx.Done():
+			a.decide(false, false)
+		}
+
+		if !a.allowed {
+			s.notApprovedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// approvalFor returns the approval of the client, isNew reports whether
+// the host is yet to be asked, i.e. there is neither a decision nor a pending request.
+func (s *Server) approvalFor(ip string) (a *approval, isNew bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.approvals[ip]; ok {
+		return a, false
+	}
+	a = &approval{done: make(chan struct{})}
+	a.forget = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.approvals[ip] == a {
+			delete(s.approvals, ip)
+		}
+	}
+	s.approvals[ip] = a
+	return a, true
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireApproval(t *testing.T) {
+	s := New(config.ShareConfig{AskBeforeSharing: true}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	h := s.requireApproval(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// the host allows 198.51.100.1 for the session, 198.51.100.3 only once & denies 198.51.100.2 only once
+	go func() {
+		for req := range s.Approvals() {
+			switch req.IP {
+			case "198.51.100.1":
+				req.Allow(true)
+			case "198.51.100.3":
+				req.Allow(false)
+			default:
+				req.Deny(false)
+			}
+		}
+	}()
+
+	serve := func(ip string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = ip + ":50000"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("198.51.100.1"))
+	assert.Equal(t, http.StatusForbidden, serve("198.51.100.2"))
+	assert.Equal(t, http.StatusOK, serve("198.51.100.3"))
+	assert.Equal(t, http.StatusOK, serve("127.0.0.1"), "the host's own machine is never asked")
+
+	// decisions remembered for the session are not asked again
+	s.mu.Lock()
+	_, remembered := s.approvals["198.51.100.1"]
+	_, forgotten := s.approvals["198.51.100.2"]
+	_, allowedOnce := s.approvals["198.51.100.3"]
+	s.mu.Unlock()
+	assert.True(t, remembered)
+	assert.False(t, forgotten)
+	assert.False(t, allowedOnce, "clients allowed once must be asked again")
+	assert.Equal(t, http.StatusOK, serve("198.51.100.1"))
+}
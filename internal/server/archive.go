@@ -0,0 +1,226 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"github.com/MuhamedUsman/letshare/internal/zipr"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const defaultArchiveName = "letshare.zip"
+
+// archiveHandler streams a zip archive of the selected shared files, the archive is built
+// on the fly while it is being sent, so the download starts right away & nothing is written to disk.
+// Each "ids" query value is an access id, optionally followed by a path inside a shared directory,
+// e.g. ?ids=123&ids=456/src/main.go, plain access ids may also be comma separated, e.g. ?ids=123,456.
+//
+// Returns:
+//   - Success (200 OK): The zip archive, streamed with chunked transfer encoding
+//   - Error (400 Bad Request): If no files were selected
+//   - Error (404 Not Found): If any of the selected files does not exist
+//   - Error (410 Gone): If any of the selected files has expired or reached its download limit
+func (s *Server) archiveHandler(w http.ResponseWriter, r *http.Request) {
+	ids := archiveIDs(r.URL.Query()["ids"])
+	if len(ids) == 0 {
+		s.badRequestResponse(w, r, "no files were selected, at least one ids query parameter is required")
+		return
+	}
+
+	entries := make([]zipr.Entry, 0, len(ids))
+	names := make(map[string]struct{}, len(ids))
+	// shared files/directories archived as a whole, count as their complete download
+	whole := make(map[string]string)
+	for _, id := range ids {
+		e, closeFn, err := s.archiveEntry(id)
+		if errors.Is(err, errLimitReached) {
+			s.goneResponse(w, r)
+			return
+		}
+		if err != nil {
+			s.notFoundResponse(w, r)
+			return
+		}
+		defer closeFn()
+		if accessID, rel, _ := strings.Cut(id, "/"); strings.Trim(rel, "/") == "" {
+			whole[accessID] = e.Name
+		}
+		e.Name = uniqueArchiveName(e.Name, names)
+		entries = append(entries, e)
+	}
+
+	cfg := getConfig()
+	algo := zipr.Store
+	if cfg.Share.Compression {
+		algo = zipr.Deflate
+	}
+	archiveName := cfg.Share.SharedZipName
+	if archiveName == "" {
+		archiveName = defaultArchiveName
+	}
+
+	// the whole ones are downloaded with the archive, see Server.reserveDownload
+	finishes := make([]func(bool), 0, len(whole))
+	finishAll := func(completed bool) {
+		for _, finish := range finishes {
+			finish(completed)
+		}
+	}
+	for id, name := range whole {
+		finish, ok := s.reserveDownload(r, id, name)
+		if !ok {
+			finishAll(false)
+			s.goneResponse(w, r)
+			return
+		}
+		finishes = append(finishes, finish)
+	}
+
+	reqBy := requestedBy(r)
+	logReq := shouldLogReq(r.RemoteAddr)
+	if logReq {
+		s.log.info("Serving archive", "Files", len(entries), "ReqBy", reqBy)
+	}
+	s.incActiveConn()       // this doesn't block
+	defer s.decActiveConn() // this blocks
+
+	auditFile(r, archiveName)
+	w, done := s.trackTransfer(w, r, archiveName, -1) // the size is only known once the archive is built
+	defer done()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+archiveName+"\"")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	// the zipper stops reading as soon as the client goes away
+	z := zipr.New(r.Context(), nil, nil, algo)
+	err := z.WriteArchive(w, entries...)
+	if err != nil && logReq {
+		// headers are already sent, the client is left with a truncated archive
+		s.log.info("Archive was interrupted", "ReqBy", reqBy, "Err", err.Error())
+	}
+	finishAll(err == nil)
+}
+
+// archiveEntry resolves a single "ids" value, see Server.archiveHandler, files inside shared
+// directories are read through os.Root, so nothing outside the shared directory can be reached.
+// The returned func must be called once the entry has been archived.
+func (s *Server) archiveEntry(id string) (zipr.Entry, func(), error) {
+	noop := func() {}
+	accessID, rel, _ := strings.Cut(id, "/")
+	filePath, ok := s.filePath(accessID)
+	if !ok {
+		return zipr.Entry{}, noop, fs.ErrNotExist
+	}
+	if !s.available(accessID) {
+		return zipr.Entry{}, noop, errLimitReached
+	}
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return zipr.Entry{}, noop, err
+	}
+
+	if !stat.IsDir() {
+		if rel != "" { // files have no children
+			return zipr.Entry{}, noop, fs.ErrNotExist
+		}
+		name := filepath.Base(filePath)
+		e := zipr.Entry{FS: os.DirFS(filepath.Dir(filePath)), Path: name, Name: name}
+		return e, noop, nil
+	}
+
+	rel = strings.Trim(rel, "/")
+	if rel == "" {
+		rel = "."
+	}
+	if !fs.ValidPath(rel) {
+		return zipr.Entry{}, noop, fs.ErrNotExist
+	}
+	root, err := os.OpenRoot(filePath)
+	if err != nil {
+		return zipr.Entry{}, noop, err
+	}
+	closeFn := func() { _ = root.Close() }
+	if _, err = root.Stat(rel); err != nil {
+		closeFn()
+		return zipr.Entry{}, noop, err
+	}
+	name := filepath.Base(filePath)
+	if rel != "." {
+		name = path.Base(rel)
+	}
+	return zipr.Entry{FS: root.FS(), Path: rel, Name: name}, closeFn, nil
+}
+
+// archiveIDs flattens the "ids" query values, splitting the comma separated access ids,
+// values with a path are kept as is, since the path itself may contain commas, duplicates are dropped.
+func archiveIDs(values []string) []string {
+	ids := make([]string, 0, len(values))
+	add := func(id string) {
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	for _, v := range values {
+		if strings.Contains(v, "/") {
+			add(v)
+			continue
+		}
+		for id := range strings.SplitSeq(v, ",") {
+			add(strings.TrimSpace(id))
+		}
+	}
+	return ids
+}
+
+// uniqueArchiveName suffixes the name with a counter if it is already taken,
+// e.g. two shared directories both named "build" become "build" & "build (2)",
+// unlike file.UniqueName the same names always get the same suffixes, so the WebDAV paths stay put.
+func uniqueArchiveName(name string, taken map[string]struct{}) string {
+	ext := path.Ext(name)
+	if name == ext {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := taken[unique]; !ok {
+			break
+		}
+		unique = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	taken[unique] = struct{}{}
+	return unique
+}
+
+// archiveID returns the "ids" query value selecting the file, see Server.archiveHandler.
+func archiveID(fi *domain.FileInfo) string {
+	if fi.Path == "" || fi.Path == "." {
+		return fi.AccessID
+	}
+	return fi.AccessID + "/" + fi.Path
+}
+
+// archiveURL returns the URL to download all the files as a single archive,
+// snippets are left out, empty if there is nothing to archive.
+func archiveURL(files []*domain.FileInfo) string {
+	q := make(url.Values)
+	for _, fi := range files {
+		if fi.Type != domain.TypeSnippet {
+			q.Add("ids", archiveID(fi))
+		}
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "/archive?" + q.Encode()
+}
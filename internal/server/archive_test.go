@@ -0,0 +1,66 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveIDs(t *testing.T) {
+	values := []string{"1,2, 3", "4/src/a,b.go", ",", "5", "2", "4/src/a,b.go"}
+	expected := []string{"1", "2", "3", "4/src/a,b.go", "5"}
+	assert.Equal(t, expected, archiveIDs(values))
+}
+
+func TestUniqueArchiveName(t *testing.T) {
+	taken := make(map[string]struct{})
+	for _, tc := range []struct{ name, want string }{
+		{"build", "build"},
+		{"build", "build (2)"},
+		{"build", "build (3)"},
+		{"notes.txt", "notes.txt"},
+		{"notes.txt", "notes (2).txt"},
+		{".env", ".env"},
+		{".env", ".env (2)"},
+	} {
+		assert.Equal(t, tc.want, uniqueArchiveName(tc.name, taken))
+	}
+}
+
+// transfersRecorder records the transfers in progress, as the response body is written.
+type transfersRecorder struct {
+	*httptest.ResponseRecorder
+	s    *Server
+	seen []Transfer
+}
+
+func (tr *transfersRecorder) Write(p []byte) (int, error) {
+	if tr.seen == nil {
+		tr.seen = tr.s.Transfers()
+	}
+	return tr.ResponseRecorder.Write(p)
+}
+
+func TestServer_ArchiveHandler(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	p := filepath.Join(t.TempDir(), "report.txt")
+	require.NoError(t, os.WriteFile(p, []byte("report"), 0o644))
+	s.setFilePaths(p)
+	id, _ := s.accessID(p)
+
+	r := httptest.NewRequest(http.MethodGet, "/archive?ids="+id, nil)
+	r.Host = "localhost"
+	r.RemoteAddr = "198.51.100.7:50000"
+	w := &transfersRecorder{ResponseRecorder: httptest.NewRecorder(), s: s}
+	s.routes().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, w.seen, 1, "the archive must be tracked while it is sent")
+	assert.Equal(t, int64(-1), w.seen[0].Size, "the size of an archive is unknown")
+	assert.Empty(t, s.Transfers())
+}
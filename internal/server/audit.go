@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"github.com/MuhamedUsman/letshare/internal/audit"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// auditFileKey is the request context key holding the file the request is about, see auditFile.
+type auditFileKey struct{}
+
+// withAuditFile returns the request with room for the file it is about, filled in by the handler, see auditFile.
+func withAuditFile(r *http.Request) (*http.Request, *string) {
+	file := new(string)
+	return r.WithContext(context.WithValue(r.Context(), auditFileKey{}, file)), file
+}
+
+// auditFile records the file the request is about, the file downloaded, uploaded or archived, in the audit log.
+func auditFile(r *http.Request, files ...string) {
+	if file, ok := r.Context().Value(auditFileKey{}).(*string); ok {
+		*file = strings.Join(files, ", ")
+	}
+}
+
+// audit appends the served request to the audit log, if it is open, the static assets of the web UI are left out,
+// they say nothing about who fetched what.
+func (s *Server) audit(r *http.Request, file string, status int, written int64, took time.Duration) {
+	if s.auditLog == nil || strings.HasPrefix(r.URL.Path, "/static/") {
+		return
+	}
+	s.mu.Lock()
+	instance := s.instance
+	s.mu.Unlock()
+	err := s.auditLog.Append(audit.Entry{
+		Time:       time.Now(),
+		Instance:   instance,
+		IP:         remoteIP(r),
+		ReqBy:      r.Header.Get("X-Requested-By"),
+		Method:     r.Method,
+		Route:      r.Pattern,
+		Path:       r.URL.Path,
+		File:       file,
+		Bytes:      written,
+		Status:     status,
+		DurationMs: took.Milliseconds(),
+	})
+	if err != nil {
+		s.log.info("Audit log write failed", "Err", err)
+	}
+}
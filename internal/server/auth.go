@@ -0,0 +1,153 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sessionCookie = "letshare_session"
+	// after maxSecretAttempts wrong attempts, the IP is locked out for secretLockout
+	maxSecretAttempts = 5
+	secretLockout     = time.Minute
+)
+
+type secretAttempts struct {
+	n           int
+	lockedUntil time.Time
+}
+
+type loginTemplateData struct {
+	HostUsername string
+	Next         string
+	Error        string
+}
+
+// loginPageHandler renders the login page for browsers, when the share is protected by a secret.
+func (s *Server) loginPageHandler(w http.ResponseWriter, r *http.Request) {
+	next := sanitizeNext(r.URL.Query().Get("next"))
+	if s.secret == "" || s.hasValidSession(r) {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	s.renderLogin(w, r, http.StatusOK, next, "")
+}
+
+// loginHandler validates the submitted secret, on success a session cookie
+// is set and the browser is redirected to where it was heading.
+//
+// Returns:
+//   - Success (303 See Other): Redirects to the "next" form value
+//   - Error (401 Unauthorized): Login page with an error, if the secret is wrong
+//   - Error (429 Too Many Requests): Login page with an error, if the IP is locked out
+func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 4<<10) // 4 KiB is more than enough for a secret
+	if err := r.ParseForm(); err != nil {
+		s.badRequestResponse(w, r, "malformed login form")
+		return
+	}
+	next := sanitizeNext(r.PostForm.Get("next"))
+	if s.secret == "" {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	ip := remoteIP(r)
+	if s.isLockedOut(ip) {
+		s.renderLogin(w, r, http.StatusTooManyRequests, next, "Too many wrong attempts, try again in a minute.")
+		return
+	}
+	if !s.isValidSecret(r.PostForm.Get("secret")) {
+		s.recordFailedAttempt(ip, requestedBy(r))
+		s.renderLogin(w, r, http.StatusUnauthorized, next, "Wrong secret, ask the host for the right one.")
+		return
+	}
+
+	s.resetFailedAttempts(ip)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    s.sessionToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (s *Server) renderLogin(w http.ResponseWriter, r *http.Request, status int, next, errTxt string) {
+	data := loginTemplateData{
+		HostUsername: getConfig().Personal.Username,
+		Next:         next,
+		Error:        errTxt,
+	}
+	if err := s.render(w, status, "login", data); err != nil {
+		s.serverErrorResponse(w, r)
+	}
+}
+
+func (s *Server) isValidSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(s.secret)) == 1
+}
+
+func (s *Server) hasValidSession(r *http.Request) bool {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(s.sessionToken)) == 1
+}
+
+func (s *Server) isLockedOut(ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.failedAttempts[ip]
+	return ok && time.Now().Before(a.lockedUntil)
+}
+
+func (s *Server) recordFailedAttempt(ip, reqBy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.failedAttempts[ip]
+	if !ok {
+		a = new(secretAttempts)
+		s.failedAttempts[ip] = a
+	}
+	a.n++
+	s.log.info("Wrong share secret", "ReqBy", reqBy, "Attempt", a.n)
+	if a.n >= maxSecretAttempts {
+		a.n = 0
+		a.lockedUntil = time.Now().Add(secretLockout)
+		s.log.info("Locked out for a minute", "ReqBy", reqBy)
+	}
+}
+
+func (s *Server) resetFailedAttempts(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failedAttempts, ip)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// secretToken returns the secret sent as the bearer token, or as the basic auth password by
+// the file managers mounting the share over WebDAV, any username goes, see Server.davHandler.
+func secretToken(r *http.Request) (string, bool) {
+	if token, ok := bearerToken(r); ok {
+		return token, true
+	}
+	_, password, ok := r.BasicAuth()
+	return password, ok
+}
+
+// sanitizeNext only allows local paths, so the login page can't be used as an open redirect.
+func sanitizeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
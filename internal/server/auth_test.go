@@ -0,0 +1,111 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeNext(t *testing.T) {
+	tests := map[string]string{
+		"":                    "/",
+		"/":                   "/",
+		"/1234":               "/1234",
+		"//evil.com":          "/",
+		"/\\evil.com":         "/",
+		"https://evil.com/":   "/",
+		"javascript:alert(1)": "/",
+	}
+	for next, want := range tests {
+		assert.Equal(t, want, sanitizeNext(next), "next %q", next)
+	}
+}
+
+func TestServer_RequireSecret(t *testing.T) {
+	s := New(config.ShareConfig{Secret: "lab-secret"}, make(chan Log, 100), make(chan int, 10))
+	defer s.StopCtxCancel()
+	h := s.routes()
+
+	serve := func(ip, token string, json bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = "localhost"
+		r.RemoteAddr = ip + ":50000"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		if json {
+			r.Header.Set("Accept", "application/json")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("198.51.100.7", "", true).Code)
+	w := serve("198.51.100.7", "", false)
+	assert.Equal(t, http.StatusSeeOther, w.Code, "browsers are sent to the login page")
+	assert.Equal(t, "/login?next=%2F", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusUnauthorized, serve("198.51.100.7", "wrong", true).Code)
+	assert.Equal(t, http.StatusOK, serve("198.51.100.7", "lab-secret", true).Code)
+
+	for range maxSecretAttempts {
+		assert.Equal(t, http.StatusUnauthorized, serve("198.51.100.8", "wrong", true).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve("198.51.100.8", "lab-secret", true).Code, "a locked out IP must be turned away, even with the secret")
+	assert.Equal(t, http.StatusOK, serve("198.51.100.9", "lab-secret", true).Code, "only the IP guessing is locked out")
+}
+
+func TestServer_LoginHandler(t *testing.T) {
+	s := New(config.ShareConfig{Secret: "lab-secret"}, make(chan Log, 100), make(chan int, 10))
+	defer s.StopCtxCancel()
+	h := s.routes()
+
+	login := func(ip, secret string) *httptest.ResponseRecorder {
+		form := url.Values{"secret": {secret}, "next": {"/1234"}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Host = "localhost"
+		r.RemoteAddr = ip + ":50000"
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := login("198.51.100.7", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies(), "a wrong secret must not get a session")
+
+	w = login("198.51.100.7", "lab-secret")
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/1234", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, sessionCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "localhost"
+	r.RemoteAddr = "198.51.100.7:50000"
+	r.Header.Set("Accept", "application/json")
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, "the session cookie must be accepted")
+
+	r.Header.Set("Cookie", sessionCookie+"=forged")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	for range maxSecretAttempts {
+		assert.Equal(t, http.StatusUnauthorized, login("198.51.100.8", "wrong").Code)
+	}
+	w = login("198.51.100.8", "lab-secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "the lockout must kick in after maxSecretAttempts")
+	assert.Empty(t, w.Result().Cookies())
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/MuhamedUsman/letshare/internal/throttle"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// SetRateLimits changes the bandwidth limits of the running server as per the ShareConfig,
+// transfers already in progress are slowed down (or sped up) right away.
+func (s *Server) SetRateLimits(cfg config.ShareConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimiter.SetRate(kbps(cfg.RateLimit))
+	s.clientRate = kbps(cfg.ClientRateLimit)
+	for _, l := range s.clientLimiters {
+		l.SetRate(s.clientRate)
+	}
+	s.log.info("Speed limits were updated", "Server", rateString(cfg.RateLimit), "PerClient", rateString(cfg.ClientRateLimit))
+}
+
+func rateString(kb int) string {
+	if kb == 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(kb) + " KB/s"
+}
+
+// clientLimiter returns the limiter shared by all the requests from the ip.
+func (s *Server) clientLimiter(ip string) *throttle.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.clientLimiters[ip]
+	if !ok {
+		l = throttle.NewLimiter(s.clientRate)
+		s.clientLimiters[ip] = l
+	}
+	return l
+}
+
+// limitBandwidth throttles the response bodies, to the server wide & per client rate limits,
+// see Server.SetRateLimits, sendfile is kept while both are unlimited, see throttledWriter.ReadFrom.
+func (s *Server) limitBandwidth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiters := []*throttle.Limiter{s.rateLimiter, s.clientLimiter(remoteIP(r))}
+		tw := throttle.NewWriter(r.Context(), w, limiters...)
+		next.ServeHTTP(&throttledWriter{ResponseWriter: w, w: tw, limiters: limiters}, r)
+	})
+}
+
+// throttledWriter writes the response body through a throttle writer.
+type throttledWriter struct {
+	http.ResponseWriter
+	w        io.Writer
+	limiters []*throttle.Limiter
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	return tw.w.Write(p)
+}
+
+// ReadFrom sends the chunks with sendfile while the limiters are unlimited, as it would bypass the throttling,
+// the limits are checked for every chunk, so the ones set meanwhile apply to the transfers in progress as well.
+func (tw *throttledWriter) ReadFrom(src io.Reader) (int64, error) {
+	return copyInChunks(src, func(chunk io.Reader) (int64, error) {
+		if rf, ok := tw.ResponseWriter.(io.ReaderFrom); ok && tw.unlimited() {
+			return rf.ReadFrom(chunk)
+		}
+		return io.Copy(tw.w, chunk)
+	})
+}
+
+func (tw *throttledWriter) unlimited() bool {
+	return !slices.ContainsFunc(tw.limiters, func(l *throttle.Limiter) bool { return l.Rate() > 0 })
+}
+
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// kbps converts a rate limit in KB/s to bytes per second.
+func kbps(kb int) int64 {
+	return int64(kb) << 10
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/MuhamedUsman/letshare/internal/throttle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readerFromRecorder records whether the response body reached io.ReaderFrom, i.e. sendfile.
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (rr *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	rr.readFrom = true
+	return io.Copy(rr.ResponseRecorder, src)
+}
+
+func TestServer_LimitBandwidth(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	p := filepath.Join(t.TempDir(), "movie.mkv")
+	content := strings.Repeat("frame", 1000)
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	s.setFilePaths(p)
+	var id string
+	for id = range s.sharedFiles() {
+	}
+	h := s.routes()
+
+	download := func() *readerFromRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		r.Host = "localhost"
+		r.RemoteAddr = "198.51.100.7:50000"
+		w := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := download()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+	assert.True(t, w.readFrom, "an unlimited download must keep sendfile")
+
+	s.SetRateLimits(config.ShareConfig{ClientRateLimit: 1 << 20})
+	w = download()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+	assert.False(t, w.readFrom, "sendfile would bypass the throttling")
+}
+
+// limitingRecorder sets a rate limit once the first chunk is sent with sendfile, i.e. during the transfer.
+type limitingRecorder struct {
+	*httptest.ResponseRecorder
+	limiter  *throttle.Limiter
+	readFrom int
+}
+
+func (lr *limitingRecorder) ReadFrom(src io.Reader) (int64, error) {
+	lr.readFrom++
+	lr.limiter.SetRate(256 << 10)
+	return io.Copy(lr.ResponseRecorder, src)
+}
+
+func TestThrottledWriter_ReadFrom(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "movie.mkv")
+	require.NoError(t, os.WriteFile(p, make([]byte, transferChunkSize+64<<10), 0o644))
+	f, err := os.Open(p)
+	require.NoError(t, err)
+	defer f.Close()
+
+	l := throttle.NewLimiter(0)
+	w := &limitingRecorder{ResponseRecorder: httptest.NewRecorder(), limiter: l}
+	tw := &throttledWriter{ResponseWriter: w, w: throttle.NewWriter(t.Context(), w, l), limiters: []*throttle.Limiter{l}}
+	start := time.Now()
+	n, err := tw.ReadFrom(f)
+	require.NoError(t, err)
+	assert.Equal(t, int64(transferChunkSize+64<<10), n)
+	assert.Equal(t, 1, w.readFrom, "the chunks after the limit is set must not use sendfile")
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond, "the limit must apply to the transfer in progress")
+}
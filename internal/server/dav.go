@@ -0,0 +1,414 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"golang.org/x/net/webdav"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// davPrefix is where the share is mounted over WebDAV, e.g. http://instance.local/dav/
+const davPrefix = "/dav"
+
+var (
+	// davReadMethods are served to every client, the share is mounted read-only
+	davReadMethods = []string{http.MethodOptions, http.MethodGet, http.MethodHead, "PROPFIND"}
+	// davWriteMethods are only served if the host accepts uploads, see davFS.OpenFile
+	davWriteMethods = []string{http.MethodPut, "PROPPATCH", "LOCK", "UNLOCK"}
+)
+
+// davClientKey is the request context key holding the davClient.
+type davClientKey struct{}
+
+// davClient is the client of the mount, its uploads are only listed to the same IP.
+type davClient struct {
+	ip, reqBy string
+	// shared returns the shared files at the root of the mount, listed once per request, see Server.davSharedEntries
+	shared func() map[string]davEntry
+}
+
+// davEntry is an item at the root of the mounted share, a shared file or directory, or a file uploaded by the client.
+type davEntry struct {
+	accessID string // empty for the uploads
+	path     string
+}
+
+// davHandler serves the share over WebDAV, so receivers can mount it as a drive in their file manager,
+// the shared files & directories sit at the root of the mount, the files inside the shared directories
+// are reached through os.Root, like Server.serveTreeHandler. If the host accepts uploads, the clients
+// may copy new files to the root of the mount, they are saved in the ReceiveConfig.DownloadFolder &
+// only ever listed to their uploader, the shared files are never overwritten, moved or deleted.
+//
+// Returns:
+//   - Success: As per the WebDAV method, e.g. 207 Multi-Status for PROPFIND
+//   - Error (403 Forbidden): When writing, if the host does not accept uploads
+//   - Error (410 Gone): If the share limits of the file are reached
+func (s *Server) davHandler(w http.ResponseWriter, r *http.Request) {
+	if slices.Contains(davWriteMethods, r.Method) && !getConfig().Share.AllowUploads {
+		s.uploadsNotAllowedResponse(w, r)
+		return
+	}
+	client := davClient{ip: remoteIP(r), reqBy: requestedBy(r), shared: sync.OnceValue(s.davSharedEntries)}
+	r = r.WithContext(context.WithValue(r.Context(), davClientKey{}, client))
+	h := &webdav.Handler{Prefix: davPrefix, FileSystem: davFS{s}, LockSystem: s.davLocks}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.serveDAVFile(w, r, h)
+	case http.MethodPut:
+		if err := liftReadDeadline(w); err != nil {
+			s.serverErrorResponse(w, r)
+			return
+		}
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, davPrefix), "/")
+		if e, ok := s.davEntry(client, name); strings.Contains(name, "/") || ok && e.accessID != "" {
+			s.davReadOnlyResponse(w, r)
+			return
+		}
+		s.incActiveConn()       // this doesn't block
+		defer s.decActiveConn() // this blocks
+		auditFile(r, name)
+		h.ServeHTTP(w, r)
+	default:
+		h.ServeHTTP(w, r)
+	}
+}
+
+// serveDAVFile serves the file like Server.serveFileHandler does, so the downloads through
+// the mount are logged, tracked & counted against the share limits the same way.
+func (s *Server) serveDAVFile(w http.ResponseWriter, r *http.Request, h *webdav.Handler) {
+	name := strings.TrimPrefix(r.URL.Path, davPrefix)
+	fsys := davFS{s}
+	e, rel, err := fsys.resolve(r.Context(), name)
+	if errors.Is(err, errLimitReached) {
+		s.goneResponse(w, r)
+		return
+	}
+	stat, statErr := fsys.Stat(r.Context(), name)
+	if err != nil || statErr != nil || stat.IsDir() {
+		h.ServeHTTP(w, r) // the handler tells the client
+		return
+	}
+
+	file := path.Join(filepath.Base(e.path), rel)
+	finish := func(bool) {} // files inside shared directories have no limits of their own, nor do uploads
+	if e.accessID != "" && rel == "" {
+		var ok bool
+		if finish, ok = s.reserveDownload(r, e.accessID, file); !ok {
+			s.goneResponse(w, r)
+			return
+		}
+	}
+	k := fmt.Sprint("dav:", file, ":", remoteIP(r))
+	if shouldLogReq(r.RemoteAddr) && s.firstLog(k) {
+		s.log.info("Serving file", "File", file, "ReqBy", requestedBy(r))
+	}
+	s.incActiveConn()       // this doesn't block
+	defer s.decActiveConn() // this blocks
+
+	auditFile(r, file)
+	w, done := s.trackTransfer(w, r, file, stat.Size())
+	defer done()
+	cw := &countingWriter{ResponseWriter: w}
+	h.ServeHTTP(cw, r)
+	finish(completedDownload(r, cw.status, cw.n, stat.Size()))
+}
+
+// davSharedEntries returns the shared files & directories at the root of the mount, [K: name, V: entry],
+// the ones with the same name are told apart like in the archives, see uniqueArchiveName.
+func (s *Server) davSharedEntries() map[string]davEntry {
+	files := s.sharedFiles()
+	ids := slices.SortedFunc(maps.Keys(files), func(a, b string) int { return cmp.Compare(files[a], files[b]) })
+	entries := make(map[string]davEntry, len(files))
+	taken := make(map[string]struct{}, len(files))
+	for _, id := range ids {
+		// named before skipping the used up files, so the rest keep their names
+		name := uniqueArchiveName(filepath.Base(files[id]), taken)
+		if s.available(id) {
+			entries[name] = davEntry{accessID: id, path: files[id]}
+		}
+	}
+	return entries
+}
+
+// davEntries returns the items at the root of the mount, [K: name, V: entry], the shared files,
+// see davClient.shared, then the uploads of the client, the shared files keep their names.
+func (s *Server) davEntries(client davClient) map[string]davEntry {
+	entries := maps.Clone(client.shared())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, p := range s.davUploads[client.ip] {
+		if _, ok := entries[name]; !ok {
+			entries[name] = davEntry{path: p}
+		}
+	}
+	return entries
+}
+
+// davEntry returns the item named name at the root of the mount, see Server.davEntries,
+// without listing all of them, as it is looked up for every item of a PROPFIND.
+func (s *Server) davEntry(client davClient, name string) (davEntry, bool) {
+	if e, ok := client.shared()[name]; ok {
+		return e, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.davUploads[client.ip][name]
+	return davEntry{path: p}, ok
+}
+
+// davFS is the webdav.FileSystem of the mounted share, see Server.davHandler.
+type davFS struct {
+	s *Server
+}
+
+// resolve returns the root entry the slash separated name is in, & its path relative to the entry,
+// the entry itself, if rel is empty, the root of the mount is the zero davEntry.
+func (fsys davFS) resolve(ctx context.Context, name string) (e davEntry, rel string, err error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return davEntry{}, "", nil
+	}
+	first, rel, _ := strings.Cut(name, "/")
+	client, _ := ctx.Value(davClientKey{}).(davClient)
+	e, ok := fsys.s.davEntry(client, first)
+	if !ok {
+		return davEntry{}, "", fs.ErrNotExist
+	}
+	if e.accessID != "" && !fsys.s.available(e.accessID) {
+		return davEntry{}, "", errLimitReached
+	}
+	if rel != "" && !fs.ValidPath(rel) {
+		return davEntry{}, "", fs.ErrNotExist
+	}
+	return e, rel, nil
+}
+
+func (fsys davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	e, rel, err := fsys.resolve(ctx, name)
+	switch {
+	case err != nil:
+		return nil, err
+	case e.path == "":
+		return davDirInfo{modTime: fsys.s.metrics.startedAt}, nil
+	case rel == "":
+		stat, err := os.Stat(e.path)
+		if err != nil {
+			return nil, err
+		}
+		return davFileInfo{FileInfo: stat, name: path.Base(strings.Trim(name, "/"))}, nil
+	}
+	root, err := os.OpenRoot(e.path)
+	if err != nil {
+		return nil, fs.ErrNotExist // a shared file has no children
+	}
+	defer root.Close()
+	return root.Stat(rel)
+}
+
+func (fsys davFS) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return fsys.upload(ctx, name)
+	}
+	e, rel, err := fsys.resolve(ctx, name)
+	switch {
+	case err != nil:
+		return nil, err
+	case e.path == "":
+		return fsys.openRoot(ctx)
+	case rel == "":
+		f, err := os.Open(e.path)
+		if err != nil {
+			return nil, err
+		}
+		return davFile{File: f, name: path.Base(strings.Trim(name, "/"))}, nil
+	}
+	root, err := os.OpenRoot(e.path)
+	if err != nil {
+		return nil, fs.ErrNotExist // a shared file has no children
+	}
+	defer root.Close()
+	return root.Open(rel)
+}
+
+// upload creates the file the client is copying to the root of the mount, see Server.davHandler,
+// the client may overwrite its own uploads, as file managers write a file in a few steps.
+func (fsys davFS) upload(ctx context.Context, name string) (webdav.File, error) {
+	cfg := getConfig()
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if !cfg.Share.AllowUploads || name == "" || strings.Contains(name, "/") {
+		return nil, fs.ErrPermission
+	}
+	client, _ := ctx.Value(davClientKey{}).(davClient)
+	e, ok := fsys.s.davEntry(client, name)
+	if ok && e.accessID != "" {
+		return nil, fs.ErrPermission // shared files are never overwritten
+	}
+
+	var f *os.File
+	var err error
+	if ok {
+		f, err = os.OpenFile(e.path, os.O_WRONLY|os.O_TRUNC, 0)
+	} else {
+		f, err = createUploadFile(cfg.Receive.DownloadFolder, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fsys.s.mu.Lock()
+	if fsys.s.davUploads[client.ip] == nil {
+		fsys.s.davUploads[client.ip] = make(map[string]string)
+	}
+	fsys.s.davUploads[client.ip][name] = f.Name()
+	fsys.s.mu.Unlock()
+	return fsys.s.newDAVUpload(f, name, client), nil
+}
+
+func (fsys davFS) openRoot(ctx context.Context) (webdav.File, error) {
+	client, _ := ctx.Value(davClientKey{}).(davClient)
+	entries := fsys.s.davEntries(client)
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		stat, err := os.Stat(entries[name].path)
+		if err != nil {
+			continue // deleted by the host meanwhile
+		}
+		infos = append(infos, davFileInfo{FileInfo: stat, name: name})
+	}
+	return &davDir{info: davDirInfo{modTime: fsys.s.metrics.startedAt}, entries: infos}, nil
+}
+
+func (davFS) Mkdir(context.Context, string, os.FileMode) error { return fs.ErrPermission }
+
+func (davFS) RemoveAll(context.Context, string) error { return fs.ErrPermission }
+
+func (davFS) Rename(context.Context, string, string) error { return fs.ErrPermission }
+
+// davUpload is a file being uploaded through the mount, its progress is relayed to the tui, like Server.receiveFile.
+type davUpload struct {
+	*os.File
+	pw   *progressWriter
+	name string
+	done func(n int64)
+}
+
+func (s *Server) newDAVUpload(f *os.File, name string, client davClient) *davUpload {
+	logID := "upload:" + f.Name()
+	return &davUpload{
+		File: f,
+		name: name,
+		pw: &progressWriter{w: f, report: func(n int64) {
+			s.log.progress(logID, "Receiving file", "File", name, "ReqBy", client.reqBy, "Received", humanize.Bytes(uint64(n)))
+		}},
+		done: func(n int64) {
+			if n > 0 { // file managers create the file empty, before they write it
+				s.log.progress(logID, "File received", "File", name, "ReqBy", client.reqBy, "Size", humanize.Bytes(uint64(n)))
+			}
+		},
+	}
+}
+
+func (u *davUpload) Write(p []byte) (int, error) {
+	return u.pw.Write(p)
+}
+
+func (u *davUpload) Stat() (fs.FileInfo, error) {
+	stat, err := u.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return davFileInfo{FileInfo: stat, name: u.name}, nil
+}
+
+func (u *davUpload) Close() error {
+	err := u.File.Close()
+	u.done(u.pw.n)
+	return err
+}
+
+// davFile is a shared file or directory at the root of the mount, named as listed there, see Server.davEntries.
+type davFile struct {
+	*os.File
+	name string
+}
+
+func (f davFile) Stat() (fs.FileInfo, error) {
+	stat, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return davFileInfo{FileInfo: stat, name: f.name}, nil
+}
+
+func (davFile) Write([]byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+// davFileInfo renames the fs.FileInfo, see davFile.
+type davFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (fi davFileInfo) Name() string { return fi.name }
+
+// davDir is the root of the mount, listing the davEntries.
+type davDir struct {
+	info    davDirInfo
+	entries []fs.FileInfo
+	read    int
+}
+
+func (d *davDir) Readdir(count int) ([]fs.FileInfo, error) {
+	rest := d.entries[d.read:]
+	if count <= 0 {
+		d.read = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(count, len(rest))]
+	d.read += len(rest)
+	return rest, nil
+}
+
+func (d *davDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (*davDir) Read([]byte) (int, error) { return 0, fs.ErrInvalid }
+
+func (*davDir) Seek(int64, int) (int64, error) { return 0, fs.ErrInvalid }
+
+func (*davDir) Write([]byte) (int, error) { return 0, fs.ErrPermission }
+
+func (*davDir) Close() error { return nil }
+
+// davDirInfo is the fs.FileInfo of the root of the mount, it was last modified when the server started.
+type davDirInfo struct {
+	modTime time.Time
+}
+
+func (davDirInfo) Name() string { return "/" }
+
+func (davDirInfo) Size() int64 { return 0 }
+
+func (davDirInfo) Mode() fs.FileMode { return fs.ModeDir | 0o555 }
+
+func (i davDirInfo) ModTime() time.Time { return i.modTime }
+
+func (davDirInfo) IsDir() bool { return true }
+
+func (davDirInfo) Sys() any { return nil }
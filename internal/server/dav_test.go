@@ -0,0 +1,71 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDavHandler(t *testing.T) {
+	s := New(config.ShareConfig{Secret: "lab-secret", MaxDownloads: 1}, make(chan Log, 100), make(chan int, 100))
+	defer s.StopCtxCancel()
+	dir, other := t.TempDir(), t.TempDir()
+	report := filepath.Join(dir, "report.txt")
+	require.NoError(t, os.WriteFile(report, []byte("report"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(other, "report.txt"), []byte("other"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0o644))
+	s.setFilePaths(report, filepath.Join(other, "report.txt"), filepath.Join(dir, "src"))
+	h := s.routes()
+
+	serve := func(method, target string, authed bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(""))
+		r.Host = "localhost"
+		r.RemoteAddr = "198.51.100.7:50000"
+		if method == "PROPFIND" {
+			r.Header.Set("Depth", "1")
+		}
+		if authed {
+			r.SetBasicAuth("anyone", "lab-secret")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("PROPFIND", "/dav/", false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic", "file managers must be asked for a password")
+
+	w = serve("PROPFIND", "/dav/", true)
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	for _, name := range []string{"/dav/report.txt", "/dav/report%20%282%29.txt", "/dav/src/"} {
+		assert.Contains(t, w.Body.String(), "<D:href>"+name+"</D:href>")
+	}
+	assert.NotContains(t, w.Body.String(), dir, "host paths must not leak")
+
+	w = serve("PROPFIND", "/dav/src/", true)
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "<D:href>/dav/src/main.go</D:href>")
+
+	w = serve(http.MethodGet, "/dav/src/main.go", true)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "package main", w.Body.String())
+
+	w = serve(http.MethodGet, "/dav/report.txt", true)
+	require.Equal(t, http.StatusOK, w.Code)
+	b, _ := io.ReadAll(w.Body)
+	assert.Equal(t, "report", string(b))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/dav/report.txt", true).Code, "the download limit must apply")
+
+	for _, method := range []string{http.MethodPut, "MKCOL", http.MethodDelete, "MOVE"} {
+		assert.NotEqual(t, http.StatusCreated, serve(method, "/dav/new.txt", true).Code, "the mount must be read-only")
+	}
+}
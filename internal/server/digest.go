@@ -0,0 +1,135 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxHashing caps the files hashed at once, so hashing doesn't starve the transfers of disk IO.
+const maxHashing = 2
+
+// digests lazily computes & caches the SHA-256 of the shared files, a cached digest is
+// only used while the file has the same size & modification time it was hashed at.
+type digests struct {
+	mu      sync.Mutex
+	entries map[string]*digestEntry // [K: file path, V: entry]
+	sem     chan struct{}
+}
+
+type digestEntry struct {
+	size    int64
+	modTime time.Time
+	// sum is the hex encoded SHA-256, empty while hashing or if hashing failed
+	sum     string
+	hashing bool
+}
+
+func newDigests() *digests {
+	return &digests{
+		entries: make(map[string]*digestEntry),
+		sem:     make(chan struct{}, maxHashing),
+	}
+}
+
+// digest returns the hex encoded SHA-256 of the file at key, if it is cached,
+// otherwise it is computed in the background, reading the file through open,
+// and an empty string is returned, ask again later.
+func (s *Server) digest(key string, stat fs.FileInfo, open func() (io.ReadCloser, error)) string {
+	d := s.digests
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.entries[key]
+	if ok && e.size == stat.Size() && e.modTime.Equal(stat.ModTime()) {
+		if e.sum == "" && !e.hashing {
+			// hashing failed, e.g. the file was being written, retry
+			e.hashing = true
+			go s.hash(e, open)
+		}
+		return e.sum
+	}
+	e = &digestEntry{size: stat.Size(), modTime: stat.ModTime(), hashing: true}
+	d.entries[key] = e
+	go s.hash(e, open)
+	return ""
+}
+
+// hash computes the SHA-256 of the file into the entry, it gives up on the file once the server stops.
+func (s *Server) hash(e *digestEntry, open func() (io.ReadCloser, error)) {
+	d := s.digests
+	select {
+	case d.sem <- struct{}{}:
+		defer func() { <-d.sem }()
+	case <-s.StopCtx.Done():
+		d.mu.Lock()
+		e.hashing = false
+		d.mu.Unlock()
+		return
+	}
+
+	var sum string
+	defer func() {
+		d.mu.Lock()
+		e.sum, e.hashing = sum, false
+		d.mu.Unlock()
+	}()
+
+	f, err := open()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	h := sha256.New()
+	buf := make([]byte, 1<<20) // 1MB buffer
+	for {
+		if s.StopCtx.Err() != nil {
+			return
+		}
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+	}
+	sum = hex.EncodeToString(h.Sum(nil))
+}
+
+// setDigestHeaders sets the Digest (RFC 3230) header of the file, if its digest is known.
+func setDigestHeaders(w http.ResponseWriter, sum string) {
+	if sum == "" {
+		return
+	}
+	b, err := hex.DecodeString(sum)
+	if err != nil {
+		return
+	}
+	w.Header().Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(b))
+}
+
+// setValidators sets the strong ETag of the file, derived from its size & modification time, not from its
+// digest, which is only known once hashed, so the ETag stays the same across the downloads of the same file.
+// Along with the Last-Modified header, set by http.ServeContent, it lets the clients resume downloads with
+// If-Range, a file changed since is served whole, instead of its remaining bytes, see client.Client.DownloadFile.
+func setValidators(w http.ResponseWriter, stat fs.FileInfo) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()))
+}
+
+// openInRoot opens the file rel inside the shared directory, through os.Root, see Server.serveTreeHandler.
+func openInRoot(dir, rel string) (io.ReadCloser, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.Open(rel)
+}
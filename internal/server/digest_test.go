@@ -0,0 +1,44 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServer_Digest(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	p := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(p, []byte("hello"), 0o644))
+	open := func() (io.ReadCloser, error) { return os.Open(p) }
+
+	digestOf := func() string {
+		stat, err := os.Stat(p)
+		require.NoError(t, err)
+		var sum string
+		assert.Eventually(t, func() bool {
+			sum = s.digest(p, stat, open)
+			return sum != ""
+		}, time.Second, 10*time.Millisecond)
+		return sum
+	}
+
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", digestOf())
+
+	// the cached digest is dropped, once the file changes
+	require.NoError(t, os.WriteFile(p, []byte("hello, world"), 0o644))
+	require.NoError(t, os.Chtimes(p, time.Time{}, time.Now().Add(time.Minute)))
+	assert.Equal(t, "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b", digestOf())
+
+	w := httptest.NewRecorder()
+	setDigestHeaders(w, digestOf())
+	assert.Equal(t, "sha-256=Ccp+TqpuiunH0mEWcSkYSINkTQffuny/vEyKLgg2DVs=", w.Header().Get("Digest"))
+	assert.Empty(t, w.Header().Get("ETag"), "the ETag must not change once the file is hashed, see setValidators")
+}
@@ -24,21 +24,77 @@ func (s *Server) serverErrorResponse(w http.ResponseWriter, r *http.Request) {
 	s.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+func (s *Server) badRequestResponse(w http.ResponseWriter, r *http.Request, message string) {
+	s.errorResponse(w, r, http.StatusBadRequest, message)
+}
+
 func (s *Server) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource cannot be found"
 	s.errorResponse(w, r, http.StatusNotFound, message)
 }
 
+func (s *Server) goneResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested resource has expired or reached its download limit"
+	s.errorResponse(w, r, http.StatusGone, message)
+}
+
+func (s *Server) secretRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the share is protected, a valid secret is required"
+	w.Header().Set("WWW-Authenticate", `Bearer realm="letshare"`)
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (s *Server) davSecretRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the share is protected, enter the secret as the password"
+	w.Header().Set("WWW-Authenticate", `Basic realm="letshare", charset="UTF-8"`)
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (s *Server) notApprovedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the host did not approve your access to the share"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (s *Server) notAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the host does not allow your address to access the share"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (s *Server) tooManyAttemptsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "too many wrong secret attempts, try again later"
+	s.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
 func (s *Server) notStoppableResponse(w http.ResponseWriter, r *http.Request) {
-	message := "the server cannot be stopped, the host will be notified for the request"
+	message := "the server cannot be stopped by others"
 	s.errorResponse(w, r, http.StatusForbidden, message)
 }
 
-func (s *Server) notIdleResponse(w http.ResponseWriter, r *http.Request) {
-	message := "server is currently not idle (serving files)"
+func (s *Server) stopDeniedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the host declined to stop the server"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (s *Server) stopUndecidedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the host did not decide on the stop request in time, try again later"
 	s.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (s *Server) uploadsNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the host does not accept uploads"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (s *Server) uploadTooLargeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the upload exceeds the size accepted by the host"
+	s.errorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
+func (s *Server) davReadOnlyResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the shared files are read-only, copy new files to the root of the share"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
+
 func (s *Server) noOSHostnameAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "Wrong door! Use my advertised mDNS service, not my OS hostname. This teapot has standards!"
 	s.errorResponse(w, r, http.StatusTeapot, message)
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// eventsPingInterval keeps the idle event streams alive, so they are not dropped as dead connections
+	eventsPingInterval = 30 * time.Second
+	// eventsBufSize is the events buffered per subscriber, a subscriber too slow to keep up misses the newer ones
+	eventsBufSize = 16
+	// shutdownNotice is sent to the receivers, once the host stops sharing, the downloads in progress are
+	// only waited on for a moment, see Server.listenAndShutdown, so they are not promised to complete
+	shutdownNotice = "The host stopped sharing, new downloads are refused, those in progress may be cut short."
+)
+
+// eventFeed fans the share's events out to the receivers subscribed at /events, see domain.Event.
+type eventFeed struct {
+	mu   sync.Mutex
+	subs map[chan domain.Event]struct{}
+	// indexVersion is incremented every time the shared files change
+	indexVersion uint64
+}
+
+func newEventFeed() *eventFeed {
+	return &eventFeed{subs: make(map[chan domain.Event]struct{})}
+}
+
+// subscribe registers a subscriber, unsubscribe must be called once it is done.
+func (f *eventFeed) subscribe() (ch <-chan domain.Event, unsubscribe func()) {
+	c := make(chan domain.Event, eventsBufSize)
+	f.mu.Lock()
+	f.subs[c] = struct{}{}
+	f.mu.Unlock()
+	return c, func() {
+		f.mu.Lock()
+		delete(f.subs, c)
+		f.mu.Unlock()
+	}
+}
+
+// publish sends the event to every subscriber without blocking, returns the number of subscribers it reached.
+func (f *eventFeed) publish(e domain.Event) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int
+	for c := range f.subs {
+		select {
+		case c <- e:
+			n++
+		default: // the subscriber is not keeping up
+		}
+	}
+	return n
+}
+
+// indexChanged bumps the index version & lets the receivers know they should fetch the index again.
+func (f *eventFeed) indexChanged() {
+	f.mu.Lock()
+	f.indexVersion++
+	e := domain.Event{Type: domain.EventIndex, IndexVersion: f.indexVersion}
+	f.mu.Unlock()
+	f.publish(e)
+}
+
+// Broadcast sends the message to the receivers currently subscribed to the share's events,
+// returns the number of receivers it reached.
+func (s *Server) Broadcast(msg string) int {
+	n := s.events.publish(domain.Event{Type: domain.EventMessage, Message: msg})
+	s.log.info("Message was sent to the receivers", "Receivers", n)
+	return n
+}
+
+// eventsHandler streams the share's events to the receiver as server-sent events, i.e. the index changes,
+// the host's messages & the shutdown notice, the stream ends once the host stops sharing.
+//
+// Returns:
+//   - Success (200 OK): The text/event-stream, kept open until the client leaves or the share stops
+func (s *Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	events, unsubscribe := s.events.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// the comment flushes the headers, so the client knows it is subscribed
+	if _, err := io.WriteString(w, ": subscribed\n\n"); err != nil || rc.Flush() != nil {
+		return
+	}
+
+	t := time.NewTicker(eventsPingInterval)
+	defer t.Stop()
+	for {
+		var err error
+		select {
+		case e := <-events:
+			err = writeEvent(w, e)
+		case <-t.C:
+			_, err = io.WriteString(w, ": ping\n\n")
+		case <-r.Context().Done():
+			return
+		case <-s.StopCtx.Done():
+			if writeEvent(w, domain.Event{Type: domain.EventShutdown, Message: shutdownNotice}) == nil {
+				_ = rc.Flush()
+			}
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format, the data is always a single line of JSON.
+func writeEvent(w io.Writer, e domain.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
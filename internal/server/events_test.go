@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bufio"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer_EventsHandler(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	sc := bufio.NewScanner(resp.Body)
+	// next returns the next event, skipping the comments
+	next := func() string {
+		var lines []string
+		for sc.Scan() {
+			switch line := sc.Text(); {
+			case strings.HasPrefix(line, ":"):
+			case line == "" && len(lines) > 0:
+				return strings.Join(lines, "\n")
+			case line != "":
+				lines = append(lines, line)
+			}
+		}
+		return ""
+	}
+	require.True(t, sc.Scan()) // ": subscribed", the handler is now subscribed
+
+	p := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(p, []byte("notes"), 0o644))
+	s.AddFiles(p)
+	assert.Equal(t, "event: index\ndata: {\"indexVersion\":1}", next())
+
+	s.Broadcast("grab the slides")
+	assert.Equal(t, "event: message\ndata: {\"message\":\"grab the slides\"}", next())
+
+	s.ShutdownServer()
+	assert.Equal(t, "event: shutdown\ndata: {\"message\":\""+shutdownNotice+"\"}", next())
+	assert.Empty(t, next(), "the stream must end once the share stops")
+}
@@ -3,8 +3,11 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 )
 
 type envelop map[string]any
@@ -29,7 +32,7 @@ func (s *Server) render(w http.ResponseWriter, status int, page string, data any
 		return fmt.Errorf("template doesnot exist %q", page)
 	}
 	b := new(bytes.Buffer)
-	if err := ts.ExecuteTemplate(b, "fileIndexes", data); err != nil {
+	if err := ts.ExecuteTemplate(b, page, data); err != nil {
 		return err
 	}
 	w.WriteHeader(status)
@@ -38,3 +41,31 @@ func (s *Server) render(w http.ResponseWriter, status int, page string, data any
 	}
 	return nil
 }
+
+// requestedBy returns the X-Requested-By header value,
+// if not set (requests from browsers), falls back to the remote IP.
+func requestedBy(r *http.Request) string {
+	reqBy := r.Header.Get("X-Requested-By")
+	if reqBy == "" {
+		reqBy = remoteIP(r)
+	}
+	return reqBy
+}
+
+// remoteIP returns the IP part of the request's RemoteAddr.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// liftReadDeadline lifts the server ReadTimeout for the request, it is way too short for uploads.
+func liftReadDeadline(w http.ResponseWriter) error {
+	err := http.NewResponseController(w).SetReadDeadline(time.Time{})
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
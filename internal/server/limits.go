@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+var errLimitReached = errors.New("share limit reached")
+
+// Limits restrict how long & how many times the shared files are served, zero means no limit.
+type Limits struct {
+	// Expiry is the duration after Server.StartServer, the whole share is served for
+	Expiry time.Duration
+	// MaxDownloads is the number of completed downloads allowed per shared file/directory
+	MaxDownloads int
+}
+
+// available reports whether the shared file is still served, i.e. the
+// share has not expired & the file has downloads left, see Limits.
+func (s *Server) available(accessID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.expired() && (s.limits.MaxDownloads == 0 || s.downloads[accessID] < s.limits.MaxDownloads)
+}
+
+// downloadsLeft returns the completed downloads the file is still allowed, 0 if unlimited.
+func (s *Server) downloadsLeft(accessID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limits.MaxDownloads == 0 {
+		return 0
+	}
+	return max(0, s.limits.MaxDownloads-s.downloads[accessID])
+}
+
+// reserveDownload takes one of the downloads left of the shared file for the request, once it is admitted,
+// so parallel requests can't exceed Limits.MaxDownloads, reports false if none is left, or the share expired.
+// finish must be called once the response is written, with whether the download completed, see completedDownload,
+// it is then recorded, otherwise the download is given back. Requests that can't complete a download,
+// e.g. HEAD or bounded Range requests, take none, they are only checked against the limits, see Server.available.
+func (s *Server) reserveDownload(r *http.Request, accessID, name string) (finish func(completed bool), ok bool) {
+	record := func(completed bool) {
+		if completed {
+			s.recordDownload(accessID, name)
+		}
+	}
+	if r.Method != http.MethodGet || !mayCompleteDownload(r) {
+		return record, s.available(accessID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expired() {
+		return nil, false
+	}
+	if s.limits.MaxDownloads == 0 {
+		return record, true
+	}
+	if s.downloads[accessID]+s.reserved[accessID] >= s.limits.MaxDownloads {
+		return nil, false
+	}
+	s.reserved[accessID]++
+	return func(completed bool) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.reserved[accessID]--; s.reserved[accessID] == 0 {
+			delete(s.reserved, accessID)
+		}
+		if completed {
+			s.countDownload(accessID, name)
+		}
+	}, true
+}
+
+// mayCompleteDownload reports whether the response to the request may deliver the file up to its last byte,
+// i.e. it asks for the whole file, or resumes it, see completedDownload.
+func mayCompleteDownload(r *http.Request) bool {
+	if r.Header.Get("Range") == "" {
+		return true
+	}
+	_, ok := resumeOffset(r)
+	return ok
+}
+
+// recordDownload counts a completed download of the shared file, once every shared file
+// has used up its downloads, the server shuts down as soon as it is idle, see Server.decActiveConn.
+func (s *Server) recordDownload(accessID string, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.countDownload(accessID, name)
+}
+
+// countDownload counts a completed download of the shared file, see Server.recordDownload,
+// the caller must hold Server.mu.
+func (s *Server) countDownload(accessID string, name string) {
+	if s.limits.MaxDownloads == 0 {
+		return
+	}
+	s.downloads[accessID]++
+	if s.downloads[accessID] == s.limits.MaxDownloads {
+		s.log.info("Download limit reached", "File", name, "Downloads", s.limits.MaxDownloads)
+		s.events.indexChanged() // the file is no longer listed
+	}
+}
+
+// exhausted reports whether every shared file has used up its downloads, the shared directories
+// are left out, their files are downloaded one by one, they are only counted when archived whole,
+// a share of directories alone is never exhausted, the caller must hold Server.mu.
+func (s *Server) exhausted() bool {
+	if s.limits.MaxDownloads == 0 {
+		return false
+	}
+	var files int
+	for id, p := range s.FilePaths {
+		if stat, err := os.Stat(p); err == nil && stat.IsDir() {
+			continue
+		}
+		if s.downloads[id] < s.limits.MaxDownloads {
+			return false
+		}
+		files++
+	}
+	return files > 0
+}
+
+// expired reports whether the share has expired, the caller must hold Server.mu.
+func (s *Server) expired() bool {
+	return !s.expiresAt.IsZero() && !time.Now().Before(s.expiresAt)
+}
+
+// expireAfter shuts the server down once the share expires, it must be called
+// after Server.expiresAt is set, and returns early if the server stops before that.
+func (s *Server) expireAfter(d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		s.log.info("Share expired, shutting down", "After", d.String())
+		s.ShutdownServer()
+	case <-s.StopCtx.Done():
+	}
+}
+
+// countingWriter counts the bytes of the response body, to tell complete downloads apart, see completedDownload.
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (cw *countingWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	n, err := cw.ResponseWriter.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the sendfile optimization of the underlying http.ResponseWriter.
+func (cw *countingWriter) ReadFrom(src io.Reader) (int64, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if rf, ok := cw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(src)
+		cw.n += n
+		return n, err
+	}
+	return io.Copy(struct{ io.Writer }{cw}, src)
+}
+
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// completedDownload reports whether the response delivered the file up to its last byte,
+// a resumed download (Range: bytes=N-) completes once the remaining bytes are delivered.
+func completedDownload(r *http.Request, status int, written, size int64) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	switch status {
+	case http.StatusOK:
+		return written == size
+	case http.StatusPartialContent:
+		offset, ok := resumeOffset(r)
+		return ok && offset+written == size
+	default:
+		return false
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletedDownload(t *testing.T) {
+	tests := []struct {
+		name, method, rng string
+		status            int
+		written, size     int64
+		want              bool
+	}{
+		{"whole file", http.MethodGet, "", http.StatusOK, 100, 100, true},
+		{"interrupted", http.MethodGet, "", http.StatusOK, 60, 100, false},
+		{"head", http.MethodHead, "", http.StatusOK, 0, 0, false},
+		{"resumed", http.MethodGet, "bytes=60-", http.StatusPartialContent, 40, 100, true},
+		{"resumed, interrupted", http.MethodGet, "bytes=60-", http.StatusPartialContent, 10, 100, false},
+		{"bounded range", http.MethodGet, "bytes=0-99", http.StatusPartialContent, 100, 100, false},
+		{"suffix range", http.MethodGet, "bytes=-40", http.StatusPartialContent, 40, 100, false},
+		{"not found", http.MethodGet, "", http.StatusNotFound, 0, 0, false},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, "/1234", nil)
+		if tc.rng != "" {
+			r.Header.Set("Range", tc.rng)
+		}
+		assert.Equal(t, tc.want, completedDownload(r, tc.status, tc.written, tc.size), tc.name)
+	}
+}
+
+func TestServer_Exhausted(t *testing.T) {
+	s := New(config.ShareConfig{MaxDownloads: 1}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	dir := t.TempDir()
+	report, src := filepath.Join(dir, "report.txt"), filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(report, []byte("report"), 0o644))
+	require.NoError(t, os.MkdirAll(src, 0o755))
+	exhausted := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.exhausted()
+	}
+
+	s.setFilePaths(src)
+	assert.False(t, exhausted(), "a share of directories alone is never exhausted")
+
+	s.setFilePaths(report)
+	assert.False(t, exhausted())
+	id, _ := s.accessID(report)
+	s.recordDownload(id, "report.txt")
+	assert.True(t, exhausted(), "the directories, downloaded file by file, must not keep the share alive")
+}
+
+func TestServer_ReserveDownload(t *testing.T) {
+	s := New(config.ShareConfig{MaxDownloads: 1}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	get := httptest.NewRequest(http.MethodGet, "/1234", nil)
+	ranged := httptest.NewRequest(http.MethodGet, "/1234", nil)
+	ranged.Header.Set("Range", "bytes=0-99")
+
+	finish, ok := s.reserveDownload(get, "1234", "report.pdf")
+	require.True(t, ok)
+	_, ok = s.reserveDownload(get, "1234", "report.pdf")
+	assert.False(t, ok, "parallel requests must not exceed the limit")
+	_, ok = s.reserveDownload(ranged, "1234", "report.pdf")
+	assert.True(t, ok, "a bounded range can't complete the download, it takes none")
+
+	finish(false)
+	finish, ok = s.reserveDownload(get, "1234", "report.pdf")
+	require.True(t, ok, "an interrupted download must give its reservation back")
+	finish(true)
+	assert.False(t, s.available("1234"))
+	_, ok = s.reserveDownload(ranged, "1234", "report.pdf")
+	assert.False(t, ok)
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version of letshare reported by the health & metrics endpoints, set by the main package at startup.
+var Version = "UNKNOWN"
+
+// maxMetricClients caps the clients tracked individually, the rest are totalled under the "other" client,
+// so a busy network cannot grow the metrics without bound.
+const maxMetricClients = 256
+
+// metrics are the usage counters of the server since it was created, see Server.metricsHandler.
+type metrics struct {
+	mu          sync.Mutex
+	startedAt   time.Time
+	bytesServed int64
+	requests    map[string]uint64 // [K: route, V: requests]
+	errors      map[int]uint64    // [K: status code, V: responses]
+	clients     map[string]*clientTotals
+}
+
+// clientTotals are the usage counters of a single client, by remote IP.
+type clientTotals struct {
+	requests    uint64
+	bytesServed int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		startedAt: time.Now(),
+		requests:  make(map[string]uint64),
+		errors:    make(map[int]uint64),
+		clients:   make(map[string]*clientTotals),
+	}
+}
+
+// record counts a served request, route is the pattern it matched, empty if it matched none.
+func (m *metrics) record(route, client string, status int, written int64) {
+	if route == "" {
+		route = "unmatched"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bytesServed += written
+	m.requests[route]++
+	if status >= 400 {
+		m.errors[status]++
+	}
+	c, ok := m.clients[client]
+	if !ok {
+		if len(m.clients) >= maxMetricClients {
+			client = "other"
+		}
+		if c, ok = m.clients[client]; !ok {
+			c = new(clientTotals)
+			m.clients[client] = c
+		}
+	}
+	c.requests++
+	c.bytesServed += written
+}
+
+func (m *metrics) uptime() time.Duration {
+	return time.Since(m.startedAt)
+}
+
+// instrument counts every request the handler serves, its route, status & the bytes of the response body,
+// & appends it to the audit log, see Server.audit.
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		cw := &countingWriter{ResponseWriter: w}
+		r, file := withAuditFile(r)
+		next.ServeHTTP(cw, r)
+		// the mux sets the pattern on the request it routed, i.e. this one, the status
+		// is unset if the handler wrote nothing, the response is then a 200 OK
+		status := cmp.Or(cw.status, http.StatusOK)
+		s.metrics.record(r.Pattern, remoteIP(r), status, cw.n)
+		s.audit(r, *file, status, cw.n, time.Since(start))
+	})
+}
+
+// SetInstance sets the mDNS instance the server is published as, reported by Server.healthHandler.
+func (s *Server) SetInstance(instance string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instance = instance
+}
+
+// healthHandler reports the server is up & responsive, along with its version, uptime, instance & shared files,
+// it is open to everyone, so monitoring needs no secret, & it reveals nothing about the shared files themselves.
+//
+// Returns:
+//   - Success (200 OK): The health of the server
+//   - Error (503 Service Unavailable): If the server is shutting down
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	status, code := "ok", http.StatusOK
+	if s.StopCtx.Err() != nil {
+		status, code = "shutting down", http.StatusServiceUnavailable
+	}
+	uptime := s.metrics.uptime()
+	s.mu.Lock()
+	data := envelop{
+		"status":          status,
+		"version":         Version,
+		"uptime":          uptime.Truncate(time.Second).String(),
+		"uptimeSeconds":   int64(uptime.Seconds()),
+		"instance":        s.instance,
+		"sharedFiles":     len(s.FilePaths),
+		"sharedSnippets":  len(s.snippets),
+		"activeDownloads": s.ActiveDowns,
+	}
+	s.mu.Unlock()
+	w.Header().Set("Cache-Control", "no-store")
+	if err := s.writeJSON(w, data, code, nil); err != nil {
+		s.serverErrorResponse(w, r)
+	}
+}
+
+// metricsHandler exposes the usage of the server in the Prometheus text format, to be scraped & graphed,
+// it sits behind the share secret, if one is set, as it reveals the clients, scrapers use the bearer token.
+//
+// Returns:
+//   - Success (200 OK): The metrics in the Prometheus text exposition format
+func (s *Server) metricsHandler(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	activeDowns, sharedFiles := s.ActiveDowns, len(s.FilePaths)+len(s.snippets)
+	s.mu.Unlock()
+
+	m := s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	p := promWriter{w: w}
+
+	p.metric("letshare_build_info", "gauge", "Version of letshare, always 1.")
+	p.sample("letshare_build_info", 1, "version", Version)
+	p.metric("letshare_uptime_seconds", "gauge", "Seconds since the server started.")
+	p.sample("letshare_uptime_seconds", int64(m.uptime().Seconds()))
+	p.metric("letshare_shared_files", "gauge", "Files, folders & text snippets currently shared.")
+	p.sample("letshare_shared_files", int64(sharedFiles))
+	p.metric("letshare_active_downloads", "gauge", "Downloads currently in progress.")
+	p.sample("letshare_active_downloads", int64(activeDowns))
+	p.metric("letshare_served_bytes_total", "counter", "Bytes of the response bodies served.")
+	p.sample("letshare_served_bytes_total", m.bytesServed)
+
+	p.metric("letshare_requests_total", "counter", "Requests served per route.")
+	for _, route := range slices.Sorted(maps.Keys(m.requests)) {
+		p.sample("letshare_requests_total", int64(m.requests[route]), "route", route)
+	}
+	p.metric("letshare_errors_total", "counter", "Error responses (4xx & 5xx) per status code.")
+	for _, code := range slices.Sorted(maps.Keys(m.errors)) {
+		p.sample("letshare_errors_total", int64(m.errors[code]), "code", strconv.Itoa(code))
+	}
+
+	clients := slices.Sorted(maps.Keys(m.clients))
+	p.metric("letshare_client_requests_total", "counter", "Requests served per client IP.")
+	for _, c := range clients {
+		p.sample("letshare_client_requests_total", int64(m.clients[c].requests), "client", c)
+	}
+	p.metric("letshare_client_served_bytes_total", "counter", "Bytes of the response bodies served per client IP.")
+	for _, c := range clients {
+		p.sample("letshare_client_served_bytes_total", m.clients[c].bytesServed, "client", c)
+	}
+}
+
+// promWriter writes metrics in the Prometheus text exposition format, it stops writing at the first error,
+// i.e. the scraper is gone.
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) metric(name, typ, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample of the metric, labels are pairs of label names & values.
+func (p *promWriter) sample(name string, v int64, labels ...string) {
+	if len(labels) == 0 {
+		p.printf("%s %d\n", name, v)
+		return
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+promEscaper.Replace(labels[i+1])+`"`)
+	}
+	p.printf("%s{%s} %d\n", name, strings.Join(pairs, ","), v)
+}
+
+func (p *promWriter) printf(format string, args ...any) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// promEscaper escapes label values, as per the text exposition format.
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_HealthAndMetrics(t *testing.T) {
+	s := New(config.ShareConfig{Secret: "1234"}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	s.SetInstance("lab")
+	f := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(f, []byte("hello"), 0o644))
+	s.setFilePaths(f)
+	h := s.routes()
+
+	serve := func(target, ip string, auth bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Host = "localhost"
+		r.RemoteAddr = ip + ":5000"
+		r.Header.Set("Accept", "application/json")
+		if auth {
+			r.Header.Set("Authorization", "Bearer 1234")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/healthz", "198.51.100.1", false)
+	require.Equal(t, http.StatusOK, w.Code, "health checks need no secret")
+	var health map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, "ok", health["status"])
+	assert.Equal(t, "lab", health["instance"])
+	assert.EqualValues(t, 1, health["sharedFiles"])
+
+	id, _ := s.accessID(f)
+	assert.Equal(t, http.StatusOK, serve("/"+id, "198.51.100.2", true).Code)
+	assert.Equal(t, http.StatusNotFound, serve("/42", "198.51.100.2", true).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("/metrics", "198.51.100.3", false).Code, "metrics sit behind the secret")
+
+	w = serve("/metrics", "198.51.100.3", true)
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	for _, sample := range []string{
+		`letshare_build_info{version="UNKNOWN"} 1`,
+		"letshare_shared_files 1",
+		"letshare_active_downloads 0",
+		`letshare_requests_total{route="GET /healthz"} 1`,
+		`letshare_requests_total{route="GET /{id}"} 2`,
+		`letshare_errors_total{code="401"} 1`,
+		`letshare_errors_total{code="404"} 1`,
+		`letshare_client_requests_total{client="198.51.100.2"} 2`,
+	} {
+		assert.Contains(t, body, sample+"\n")
+	}
+	assert.Contains(t, body, "# TYPE letshare_served_bytes_total counter\n")
+
+	s.StopCtxCancel()
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/healthz", "198.51.100.1", false).Code)
+}
+
+func TestPromWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	p := promWriter{w: w}
+	p.sample("m", 3, "route", "a\"b\\c\nd", "code", "200")
+	assert.Equal(t, `m{route="a\"b\\c\nd",code="200"} 3`+"\n", w.Body.String())
+}
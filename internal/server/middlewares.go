@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
 	"strings"
@@ -58,3 +59,42 @@ func (s *Server) secureHeaders(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireSecret guards the routes behind the share secret, if one is set.
+// Clients authenticate through the "Authorization: Bearer <secret>" header,
+// browsers are redirected to the login page, which sets a session cookie on success.
+func (s *Server) requireSecret(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.secret == "" || s.hasValidSession(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := remoteIP(r)
+		if s.isLockedOut(ip) {
+			s.tooManyAttemptsResponse(w, r)
+			return
+		}
+
+		if token, ok := secretToken(r); ok {
+			if s.isValidSecret(token) {
+				s.resetFailedAttempts(ip)
+				next.ServeHTTP(w, r)
+				return
+			}
+			s.recordFailedAttempt(ip, requestedBy(r))
+			s.secretRequiredResponse(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, davPrefix+"/") {
+			s.davSecretRequiredResponse(w, r) // file managers ask for the secret as a password
+			return
+		}
+		if r.Header.Get("Accept") == "application/json" {
+			s.secretRequiredResponse(w, r)
+			return
+		}
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// previewKey is the request context key set on the previews, see Server.previewHandler.
+type previewKey struct{}
+
+// previewHandler serves the shared file identified by the access id, or a file inside a shared directory,
+// inline for the previews of the web UI, see previewKind, text is always served as plain text, so a shared
+// HTML file is shown, not rendered. Previews are served like the downloads, see Server.serveFileHandler,
+// so they are logged, audited, tracked & counted against the share limits the same way, a preview loaded
+// whole is a download, a bounded Range, e.g. the excerpt of a text, never is, see completedDownload.
+//
+// Returns:
+//   - Success (200 OK / 206 Partial Content): The file, or the requested range of it
+//   - Error (404 Not Found): If the file does not exist, or cannot be previewed
+//   - Error (410 Gone): If the share limits of the file are reached
+func (s *Server) previewHandler(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(strings.Trim(r.PathValue("path"), "/"))
+	if r.PathValue("path") == "" {
+		filePath, ok := s.filePath(r.PathValue("id"))
+		if !ok {
+			s.notFoundResponse(w, r)
+			return
+		}
+		name = filepath.Base(filePath)
+	}
+	kind := previewKind(name)
+	if kind == "" {
+		s.notFoundResponse(w, r)
+		return
+	}
+	if kind == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	s.serveFileHandler(w, r.WithContext(context.WithValue(r.Context(), previewKey{}, true)))
+}
+
+// isPreview reports whether the file is served for a preview, see Server.previewHandler.
+func isPreview(r *http.Request) bool {
+	preview, _ := r.Context().Value(previewKey{}).(bool)
+	return preview
+}
+
+// contentDisposition returns the Content-Disposition of the file served for the request,
+// inline for the previews, as an attachment otherwise.
+func contentDisposition(r *http.Request, name string) string {
+	if isPreview(r) {
+		return "inline; filename=\"" + name + "\""
+	}
+	return "attachment; filename=\"" + name + "\""
+}
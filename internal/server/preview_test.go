@@ -0,0 +1,62 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_PreviewHandler(t *testing.T) {
+	s := New(config.ShareConfig{MaxDownloads: 1}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	dir, other := t.TempDir(), t.TempDir()
+	notes, page, bundle := filepath.Join(dir, "notes.txt"), filepath.Join(dir, "page.html"), filepath.Join(other, "bundle.zip")
+	require.NoError(t, os.WriteFile(notes, []byte("notes"), 0o644))
+	require.NoError(t, os.WriteFile(page, []byte("<script>alert(1)</script>"), 0o644))
+	require.NoError(t, os.WriteFile(bundle, []byte("PK"), 0o644))
+	s.setFilePaths(notes, page, bundle, dir)
+	h := s.routes()
+
+	serve := func(target, rng string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Host = "localhost"
+		r.RemoteAddr = "198.51.100.7:50000"
+		if rng != "" {
+			r.Header.Set("Range", rng)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	id := func(p string) string {
+		id, _ := s.accessID(p)
+		return id
+	}
+
+	for range 2 { // the excerpt of a text, as the web UI asks for it
+		w := serve("/preview/"+id(notes), "bytes=0-65535")
+		require.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "notes", w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "inline", "previews are shown inline")
+	}
+	assert.True(t, s.available(id(notes)), "a bounded excerpt is not a download")
+
+	w := serve("/preview/"+id(notes), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, s.available(id(notes)), "a preview loaded whole is a download")
+	assert.Equal(t, http.StatusGone, serve("/preview/"+id(notes), "").Code, "previews must respect the download limit")
+	assert.Equal(t, http.StatusGone, serve("/"+id(notes), "").Code)
+
+	w = serve("/preview/"+id(page), "bytes=0-65535")
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"), "shared HTML must not be rendered")
+
+	assert.Equal(t, http.StatusOK, serve("/preview/"+id(dir)+"/notes.txt", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/preview/"+id(bundle), "").Code, "only previewable files are served")
+	assert.Equal(t, http.StatusNotFound, serve("/preview/"+id(dir), "").Code)
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// htmlPerPage is the files listed per page in the browser, JSON clients get all at once, unless they ask
+	htmlPerPage = 100
+	// maxPerPage caps the files per page a client may ask for
+	maxPerPage = 1000
+)
+
+// parseIndexQuery parses the sorting, filtering & pagination of the index from the query parameters,
+// see domain.IndexQuery.Values, perPage is used if the client did not ask for the files per page.
+func parseIndexQuery(v url.Values, perPage int) (domain.IndexQuery, error) {
+	q := domain.IndexQuery{
+		Sort:    strings.ToLower(v.Get("sort")),
+		Search:  strings.TrimSpace(v.Get("q")),
+		Page:    1,
+		PerPage: perPage,
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = domain.SortByName
+	case domain.SortByName, domain.SortBySize, domain.SortByType, domain.SortByDate:
+	default:
+		return q, errors.New("sort must be one of name, size, type or date")
+	}
+
+	switch strings.ToLower(v.Get("order")) {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, errors.New("order must be either asc or desc")
+	}
+
+	for _, ext := range v["ext"] {
+		for e := range strings.SplitSeq(ext, ",") {
+			e = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(e), "."))
+			if e != "" && !slices.Contains(q.Exts, e) {
+				q.Exts = append(q.Exts, e)
+			}
+		}
+	}
+
+	var err error
+	if p := v.Get("page"); p != "" {
+		if q.Page, err = strconv.Atoi(p); err != nil || q.Page < 1 {
+			return q, errors.New("page must be a positive number")
+		}
+	}
+	if pp := v.Get("per_page"); pp != "" {
+		if q.PerPage, err = strconv.Atoi(pp); err != nil || q.PerPage < 0 || q.PerPage > maxPerPage {
+			return q, errors.New("per_page must be a number between 0 & " + strconv.Itoa(maxPerPage))
+		}
+	}
+	return q, nil
+}
+
+// queryIndex filters & sorts the file indexes as per the query, matched are all the files
+// matching it, page are the ones on the page asked for, empty if past the last page.
+func queryIndex(fsInfos []*domain.FileInfo, q domain.IndexQuery) (matched, page []*domain.FileInfo) {
+	search := strings.ToLower(q.Search)
+	matched = slices.DeleteFunc(slices.Clone(fsInfos), func(fi *domain.FileInfo) bool {
+		if search != "" && !strings.Contains(strings.ToLower(fi.Name), search) {
+			return true
+		}
+		// folders have no extension, so they never match one
+		return len(q.Exts) > 0 && (fi.IsDir || !slices.Contains(q.Exts, fileExt(fi.Name)))
+	})
+
+	slices.SortStableFunc(matched, func(a, b *domain.FileInfo) int {
+		var c int
+		switch q.Sort {
+		case domain.SortBySize:
+			c = cmp.Compare(a.Size, b.Size)
+		case domain.SortByType:
+			c = cmp.Compare(sortType(a), sortType(b))
+		case domain.SortByDate:
+			c = a.ModTime.Compare(b.ModTime)
+		}
+		if c == 0 { // ties, & sorting by name
+			c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		if q.Desc {
+			return -c
+		}
+		return c
+	})
+
+	if q.PerPage == 0 {
+		return matched, matched
+	}
+	start := min((q.Page-1)*q.PerPage, len(matched))
+	end := min(start+q.PerPage, len(matched))
+	return matched, matched[start:end]
+}
+
+// sortType is the type the file is sorted by, folders come before the files.
+func sortType(fi *domain.FileInfo) string {
+	if fi.IsDir {
+		return ""
+	}
+	return "." + fileExt(fi.Name)
+}
+
+// fileExt returns the lowercase extension of the file without the leading dot,
+// empty for files without one, like README or .gitignore, see fileType.
+func fileExt(name string) string {
+	if strings.LastIndex(name, ".") <= 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+}
+
+// pageCount returns the number of pages the matched files are listed on, at least 1.
+func pageCount(matched int, q domain.IndexQuery) int {
+	if q.PerPage == 0 || matched == 0 {
+		return 1
+	}
+	return (matched + q.PerPage - 1) / q.PerPage
+}
+
+// pageURL returns the relative URL of the page of the query, keeping the rest of the query as is,
+// the default files per page are left out, so the links stay short.
+func pageURL(q domain.IndexQuery, page int) string {
+	q.Page = page
+	if q.PerPage == htmlPerPage {
+		q.PerPage = 0
+	}
+	if q.Sort == domain.SortByName {
+		q.Sort = ""
+	}
+	v := q.Values()
+	if page == 1 {
+		v.Del("page")
+	}
+	return "?" + v.Encode()
+}
+
+// perPage returns the files listed per page by default, browsers get them in pages, JSON clients all at once.
+func perPage(preferJSON bool) int {
+	if preferJSON {
+		return 0
+	}
+	return htmlPerPage
+}
+
+// setQuery sets the query the Files are listed with & the links to the neighbouring pages,
+// matched are all the files matching the query.
+func (d *indexFileTemplateData) setQuery(q domain.IndexQuery, matched []*domain.FileInfo) {
+	d.Query = q
+	d.Matched = len(matched)
+	d.Filtered = len(matched) < d.TotalFiles
+	d.Pages = pageCount(len(matched), q)
+	if q.Page > 1 {
+		d.PrevURL = pageURL(q, min(q.Page-1, d.Pages))
+	}
+	if q.Page < d.Pages {
+		d.NextURL = pageURL(q, q.Page+1)
+	}
+	d.ArchiveURL = archiveURL(matched)
+}
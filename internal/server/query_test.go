@@ -0,0 +1,74 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseIndexQuery(t *testing.T) {
+	q, err := parseIndexQuery(url.Values{}, htmlPerPage)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.IndexQuery{Sort: domain.SortByName, Page: 1, PerPage: htmlPerPage}, q)
+
+	want := domain.IndexQuery{Sort: domain.SortBySize, Desc: true, Search: "trip", Exts: []string{"jpg", "png"}, Page: 3, PerPage: 20}
+	q, err = parseIndexQuery(want.Values(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, want, q, "the query must survive a round trip through its query parameters")
+
+	q, err = parseIndexQuery(url.Values{"ext": {".JPG, png", "jpg"}}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"jpg", "png"}, q.Exts)
+
+	for _, v := range []url.Values{
+		{"sort": {"color"}},
+		{"order": {"up"}},
+		{"page": {"0"}},
+		{"per_page": {"100000"}},
+	} {
+		_, err = parseIndexQuery(v, 0)
+		assert.Error(t, err, v.Encode())
+	}
+}
+
+func TestQueryIndex(t *testing.T) {
+	now := time.Now()
+	files := []*domain.FileInfo{
+		{Name: "b.png", Size: 30, ModTime: now},
+		{Name: "A.jpg", Size: 10, ModTime: now.Add(-time.Hour)},
+		{Name: "photos", IsDir: true, ModTime: now.Add(-2 * time.Hour)},
+		{Name: "c.txt", Size: 20, ModTime: now.Add(time.Hour)},
+	}
+	names := func(fi []*domain.FileInfo) []string {
+		n := make([]string, len(fi))
+		for i, f := range fi {
+			n[i] = f.Name
+		}
+		return n
+	}
+
+	matched, page := queryIndex(files, domain.IndexQuery{})
+	assert.Equal(t, []string{"A.jpg", "b.png", "c.txt", "photos"}, names(page), "sorted by name, case-insensitive")
+	assert.Len(t, matched, 4)
+
+	_, page = queryIndex(files, domain.IndexQuery{Sort: domain.SortBySize, Desc: true})
+	assert.Equal(t, []string{"b.png", "c.txt", "A.jpg", "photos"}, names(page))
+
+	_, page = queryIndex(files, domain.IndexQuery{Sort: domain.SortByType})
+	assert.Equal(t, []string{"photos", "A.jpg", "b.png", "c.txt"}, names(page), "folders must come first")
+
+	_, page = queryIndex(files, domain.IndexQuery{Sort: domain.SortByDate})
+	assert.Equal(t, []string{"photos", "A.jpg", "b.png", "c.txt"}, names(page))
+
+	matched, page = queryIndex(files, domain.IndexQuery{Exts: []string{"jpg", "png"}, Page: 2, PerPage: 1})
+	assert.Len(t, matched, 2, "folders never match an extension")
+	assert.Equal(t, []string{"b.png"}, names(page))
+	assert.Equal(t, 2, pageCount(len(matched), domain.IndexQuery{PerPage: 1}))
+
+	matched, page = queryIndex(files, domain.IndexQuery{Search: "PHO", Page: 5, PerPage: 10})
+	assert.Equal(t, []string{"photos"}, names(matched))
+	assert.Empty(t, page, "pages past the last one are empty")
+	assert.Equal(t, "b.png", files[0].Name, "the given files must be left as is")
+}
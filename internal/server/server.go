@@ -1,16 +1,26 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/audit"
 	"github.com/MuhamedUsman/letshare/internal/bgtask"
+	"github.com/MuhamedUsman/letshare/internal/cert"
 	"github.com/MuhamedUsman/letshare/internal/config"
 	"github.com/MuhamedUsman/letshare/internal/domain"
 	"github.com/MuhamedUsman/letshare/internal/network"
+	"github.com/MuhamedUsman/letshare/internal/throttle"
 	"github.com/MuhamedUsman/letshare/internal/webui"
-	"hash/crc32"
+	"golang.org/x/net/webdav"
+	"io"
+	"maps"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"slices"
@@ -21,13 +31,18 @@ import (
 )
 
 const (
-	DefaultPort  = 80
-	TestHTTPPort = 8080
+	DefaultPort    = 80
+	DefaultTLSPort = 443
+	TestHTTPPort   = 8080
+	TestHTTPSPort  = 8443
 )
 
 type Log struct {
 	Msg  string
 	Args []any
+	// ID groups the logs of a single long-running operation (e.g. an upload),
+	// so the log view can replace the previous entry instead of appending a new one.
+	ID string
 }
 
 // tlog is used to lazy Log messages from the server which tui will display
@@ -43,6 +58,14 @@ func (t tlog) info(msg string, args ...any) {
 	}
 }
 
+// progress is same as info, but marks the Log with an id, see Log.ID
+func (t tlog) progress(id, msg string, args ...any) {
+	select {
+	case t.logCh <- Log{Msg: msg, Args: args, ID: id}:
+	default:
+	}
+}
+
 func (t tlog) relayActiveDown(n int, force bool) {
 	if force {
 		t.activeDownCh <- n
@@ -64,55 +87,205 @@ func (e ShutdownErr) Error() string {
 }
 
 type Server struct {
-	// file paths to be served, [K: accessID, V: filepath]
-	FilePaths map[uint32]string
+	// file paths to be served, [K: accessID, V: filepath], guarded by mu
+	// once the server starts, see Server.AddFiles & Server.RemoveFiles
+	FilePaths map[string]string
 	log       tlog
 	mu        *sync.Mutex
 	// Once Done, the server will exit
 	StopCtx context.Context
 	// Cancel func for StopCtx
 	StopCtxCancel context.CancelFunc
-	// notifyCh is used to notify for the server shutdown request when ActiveDowns > 0
-	notifyCh chan<- string // X-Requested-By header value
 	// indicates if the server is idling or currently serving files
 	ActiveDowns   int
 	alreadyLogged map[string]struct{}
 	// Option to let others on the same LAN to stopHandler this instance from hosting
 	Stoppable bool
+	// stop requests for the host to decide, see Server.StopRequests
+	stopReqCh chan StopReq
+	// pending stop requests, [K: remote IP, V: stopDecision]
+	stopReqs map[string]*stopDecision
+	// IPs of the peers that stop the idle server without asking, see Server.isTrustedStopper
+	trustedPeers []string
+	// stopToken lets its holders stop the idle server without asking, empty means no token
+	stopToken string
+	// secret (PIN/password) required to access the share, empty means no secret
+	secret string
+	// sessionToken is set as a cookie on browsers after a successful login
+	sessionToken string
+	// failed secret attempts per remote IP, see Server.requireSecret
+	failedAttempts map[string]*secretAttempts
+	// serve over HTTPS with the self-signed device certificate, see cert.Load
+	https bool
+	// limits of the share, see Limits
+	limits Limits
+	// when the share expires, set by Server.StartServer, zero if it never expires
+	expiresAt time.Time
+	// completed downloads per accessID, see Server.recordDownload
+	downloads map[string]int
+	// downloads in progress per accessID, taken from the ones left, see Server.reserveDownload
+	reserved map[string]int
+	// rateLimiter throttles the whole server, see Server.limitBandwidth
+	rateLimiter *throttle.Limiter
+	// clientRate in bytes per second, of each of the clientLimiters
+	clientRate int64
+	// clientLimiters throttle each client, [K: remote IP, V: limiter]
+	clientLimiters map[string]*throttle.Limiter
+	// approvalCh is nil, unless the host is asked before sharing, see Server.Approvals
+	approvalCh chan ApprovalReq
+	// host's decisions, pending or made, [K: remote IP, V: approval]
+	approvals map[string]*approval
+	// SHA-256 of the shared files, see Server.digest
+	digests *digests
+	// removed paths, still deleted on shutdown if temporary, see Server.deleteTempFiles
+	removedPaths []string
+	// events pushed to the receivers, see Server.eventsHandler
+	events *eventFeed
+	// thumbnails of the shared images, see Server.thumbnailHandler
+	thumbnails *thumbnails
+	// downloads in progress, see Server.Transfers
+	transfers *transfers
+	// text snippets shared alongside the files, [K: accessID, V: snippet], guarded by mu, see Server.AddSnippet
+	snippets map[string]*snippet
+	// davLocks are the locks taken by the WebDAV clients, see Server.davHandler
+	davLocks webdav.LockSystem
+	// davUploads are the files uploaded through WebDAV, [K: IP, V: [K: name, V: path]], guarded by mu
+	davUploads map[string]map[string]string
+	// accessKey derives the access ids, see Server.accessID, a random one till the
+	// persisted key is loaded by Server.configureServer, guarded by mu
+	accessKey []byte
+	// instance is the mDNS instance the server is published as, guarded by mu, see Server.SetInstance
+	instance string
+	// usage counters, see Server.metricsHandler
+	metrics *metrics
+	// auditLog records every request served, opened by Server.configureServer, nil if it can't be opened
+	auditLog *audit.Log
+	// access rules of the clients, see Server.filterClients
+	access accessRules
+	// interfaces to bind to, names or addresses, empty to pick automatically, see Server.Interfaces
+	interfaces []string
+	// port to bind to first, 0 for the default of the scheme, see Server.Port
+	port int
+	// boundPort is the port the server is listening on, guarded by mu, 0 till it listens
+	boundPort int
+	// listening is closed once the server listens, see Server.Listening
+	listening chan struct{}
 }
 
-func New(stoppable bool, logCh chan<- Log, activeDownCh chan<- int) *Server {
+// New creates a Server configured as per the ShareConfig, the config is captured at creation,
+// so changing the preferences does not affect a running server, except for Server.SetRateLimits.
+func New(cfg config.ShareConfig, logCh chan<- Log, activeDownCh chan<- int) *Server {
 	ctx, cancel := context.WithCancel(bgtask.Get().ShutdownCtx())
 	l := tlog{logCh: logCh, activeDownCh: activeDownCh}
+	var approvalCh chan ApprovalReq
+	if cfg.AskBeforeSharing {
+		approvalCh = make(chan ApprovalReq)
+	}
 	return &Server{
-		FilePaths:     make(map[uint32]string),
-		log:           l,
-		mu:            new(sync.Mutex),
-		StopCtx:       ctx,
-		StopCtxCancel: cancel,
-		alreadyLogged: make(map[string]struct{}),
-		Stoppable:     stoppable,
+		FilePaths:      make(map[string]string),
+		log:            l,
+		mu:             new(sync.Mutex),
+		StopCtx:        ctx,
+		StopCtxCancel:  cancel,
+		alreadyLogged:  make(map[string]struct{}),
+		Stoppable:      cfg.StoppableInstance,
+		stopReqCh:      make(chan StopReq),
+		stopReqs:       make(map[string]*stopDecision),
+		trustedPeers:   cfg.TrustedPeers,
+		stopToken:      cfg.StopToken,
+		secret:         cfg.Secret,
+		sessionToken:   rand.Text(),
+		failedAttempts: make(map[string]*secretAttempts),
+		https:          cfg.HTTPS,
+		limits: Limits{
+			Expiry:       time.Duration(cfg.ExpiryMinutes) * time.Minute,
+			MaxDownloads: cfg.MaxDownloads,
+		},
+		downloads:      make(map[string]int),
+		reserved:       make(map[string]int),
+		rateLimiter:    throttle.NewLimiter(kbps(cfg.RateLimit)),
+		clientRate:     kbps(cfg.ClientRateLimit),
+		clientLimiters: make(map[string]*throttle.Limiter),
+		approvalCh:     approvalCh,
+		approvals:      make(map[string]*approval),
+		digests:        newDigests(),
+		events:         newEventFeed(),
+		thumbnails:     newThumbnails(),
+		transfers:      newTransfers(),
+		snippets:       make(map[string]*snippet),
+		accessKey:      newAccessKey(),
+		davLocks:       webdav.NewMemLS(),
+		davUploads:     make(map[string]map[string]string),
+		metrics:        newMetrics(),
+		access:         newAccessRules(cfg.AllowList, cfg.DenyList),
+		interfaces:     cfg.Interfaces,
+		port:           cfg.Port,
+		listening:      make(chan struct{}),
+	}
+}
+
+// Port returns the port the server is listening on, once it is, see Server.Listening,
+// till then, the port it binds to first, the configured one or the default, see GetPort.
+func (s *Server) Port() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.boundPort != 0 {
+		return s.boundPort
+	}
+	return cmp.Or(s.port, GetPort(s.https))
+}
+
+// Scheme returns the URL scheme the server is served with, "https" or "http", it is fixed once the
+// server is created, so it stays the same even if ShareConfig.HTTPS is changed while serving.
+func (s *Server) Scheme() string {
+	if s.https {
+		return "https"
 	}
+	return "http"
+}
+
+// Listening returns a channel closed once the server is listening, Server.Port then returns the
+// port it is reachable on, which is published over mDNS, it is never closed if the server fails to start.
+func (s *Server) Listening() <-chan struct{} {
+	return s.listening
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate the server is
+// serving with, to be published over mDNS, empty if the server is not serving over HTTPS.
+func (s *Server) Fingerprint() (string, error) {
+	if !s.https {
+		return "", nil
+	}
+	c, err := cert.Load()
+	if err != nil {
+		return "", err
+	}
+	return c.Fingerprint, nil
 }
 
 // StartServer starts an HTTP/HTTPS server that serves files from Server.FilePaths.
-// It binds to the machine's outbound IP address and handles graceful shutdown.
-// NOTE: This must run first before MDNS entry is published as it dynamically determines
-// the port to bind to, based on tls certificate availability.
-// For more info see GetPort() && Server.configureServer().
+// It binds to the addresses of the selected network interfaces and handles graceful shutdown.
+// NOTE: The MDNS entry must be published once the server is listening, see Server.Listening(),
+// as the port is only known then, the preferred one may be unavailable, e.g. port 80 without root privileges.
+// For more info see Server.Port() && Server.configureServer().
 //
 // Returns:
 //   - error: An error if the server fails to start, encounters issues during shutdown,
 //     or if background tasks cannot be properly terminated.
 //
 // Note:
-//   - Uses GetOutboundIP() to determine the IP address for binding.
+//   - Uses Server.Interfaces() to determine the IP addresses for binding.
 //   - Will wait up to 2 seconds for server shutdown & 5 seconds for background tasks.
 func (s *Server) StartServer(filePaths ...string) error {
-	server, err := s.configureServer()
+	server, listeners, err := s.configureServer()
 	if err != nil {
+		s.StopCtxCancel() // never listened, so nothing waits on it any longer
 		return err
 	}
+	s.mu.Lock()
+	s.boundPort = listeners[0].Addr().(*net.TCPAddr).Port
+	s.mu.Unlock()
+	close(s.listening)
 
 	s.setFilePaths(filePaths...)
 	defer func() {
@@ -124,9 +297,18 @@ func (s *Server) StartServer(filePaths ...string) error {
 		close(s.log.activeDownCh)
 	}()
 
-	s.log.info("Starting server", "Addr", server.Addr)
+	addrs := make([]string, len(listeners))
+	for i, ln := range listeners {
+		addrs[i] = ln.Addr().String()
+	}
+	s.log.info("Starting server", "Addr", strings.Join(addrs, ", "), "HTTPS", s.https)
+	if s.limits.Expiry > 0 {
+		s.expiresAt = time.Now().Add(s.limits.Expiry)
+		go s.expireAfter(s.limits.Expiry)
+	}
 	errChan := s.listenAndShutdown(server)
-	if err = server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	err = s.serve(server, listeners)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		return ShutdownErr{err, s.ActiveDowns}
@@ -138,42 +320,152 @@ func (s *Server) StartServer(filePaths ...string) error {
 	return nil
 }
 
-// configureServer sets up the HTTP server with the necessary configurations.
+// Interfaces returns the network interfaces the server binds to, as per the share preferences,
+// see network.Select, each of them is published over mDNS with its own addresses.
+func (s *Server) Interfaces() ([]network.Interface, error) {
+	return network.Select(s.interfaces)
+}
+
+// configureServer sets up the HTTP server with the necessary configurations,
+// & listens on every address of the selected network interfaces, see Server.listen.
 //
 // Returns:
 //   - *http.Server: Configured HTTP server instance.
+//   - []net.Listener: A listener per address, to be served, see Server.serve.
 //   - error: An error if there is an issue during process.
-func (s *Server) configureServer() (*http.Server, error) {
-	addr, err := network.GetOutboundIP()
+func (s *Server) configureServer() (*http.Server, []net.Listener, error) {
+	if s.access.err != nil {
+		return nil, nil, fmt.Errorf("parsing access rules: %w", s.access.err)
+	}
+	ifaces, err := s.Interfaces()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	addrs := network.Addrs(ifaces)
 
 	var proto http.Protocols
-	proto.SetUnencryptedHTTP2(true)
 	proto.SetHTTP1(true)
 
+	var tlsCfg *tls.Config
+	if s.https {
+		c, err := cert.Load()
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading tls certificate: %w", err)
+		}
+		tlsCfg = &tls.Config{
+			Certificates: []tls.Certificate{c.TLS},
+			MinVersion:   tls.VersionTLS12,
+		}
+		proto.SetHTTP2(true)
+	} else {
+		proto.SetUnencryptedHTTP2(true)
+	}
+
+	key, err := loadAccessKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading access key: %w", err)
+	}
+	s.setAccessKey(key)
+
+	listeners, err := s.listen(addrs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.auditLog, err = audit.Open(); err != nil {
+		s.log.info("Audit log is unavailable, requests are not recorded", "Err", err)
+	}
+
 	server := &http.Server{
-		Addr:              fmt.Sprint(addr, ":", GetPort()),
+		// the first of the listeners, the server is served on all of them, see Server.serve
+		Addr:              listeners[0].Addr().String(),
 		Handler:           s.routes(),
 		ReadTimeout:       4 * time.Second,
 		ReadHeaderTimeout: 2 * time.Second,
 		IdleTimeout:       10 * time.Second,
 		Protocols:         &proto,
+		TLSConfig:         tlsCfg,
 	}
-	return server, nil
+	return server, listeners, nil
 }
 
-func (s *Server) ShutdownServer() {
+// listen listens on every address at the same port, the preferred one, see Server.Port, if it can't be bound,
+// e.g. port 80 without root privileges or a port already in use, a free port is picked by the OS instead.
+func (s *Server) listen(addrs []netip.Addr) ([]net.Listener, error) {
+	port := s.Port()
+	listeners, err := listenAll(addrs, port)
+	if err == nil {
+		return listeners, nil
+	}
+	listeners, fallbackErr := listenAll(addrs, 0)
+	if fallbackErr != nil {
+		return nil, err // the preferred port is the one the user knows about
+	}
+	fallback := listeners[0].Addr().(*net.TCPAddr).Port
+	s.log.info("Preferred port is unavailable, serving on another one", "Port", port, "Fallback", fallback, "Err", err)
+	return listeners, nil
+}
+
+// listenAll listens on every address at the port, 0 for a port picked by the OS, shared by the rest of the addresses,
+// either it listens on all of them, or on none.
+func listenAll(addrs []netip.Addr, port int) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", netip.AddrPortFrom(addr, uint16(port)).String())
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("listening on %s: %w", netip.AddrPortFrom(addr, uint16(port)), err)
+		}
+		listeners = append(listeners, ln)
+		port = ln.Addr().(*net.TCPAddr).Port
+	}
+	return listeners, nil
+}
+
+// URLHost returns the host of the URL the server is reachable on, the port is
+// left out if it is the default of the scheme, so the URL stays short.
+func URLHost(host string, port int, secure bool) string {
+	hostPort := net.JoinHostPort(host, strconv.Itoa(port))
+	if (!secure && port == DefaultPort) || (secure && port == DefaultTLSPort) {
+		return strings.TrimSuffix(hostPort, ":"+strconv.Itoa(port)) // IPv6 hosts keep their brackets
+	}
+	return hostPort
+}
+
+// serve serves the server on all the listeners, till it is shut down, if any of them fails,
+// the server is closed, so the rest stop as well, returns the first error.
+func (s *Server) serve(server *http.Server, listeners []net.Listener) error {
+	errCh := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func() {
+			if s.https {
+				// certificate is already in server.TLSConfig
+				errCh <- server.ServeTLS(ln, "", "")
+			} else {
+				errCh <- server.Serve(ln)
+			}
+		}()
+	}
+	err := <-errCh
+	if !errors.Is(err, http.ErrServerClosed) {
+		_ = server.Close()
+	}
+	return err
+}
+
+// SetStoppable sets whether others on the LAN may stop the server when it is idle, see Server.stopHandler.
+func (s *Server) SetStoppable(stoppable bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.StopCtxCancel()
+	s.Stoppable = stoppable
 }
 
-func (s *Server) NotifyForShutdownReqWhenNotIdle(ch chan<- string) {
+func (s *Server) ShutdownServer() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.notifyCh = ch
+	s.StopCtxCancel()
 }
 
 func (s *Server) listenAndShutdown(server *http.Server) chan error {
@@ -195,14 +487,33 @@ func (s *Server) listenAndShutdown(server *http.Server) chan error {
 
 func (s *Server) routes() http.Handler {
 	mux := http.NewServeMux()
-	base := newChain(s.recoverPanic, s.disallowOSHostnames, s.secureHeaders)
+	base := newChain(s.recoverPanic, s.disallowOSHostnames, s.filterClients, s.secureHeaders)
+	protected := newChain(s.recoverPanic, s.disallowOSHostnames, s.filterClients, s.secureHeaders, s.requireSecret, s.requireApproval, s.limitBandwidth)
 
 	fileServer := http.FileServer(http.FS(webui.Files))
 	mux.Handle("GET /static/", base.then(fileServer))
-	mux.Handle("GET /{$}", base.thenFunc(s.indexFilesHandler))
-	mux.Handle("GET /{id}", base.thenFunc(s.serveFileHandler))
+	mux.Handle("GET /login", base.thenFunc(s.loginPageHandler))
+	mux.Handle("POST /login", base.thenFunc(s.loginHandler))
+	mux.Handle("GET /{$}", protected.thenFunc(s.indexFilesHandler))
+	mux.Handle("GET /{id}", protected.thenFunc(s.serveFileHandler))
+	mux.Handle("GET /{id}/{path...}", protected.thenFunc(s.serveFileHandler))
+	mux.Handle("GET /thumb/{id}", protected.thenFunc(s.thumbnailHandler))
+	mux.Handle("GET /thumb/{id}/{path...}", protected.thenFunc(s.thumbnailHandler))
+	mux.Handle("GET /preview/{id}", protected.thenFunc(s.previewHandler))
+	mux.Handle("GET /preview/{id}/{path...}", protected.thenFunc(s.previewHandler))
+	mux.Handle("GET /archive", protected.thenFunc(s.archiveHandler))
+	mux.Handle("GET /events", protected.thenFunc(s.eventsHandler))
+	mux.Handle("POST /upload", protected.thenFunc(s.uploadHandler))
 	mux.Handle("POST /stop", base.thenFunc(s.stopHandler))
-	return mux
+	mux.Handle("GET /healthz", base.thenFunc(s.healthHandler))
+	// file managers mounting the share over WebDAV, see Server.davHandler
+	for _, method := range slices.Concat(davReadMethods, davWriteMethods) {
+		mux.Handle(method+" "+davPrefix+"/", protected.thenFunc(s.davHandler))
+	}
+	// scrapers authenticate with the bearer token, they cannot be asked for approval
+	metered := newChain(s.recoverPanic, s.disallowOSHostnames, s.filterClients, s.secureHeaders, s.requireSecret)
+	mux.Handle("GET /metrics", metered.thenFunc(s.metricsHandler))
+	return s.instrument(mux)
 }
 
 type indexFileTemplateData struct {
@@ -210,6 +521,26 @@ type indexFileTemplateData struct {
 	TotalFiles   int
 	TotalSize    int64
 	Files        []*domain.FileInfo
+	AllowUploads bool
+	// Location is the browsed shared directory, e.g. "build/src", empty on the index page
+	Location string
+	// ParentURL links to the parent directory, when browsing a shared directory
+	ParentURL string
+	// ExpiresAt is when the share expires, zero if it never expires
+	ExpiresAt time.Time
+	// Query is the sorting, filtering & pagination the Files are listed with, see indexFileTemplateData.setQuery
+	Query domain.IndexQuery
+	// Matched is the number of files matching the Query, across all the pages
+	Matched int
+	// Pages is the number of pages, PrevURL & NextURL link the neighbouring pages, empty if there is none
+	Pages            int
+	PrevURL, NextURL string
+	// Filtered is set, if the Query leaves some of the files out
+	Filtered bool
+	// ArchiveURL downloads all the matched files as a single zip
+	ArchiveURL string
+	// Snippets are the texts of the shared snippets, [K: accessID, V: text]
+	Snippets map[string]string
 }
 
 // indexFilesHandler creates an HTTP handler that serves file indexes for Server.FilePaths.
@@ -217,40 +548,80 @@ type indexFileTemplateData struct {
 // If an error occurs while reading the directory or generating the JSON response,
 // an error response will be returned using serverErrorResponse.
 func (s *Server) indexFilesHandler(w http.ResponseWriter, r *http.Request) {
+	preferJSON := r.Header.Get("Accept") == "application/json"
+	q, err := parseIndexQuery(r.URL.Query(), perPage(preferJSON))
+	if err != nil {
+		s.badRequestResponse(w, r, err.Error())
+		return
+	}
+
 	var fsInfos []*domain.FileInfo
-	for k, v := range s.FilePaths {
+	for k, v := range s.sharedFiles() {
+		if !s.available(k) { // expired or used up files are gone for good
+			continue
+		}
 		stat, err := os.Lstat(v)
 		if err != nil {
 			s.serverErrorResponse(w, r)
 			return
 		}
 		fsInfo := &domain.FileInfo{
-			AccessID: k,
-			Name:     stat.Name(),
-			Size:     stat.Size(),
+			AccessID:      k,
+			Name:          stat.Name(),
+			IsDir:         stat.IsDir(),
+			ExpiresAt:     s.expiresAt,
+			DownloadsLeft: s.downloadsLeft(k),
+			ModTime:       stat.ModTime(),
+		}
+		if !fsInfo.IsDir {
+			fsInfo.Size = stat.Size()
+			fsInfo.SHA256 = s.digest(v, stat, func() (io.ReadCloser, error) { return os.Open(v) })
 		}
 		fsInfos = append(fsInfos, fsInfo)
 	}
-	sortByNameAsc(fsInfos)
+	snippets := s.sharedSnippets()
+	for k, sn := range snippets {
+		if !s.available(k) {
+			continue
+		}
+		fsInfos = append(fsInfos, &domain.FileInfo{
+			AccessID:      k,
+			Name:          sn.name,
+			Size:          int64(len(sn.text)),
+			ExpiresAt:     s.expiresAt,
+			DownloadsLeft: s.downloadsLeft(k),
+			ModTime:       sn.addedAt,
+			Type:          domain.TypeSnippet,
+		})
+	}
+	matched, page := queryIndex(fsInfos, q)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(matched)))
 
 	logReq := shouldLogReq(r.RemoteAddr)
-	reqBy := r.Header.Get("X-Requested-By")
-	if reqBy == "" {
-		reqBy = strings.Split(r.RemoteAddr, ":")[0]
-	}
+	reqBy := requestedBy(r)
 
-	if r.Header.Get("Accept") == "application/json" {
-		if err := s.writeJSON(w, envelop{"fileIndexes": fsInfos}, http.StatusOK, nil); err != nil {
+	if preferJSON {
+		if err := s.writeJSON(w, envelop{"version": domain.IndexVersion, "fileIndexes": page}, http.StatusOK, nil); err != nil {
 			s.serverErrorResponse(w, r)
 		}
 	} else {
+		cfg := getConfig()
 		data := indexFileTemplateData{
-			HostUsername: getConfig().Personal.Username,
+			HostUsername: cfg.Personal.Username,
 			TotalFiles:   len(fsInfos),
 			TotalSize:    getTotalFileSize(fsInfos),
-			Files:        fsInfos,
+			Files:        page,
+			AllowUploads: cfg.Share.AllowUploads,
+			ExpiresAt:    s.expiresAt,
+			Snippets:     make(map[string]string, len(snippets)),
 		}
-		if err := s.render(w, http.StatusOK, "fileIndexes.tmpl.html", data); err != nil {
+		for k, sn := range snippets {
+			data.Snippets[k] = sn.text
+		}
+		data.setQuery(q, matched)
+		// files may be added or removed while sharing, so the browser must not reuse a stale index
+		w.Header().Set("Cache-Control", "no-cache")
+		if err := s.render(w, http.StatusOK, "fileIndexes", data); err != nil {
 			s.serverErrorResponse(w, r)
 			return
 		}
@@ -264,93 +635,146 @@ func (s *Server) indexFilesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveFileHandler serves the shared file identified by the access id,
+// shared directories are browsed & served through Server.serveTreeHandler.
 func (s *Server) serveFileHandler(w http.ResponseWriter, r *http.Request) {
-	accessID := r.PathValue("id")
-	id, err := strconv.ParseUint(accessID, 10, 32)
-	if err != nil { // Invalid access ID
-		s.notFoundResponse(w, r)
+	id := r.PathValue("id")
+	s.mu.Lock()
+	sn, isSnippet := s.snippets[id]
+	s.mu.Unlock()
+	if isSnippet {
+		if r.PathValue("path") != "" { // snippets have no children
+			s.notFoundResponse(w, r)
+			return
+		}
+		s.serveSnippet(w, r, id, sn)
 		return
 	}
 
-	filePath, ok := s.FilePaths[uint32(id)]
+	filePath, ok := s.filePath(id)
 	if !ok {
 		s.notFoundResponse(w, r)
 		return
 	}
+	if !s.available(id) {
+		s.goneResponse(w, r)
+		return
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+	rel := r.PathValue("path")
+	if stat.IsDir() {
+		s.serveTreeHandler(w, r, id, filePath, rel)
+		return
+	}
+	if rel != "" { // files have no children
+		s.notFoundResponse(w, r)
+		return
+	}
 
 	filename := filepath.Base(filePath)
-	k := fmt.Sprint(accessID, ":", strings.Split(r.RemoteAddr, ":")[0])
-	_, ok = s.alreadyLogged[k]
-	shouldLog := shouldLogReq(r.RemoteAddr) && r.Method == http.MethodGet && !ok
-
-	if shouldLog {
-		s.alreadyLogged[k] = struct{}{} // mark as logged
-		reqBy := r.Header.Get("X-Requested-By")
-		if reqBy == "" {
-			reqBy = strings.Split(r.RemoteAddr, ":")[0]
-		}
-		s.log.info("Serving file", "File", filename, "ReqBy", reqBy)
+	finish, ok := s.reserveDownload(r, id, filename)
+	if !ok {
+		s.goneResponse(w, r)
+		return
+	}
+	k := fmt.Sprint(id, ":", remoteIP(r))
+	if shouldLogReq(r.RemoteAddr) && r.Method == http.MethodGet && s.firstLog(k) {
+		s.log.info("Serving file", "File", filename, "ReqBy", requestedBy(r))
 	}
 	s.incActiveConn()       // this doesn't block
 	defer s.decActiveConn() // this blocks
 
-	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
-	http.ServeFile(w, r, filePath)
+	auditFile(r, filename)
+	w.Header().Set("Content-Disposition", contentDisposition(r, filename))
+	setDigestHeaders(w, s.digest(filePath, stat, func() (io.ReadCloser, error) { return os.Open(filePath) }))
+	setValidators(w, stat)
+	w, done := s.trackTransfer(w, r, filename, stat.Size())
+	defer done()
+	cw := &countingWriter{ResponseWriter: w}
+	http.ServeFile(cw, r, filePath)
+	finish(completedDownload(r, cw.status, cw.n, stat.Size()))
 }
 
-// stopHandler handles HTTP requests to shut down the server.
-// Only works when the server is stoppable and not actively serving files.
-//
-// Returns:
-// - Success (202 Accepted): When shutdown is initiated
-// - Error: When the server is not stoppable or is currently serving files
-func (s *Server) stopHandler(w http.ResponseWriter, r *http.Request) {
-	reqBy := r.Header.Get("X-Requested-By")
-	if reqBy == "" {
-		reqBy = strings.Split(r.RemoteAddr, ":")[0]
-	}
-
-	if shouldLogReq(r.RemoteAddr) {
-		s.log.info("Server shutdown request", "ReqBy", reqBy)
+// setFilePaths sets the file paths to be served by the server.
+func (s *Server) setFilePaths(filePaths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range filePaths {
+		id, _ := s.accessID(p)
+		s.FilePaths[id] = p
 	}
+}
 
+// AddFiles shares more files while the server is running, the already shared files are skipped,
+// returns the number of files added, the receivers subscribed to the events are told to fetch the index again.
+func (s *Server) AddFiles(filePaths ...string) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	c := s.ActiveDowns
-
-	if s.Stoppable {
-		if c == 0 {
-			s.StopCtxCancel()
-			msg := "Shutdown initiated, it may take maximum of 7 seconds to shutdown."
-			s.log.info(msg)
-			if err := s.writeJSON(w, envelop{"status": msg}, http.StatusAccepted, nil); err != nil {
-				s.serverErrorResponse(w, r)
-			}
-			return
-		}
-
-		if s.notifyCh != nil {
-			s.notifyCh <- reqBy
-			close(s.notifyCh)
-			s.notifyCh = nil
+	var added int
+	for _, p := range filePaths {
+		id, shared := s.accessID(p)
+		if shared {
+			continue
 		}
-		s.notIdleResponse(w, r)
-		return
+		s.FilePaths[id] = p
+		s.removedPaths = slices.DeleteFunc(s.removedPaths, func(rp string) bool { return rp == p })
+		added++
+	}
+	if added > 0 {
+		s.log.info("Files were added to the share", "Count", added)
+		s.events.indexChanged()
 	}
-	s.notStoppableResponse(w, r)
+	return added
 }
 
-// setFilePaths sets the file paths to be served by the server.
-func (s *Server) setFilePaths(filePaths ...string) {
+// RemoveFiles stops sharing the files while the server is running, the downloads already
+// in progress are served till the end, returns the number of files removed.
+func (s *Server) RemoveFiles(filePaths ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed int
 	for _, p := range filePaths {
-		hash := crc32.ChecksumIEEE([]byte(p))
-		s.FilePaths[hash] = p
+		id, shared := s.accessID(p)
+		if !shared {
+			continue
+		}
+		delete(s.FilePaths, id)
+		s.removedPaths = append(s.removedPaths, p)
+		removed++
 	}
+	if removed > 0 {
+		s.log.info("Files were removed from the share", "Count", removed)
+		s.events.indexChanged()
+	}
+	return removed
+}
+
+// sharedFiles returns a snapshot of Server.FilePaths, safe to range over without holding Server.mu.
+func (s *Server) sharedFiles() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.FilePaths)
+}
+
+// filePath returns the path of the shared file identified by the access id.
+func (s *Server) filePath(accessID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.FilePaths[accessID]
+	return p, ok
 }
 
 func (s *Server) deleteTempFiles() {
 	s.log.info("Deleting temporary files")
-	for _, p := range s.FilePaths {
+	s.mu.Lock()
+	paths := slices.AppendSeq(slices.Clone(s.removedPaths), maps.Values(s.FilePaths))
+	s.mu.Unlock()
+	for _, p := range paths {
 		if strings.HasPrefix(p, os.TempDir()) {
 			_ = os.Remove(p)
 		}
@@ -368,6 +792,10 @@ func (s *Server) decActiveConn() {
 	s.mu.Lock()
 	s.ActiveDowns--
 	s.log.relayActiveDown(s.ActiveDowns, true)
+	if s.ActiveDowns == 0 && s.exhausted() && s.StopCtx.Err() == nil {
+		s.log.info("All files reached the download limit, shutting down")
+		s.StopCtxCancel()
+	}
 	s.mu.Unlock()
 }
 
@@ -379,17 +807,25 @@ func getConfig() config.Config {
 	return cfg
 }
 
-// not logging the request from the same machine
-func shouldLogReq(addr string) bool {
-	reqIp := strings.Split(addr, ":")[0]
-	ip, _ := network.GetOutboundIP()
-	return ip.String() != reqIp
+// firstLog reports whether the event keyed by k is logged for the first time, it is then marked as logged,
+// so repeated requests, e.g. the chunks of a download, are logged once.
+func (s *Server) firstLog(k string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.alreadyLogged[k]; ok {
+		return false
+	}
+	s.alreadyLogged[k] = struct{}{}
+	return true
 }
 
-func sortByNameAsc(fi []*domain.FileInfo) {
-	slices.SortFunc(fi, func(a, b *domain.FileInfo) int {
-		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
-	})
+// not logging the request from the same machine
+func shouldLogReq(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return !network.IsLocal(host)
 }
 
 func getTotalFileSize(fi []*domain.FileInfo) int64 {
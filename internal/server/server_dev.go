@@ -6,9 +6,15 @@ import (
 	"github.com/MuhamedUsman/letshare/internal/config"
 )
 
-func GetPort() int {
+func GetPort(secure bool) int {
 	if config.TestFlag {
+		if secure {
+			return TestHTTPSPort
+		}
 		return TestHTTPPort
 	}
+	if secure {
+		return DefaultTLSPort
+	}
 	return DefaultPort
 }
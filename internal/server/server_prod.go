@@ -2,6 +2,9 @@
 
 package server
 
-func GetPort() int {
+func GetPort(secure bool) int {
+	if secure {
+		return DefaultTLSPort
+	}
 	return DefaultPort
 }
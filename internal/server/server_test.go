@@ -0,0 +1,152 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestServer_AddRemoveFiles(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	require.NoError(t, os.WriteFile(a, []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("b"), 0o644))
+	s.setFilePaths(a)
+	h := s.routes()
+
+	serve := func(p string) int {
+		s.mu.Lock()
+		id, _ := s.accessID(p)
+		s.mu.Unlock()
+		r := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		r.Host = "localhost"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve(b))
+	assert.Equal(t, 1, s.AddFiles(a, b), "already shared files must be skipped")
+	assert.Equal(t, http.StatusOK, serve(b))
+
+	assert.Equal(t, 1, s.RemoveFiles(a))
+	assert.Equal(t, 0, s.RemoveFiles(a))
+	assert.Equal(t, http.StatusNotFound, serve(a))
+	assert.Equal(t, http.StatusOK, serve(b))
+}
+
+func TestServer_resume(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	p := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(p, []byte("hello, world"), 0o644))
+	s.setFilePaths(p)
+	s.mu.Lock()
+	id, _ := s.accessID(p)
+	s.mu.Unlock()
+	h := s.routes()
+
+	serve := func(ifRange string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		r.Host = "localhost"
+		r.Header.Set("Range", "bytes=7-")
+		r.Header.Set("If-Range", ifRange)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("")
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+
+	w = serve(etag)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "world", w.Body.String())
+
+	// replaced between the sessions, the whole file must be served, not spliced onto the old one
+	require.NoError(t, os.WriteFile(p, []byte("goodbye, world"), 0o644))
+	w = serve(etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "goodbye, world", w.Body.String())
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestServer_listen(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer taken.Close()
+	port := taken.Addr().(*net.TCPAddr).Port
+
+	s := New(config.ShareConfig{Port: port}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	assert.Equal(t, port, s.Port())
+
+	addrs := []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("127.0.0.2")}
+	listeners, err := s.listen(addrs)
+	if err != nil {
+		// 127.0.0.2 is not a loopback address everywhere, e.g. macOS
+		listeners, err = s.listen(addrs[:1])
+	}
+	require.NoError(t, err)
+	defer func() {
+		for _, ln := range listeners {
+			_ = ln.Close()
+		}
+	}()
+	fallback := listeners[0].Addr().(*net.TCPAddr).Port
+	assert.NotEqual(t, port, fallback, "the port in use must not be bound twice")
+	for _, ln := range listeners {
+		assert.Equal(t, fallback, ln.Addr().(*net.TCPAddr).Port, "every address must share the port")
+	}
+}
+
+func TestURLHost(t *testing.T) {
+	assert.Equal(t, "letshare.local", URLHost("letshare.local", DefaultPort, false))
+	assert.Equal(t, "letshare.local:443", URLHost("letshare.local", DefaultTLSPort, false))
+	assert.Equal(t, "192.168.1.7", URLHost("192.168.1.7", DefaultTLSPort, true))
+	assert.Equal(t, "192.168.1.7:8080", URLHost("192.168.1.7", 8080, true))
+	assert.Equal(t, "[fd00::7]", URLHost("fd00::7", DefaultPort, false))
+	assert.Equal(t, "[fd00::7]:41234", URLHost("fd00::7", 41234, false))
+}
+
+func TestServer_Scheme(t *testing.T) {
+	s := New(config.ShareConfig{HTTPS: true}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	assert.Equal(t, "https", s.Scheme())
+
+	s = New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	assert.Equal(t, "http", s.Scheme())
+}
+
+func TestServer_firstLog(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	var wg sync.WaitGroup
+	var logged atomic.Int32
+	for range 50 { // the requests of the downloads, the trees & the mount log concurrently
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.firstLog("report.pdf:198.51.100.7") {
+				logged.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), logged.Load())
+	assert.True(t, s.firstLog("report.pdf:198.51.100.8"))
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxSnippetSize caps the text shared as a single snippet, in bytes
+	MaxSnippetSize = 64 << 10 // 64KB
+	// snippetNameLen is the runes of the text's first line, the snippet is named after
+	snippetNameLen = 48
+)
+
+var (
+	ErrEmptySnippet    = errors.New("snippet is empty")
+	ErrSnippetTooLarge = errors.New("snippet is too large")
+)
+
+// snippet is a text shared alongside the files, kept in memory only.
+type snippet struct {
+	name, text string
+	addedAt    time.Time
+}
+
+// AddSnippet shares the text, e.g. a URL, a token or a paragraph, alongside the files, snippets are only kept in
+// memory & are gone once the server stops, returns the access id of the snippet, sharing the same text twice
+// returns the same id, the receivers subscribed to the events are told to fetch the index again.
+func (s *Server) AddSnippet(text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", ErrEmptySnippet
+	}
+	if len(text) > MaxSnippetSize {
+		return "", ErrSnippetTooLarge
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, shared := s.accessID(snippetIDPrefix + text)
+	if shared {
+		return id, nil
+	}
+	sn := &snippet{name: snippetName(text), text: text, addedAt: time.Now()}
+	s.snippets[id] = sn
+	s.log.info("Text snippet was added to the share", "Snippet", sn.name)
+	s.events.indexChanged()
+	return id, nil
+}
+
+// RemoveSnippet stops sharing the snippet, reports whether it was shared.
+func (s *Server) RemoveSnippet(accessID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snippets[accessID]; !ok {
+		return false
+	}
+	delete(s.snippets, accessID)
+	s.events.indexChanged()
+	return true
+}
+
+// sharedSnippets returns a snapshot of the shared snippets, safe to range over without holding Server.mu.
+func (s *Server) sharedSnippets() map[string]*snippet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.snippets)
+}
+
+// serveSnippet serves the text of the snippet, as a plain text file.
+//
+// Returns:
+//   - Success (200 OK): The text of the snippet
+//   - Error (410 Gone): If the share limits of the snippet are reached
+func (s *Server) serveSnippet(w http.ResponseWriter, r *http.Request, accessID string, sn *snippet) {
+	finish, ok := s.reserveDownload(r, accessID, sn.name)
+	if !ok {
+		s.goneResponse(w, r)
+		return
+	}
+	if shouldLogReq(r.RemoteAddr) && r.Method == http.MethodGet {
+		s.log.info("Serving snippet", "Snippet", sn.name, "ReqBy", requestedBy(r))
+	}
+	auditFile(r, sn.name)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"snippet-"+accessID+".txt\"")
+	cw := &countingWriter{ResponseWriter: w}
+	http.ServeContent(cw, r, "", sn.addedAt, strings.NewReader(sn.text))
+	finish(completedDownload(r, cw.status, cw.n, int64(len(sn.text))))
+}
+
+// snippetName names the snippet after the first non-blank line of its text.
+func snippetName(text string) string {
+	var name string
+	for line := range strings.Lines(text) {
+		if name = strings.Join(strings.Fields(line), " "); name != "" {
+			break
+		}
+	}
+	if utf8.RuneCountInString(name) > snippetNameLen {
+		name = string([]rune(name)[:snippetNameLen-1]) + "…"
+	}
+	return name
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_AddSnippet(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	h := s.routes()
+
+	_, err := s.AddSnippet(" \n\t")
+	assert.ErrorIs(t, err, ErrEmptySnippet)
+	_, err = s.AddSnippet(strings.Repeat("a", MaxSnippetSize+1))
+	assert.ErrorIs(t, err, ErrSnippetTooLarge)
+
+	text := "\n  wifi password:   hunter2  \nsecond line"
+	id, err := s.AddSnippet(text)
+	require.NoError(t, err)
+	again, err := s.AddSnippet(text)
+	require.NoError(t, err)
+	assert.Equal(t, id, again, "sharing the same text twice must return the same id")
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Host = "localhost"
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/")
+	require.Equal(t, http.StatusOK, w.Code)
+	var index domain.Index
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &index))
+	assert.Equal(t, domain.IndexVersion, index.Version)
+	require.Len(t, index.FileIndexes, 1)
+	fi := index.FileIndexes[0]
+	assert.Equal(t, domain.TypeSnippet, fi.Type)
+	assert.Equal(t, "wifi password: hunter2", fi.Name, "snippets are named after their first line")
+	assert.Equal(t, int64(len(text)), fi.Size)
+
+	assert.Equal(t, id, fi.AccessID)
+	w = serve("/" + id)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, text, w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusNotFound, serve("/"+id+"/child").Code, "snippets have no children")
+	assert.Empty(t, archiveURL(index.FileIndexes), "snippets are never archived")
+
+	assert.True(t, s.RemoveSnippet(id))
+	assert.False(t, s.RemoveSnippet(id))
+	assert.Equal(t, http.StatusNotFound, serve("/"+id).Code)
+}
+
+func TestSnippetName(t *testing.T) {
+	assert.Equal(t, "https://example.com", snippetName("\n\nhttps://example.com\n"))
+	long := snippetName(strings.Repeat("ü", 100))
+	assert.Equal(t, snippetNameLen, len([]rune(long)))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
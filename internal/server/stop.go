@@ -0,0 +1,184 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+// stopReqTimeout is how long a stop request waits for the host to decide, the requester long-polls for the decision.
+const stopReqTimeout = time.Minute
+
+// StopReq asks the host to shut down the server, on behalf of another user on the LAN, see Server.StopRequests.
+type StopReq struct {
+	// ReqBy is the X-Requested-By header value, or the IP for browsers
+	ReqBy string
+	// IP of the requester, its requests wait for the same decision
+	IP string
+	// ActiveDowns in progress when the request was made, they halt if the host agrees
+	ActiveDowns int
+	d           *stopDecision
+}
+
+// Allow shuts down the server, the requester is told the shutdown is initiated.
+func (r StopReq) Allow() {
+	r.d.decide(true)
+}
+
+// Deny keeps the server running, the requester is told the host declined.
+func (r StopReq) Deny() {
+	r.d.decide(false)
+}
+
+// Done is closed once the request is decided, by the host, or by the server if the host takes too long.
+func (r StopReq) Done() <-chan struct{} {
+	return r.d.done
+}
+
+// stopDecision is the host's decision on the stop requests of a single requester, it is never
+// remembered, the host is asked again on the next request, once the pending one is decided.
+type stopDecision struct {
+	once sync.Once
+	done chan struct{}
+	// allowed by the host, expired if the host did not decide in time
+	allowed, expired bool
+	forget           func() // removes the decision from Server.stopReqs
+}
+
+func (d *stopDecision) decide(allowed bool) {
+	d.once.Do(func() {
+		d.allowed = allowed
+		d.forget()
+		close(d.done)
+	})
+}
+
+// expire denies the requests, as the host did not decide in time.
+func (d *stopDecision) expire() {
+	d.once.Do(func() {
+		d.expired = true
+		d.forget()
+		close(d.done)
+	})
+}
+
+// StopRequests returns the channel the stop requests are sent on, the host must decide on every request received,
+// requests from trusted peers, or holders of the stop token, stop an idle server without asking, see Server.stopHandler.
+func (s *Server) StopRequests() <-chan StopReq {
+	return s.stopReqCh
+}
+
+// stopDecisionFor returns the pending decision on the requester's stop requests,
+// isNew reports whether the host is yet to be asked.
+func (s *Server) stopDecisionFor(ip string) (d *stopDecision, isNew bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.stopReqs[ip]; ok {
+		return d, false
+	}
+	d = &stopDecision{done: make(chan struct{})}
+	d.forget = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.stopReqs[ip] == d {
+			delete(s.stopReqs, ip)
+		}
+	}
+	s.stopReqs[ip] = d
+	return d, true
+}
+
+// isTrustedStopper reports whether the requester may stop the server without asking the host,
+// i.e. it is one of the trusted peers, or holds the stop token in the X-Stop-Token header.
+func (s *Server) isTrustedStopper(r *http.Request) bool {
+	if token := r.Header.Get("X-Stop-Token"); s.stopToken != "" && token != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.stopToken)) == 1 {
+			return true
+		}
+	}
+	ip, err := netip.ParseAddr(remoteIP(r))
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(s.trustedPeers, func(peer string) bool {
+		p, err := netip.ParseAddr(peer)
+		return err == nil && p.Unmap() == ip.Unmap()
+	})
+}
+
+// stopHandler handles HTTP requests to shut down the server, only if it is stoppable.
+// Trusted peers & holders of the stop token stop an idle server right away, every other request,
+// or one while files are being served, is queued for the host to decide, see Server.StopRequests,
+// the requester long-polls for the decision, for up to stopReqTimeout.
+//
+// Returns:
+//   - Success (202 Accepted): When shutdown is initiated
+//   - Error (403 Forbidden): When the server is not stoppable, or the host declined
+//   - Error (409 Conflict): When the host did not decide in time
+func (s *Server) stopHandler(w http.ResponseWriter, r *http.Request) {
+	reqBy, ip := requestedBy(r), remoteIP(r)
+	if shouldLogReq(r.RemoteAddr) {
+		s.log.info("Server shutdown request", "ReqBy", reqBy)
+	}
+
+	s.mu.Lock()
+	stoppable, activeDowns := s.Stoppable, s.ActiveDowns
+	s.mu.Unlock()
+	if !stoppable {
+		s.notStoppableResponse(w, r)
+		return
+	}
+	if activeDowns == 0 && s.isTrustedStopper(r) {
+		s.stopAcceptedResponse(w, r)
+		return
+	}
+
+	d, isNew := s.stopDecisionFor(ip)
+	if isNew {
+		req := StopReq{ReqBy: reqBy, IP: ip, ActiveDowns: activeDowns, d: d}
+		s.log.info("Waiting for your decision on the shutdown request", "ReqBy", reqBy)
+		select {
+		case s.stopReqCh <- req:
+		case <-r.Context().Done():
+			d.decide(false)
+		case <-s.StopCtx.Done():
+		}
+	}
+
+	t := time.NewTimer(stopReqTimeout)
+	defer t.Stop()
+	select {
+	case <-d.done:
+	case <-t.C:
+		d.expire() // the host is away, ask again next time
+	case <-r.Context().Done():
+		return // the requester gave up
+	case <-s.StopCtx.Done():
+		s.stopAcceptedResponse(w, r) // stopped meanwhile, by the host or another requester
+		return
+	}
+
+	if d.expired {
+		s.stopUndecidedResponse(w, r)
+		return
+	}
+	if !d.allowed {
+		s.log.info("Declined the shutdown request", "ReqBy", reqBy)
+		s.stopDeniedResponse(w, r)
+		return
+	}
+	s.stopAcceptedResponse(w, r)
+}
+
+// stopAcceptedResponse initiates the shutdown & tells the requester.
+func (s *Server) stopAcceptedResponse(w http.ResponseWriter, r *http.Request) {
+	s.StopCtxCancel()
+	msg := "Shutdown initiated, it may take maximum of 7 seconds to shutdown."
+	s.log.info(msg)
+	if err := s.writeJSON(w, envelop{"status": msg}, http.StatusAccepted, nil); err != nil {
+		s.serverErrorResponse(w, r)
+	}
+}
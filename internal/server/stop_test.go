@@ -0,0 +1,61 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStopHandler(t *testing.T) {
+	newServer := func(stoppable bool) *Server {
+		cfg := config.ShareConfig{StoppableInstance: stoppable, TrustedPeers: []string{"198.51.100.1"}, StopToken: "lab-token"}
+		s := New(cfg, make(chan Log, 10), make(chan int, 10))
+		t.Cleanup(s.StopCtxCancel)
+		return s
+	}
+	stop := func(s *Server, ip, token string) int {
+		r := httptest.NewRequest(http.MethodPost, "/stop", nil)
+		r.RemoteAddr = ip + ":50000"
+		if token != "" {
+			r.Header.Set("X-Stop-Token", token)
+		}
+		w := httptest.NewRecorder()
+		s.stopHandler(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, stop(newServer(false), "198.51.100.1", ""), "not stoppable, even by trusted peers")
+
+	s := newServer(true)
+	assert.Equal(t, http.StatusAccepted, stop(s, "198.51.100.1", ""))
+	assert.Error(t, s.StopCtx.Err(), "trusted peers stop the idle server without asking")
+
+	s = newServer(true)
+	assert.Equal(t, http.StatusAccepted, stop(s, "198.51.100.2", "lab-token"))
+	assert.Error(t, s.StopCtx.Err(), "holders of the stop token stop the idle server without asking")
+
+	// the host declines the first request & allows the second
+	s = newServer(true)
+	go func() {
+		(<-s.StopRequests()).Deny()
+		req := <-s.StopRequests()
+		assert.Equal(t, "198.51.100.2", req.ReqBy)
+		req.Allow()
+	}()
+	assert.Equal(t, http.StatusForbidden, stop(s, "198.51.100.2", "wrong-token"))
+	assert.NoError(t, s.StopCtx.Err())
+	assert.Equal(t, http.StatusAccepted, stop(s, "198.51.100.2", ""))
+	assert.Error(t, s.StopCtx.Err())
+
+	// the host is asked while files are being served, even by trusted peers
+	s = newServer(true)
+	s.ActiveDowns = 1
+	go func() {
+		req := <-s.StopRequests()
+		assert.Equal(t, 1, req.ActiveDowns)
+		req.Deny()
+	}()
+	assert.Equal(t, http.StatusForbidden, stop(s, "198.51.100.1", ""))
+}
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"github.com/MuhamedUsman/letshare/internal/domain"
 	"github.com/MuhamedUsman/letshare/internal/webui"
 	"github.com/dustin/go-humanize"
 	"html/template"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -17,13 +19,21 @@ var (
 func getTemplate() *template.Template {
 	once.Do(func() {
 		funcs := template.FuncMap{
+			"archiveID":     archiveID,
 			"fileType":      fileType,
+			"fileURL":       fileURL,
+			"hasThumbnail":  hasThumbnail,
 			"humanizeSize":  humanizeSize,
+			"isoTime":       isoTime,
+			"join":          strings.Join,
+			"previewKind":   previewKind,
+			"previewURL":    previewURL,
+			"thumbURL":      thumbURL,
 			"trimExtSuffix": trimExtSuffix,
 		}
 		t = template.Must(template.New("fileIndexes").
 			Funcs(funcs).
-			ParseFS(webui.Files, "index.tmpl.html"),
+			ParseFS(webui.Files, "*.tmpl.html"),
 		)
 	})
 	return t
@@ -64,3 +74,38 @@ func fileType(name string) (typ string) {
 func humanizeSize(size int64) string {
 	return humanize.Bytes(uint64(size))
 }
+
+func isoTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// previewKinds are the files browsers can show inline, [K: extension, V: kind], see previewKind
+var previewKinds = map[string]string{
+	".jpg": "image", ".jpeg": "image", ".png": "image", ".gif": "image", ".webp": "image",
+	".bmp": "image", ".svg": "image", ".avif": "image",
+	".mp4": "video", ".m4v": "video", ".webm": "video", ".mov": "video", ".ogv": "video",
+	".mp3": "audio", ".m4a": "audio", ".aac": "audio", ".wav": "audio", ".ogg": "audio",
+	".oga": "audio", ".opus": "audio", ".flac": "audio",
+	".txt": "text", ".md": "text", ".log": "text", ".csv": "text", ".json": "text", ".xml": "text",
+	".yaml": "text", ".yml": "text", ".toml": "text", ".ini": "text", ".conf": "text", ".env": "text",
+	".go": "text", ".mod": "text", ".py": "text", ".js": "text", ".ts": "text", ".java": "text",
+	".kt": "text", ".c": "text", ".h": "text", ".cpp": "text", ".hpp": "text", ".cs": "text",
+	".rs": "text", ".rb": "text", ".php": "text", ".swift": "text", ".sh": "text", ".ps1": "text",
+	".bat": "text", ".sql": "text", ".html": "text", ".css": "text", ".tmpl": "text",
+}
+
+// previewKind returns how the web UI previews the file, i.e. "image", "video", "audio" or "text",
+// empty if it can only be downloaded, audio & video are streamed through Range requests.
+func previewKind(name string) string {
+	return previewKinds[strings.ToLower(filepath.Ext(name))]
+}
+
+// previewURL returns the URL path the file is previewed at, see Server.previewHandler.
+func previewURL(fi *domain.FileInfo) string {
+	return "/preview" + fileURL(fi)
+}
+
+// thumbURL returns the URL path the thumbnail of the file is served at, see Server.thumbnailHandler.
+func thumbURL(fi *domain.FileInfo) string {
+	return "/thumb" + fileURL(fi)
+}
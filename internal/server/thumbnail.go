@@ -0,0 +1,325 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	// pure Go decoders of the formats thumbnails are generated for
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// thumbSize is the longer side of the thumbnails, in pixels
+	thumbSize = 320
+	// maxThumbSource is the largest image, in bytes, thumbnails are generated for
+	maxThumbSource = 64 << 20 // 64MB
+	// maxThumbPixels guards against decompression bombs, a few KB image claiming to be gigapixels
+	maxThumbPixels = 64 << 20
+	// maxThumbnailing caps the images decoded at once, decoding takes a lot of memory
+	maxThumbnailing = 2
+	// maxThumbCache is the number of thumbnails kept in memory, ~20KB each
+	maxThumbCache = 512
+)
+
+var (
+	errNoThumbnail = errors.New("no thumbnail for the file")
+	// thumbBackground fills the transparent parts of the images, it matches the file cards
+	thumbBackground = color.RGBA{R: 0x27, G: 0x28, B: 0x22, A: 0xff}
+	thumbExts       = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".tif", ".tiff"}
+)
+
+// thumbnails generates & caches the JPEG thumbnails of the shared images, a cached thumbnail is
+// only used while the image has the same size & modification time it was generated at.
+type thumbnails struct {
+	mu      sync.Mutex
+	entries map[string]*thumbEntry // [K: file path, V: entry]
+	sem     chan struct{}
+	// group makes the concurrent requests for the same thumbnail share a single generation
+	group singleflight.Group
+}
+
+type thumbEntry struct {
+	size    int64
+	modTime time.Time
+	data    []byte
+}
+
+func newThumbnails() *thumbnails {
+	return &thumbnails{
+		entries: make(map[string]*thumbEntry),
+		sem:     make(chan struct{}, maxThumbnailing),
+	}
+}
+
+// thumbnailHandler serves a downscaled JPEG preview of the shared image identified by the access id,
+// or of an image inside a shared directory, thumbnails are generated on the first request & cached.
+// Thumbnails are not downloads, they are neither logged nor counted against the share limits.
+//
+// Returns:
+//   - Success (200 OK): The JPEG thumbnail
+//   - Error (404 Not Found): If the file does not exist, is not an image, or cannot be decoded
+//   - Error (410 Gone): If the share limits of the file are reached
+func (s *Server) thumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	filePath, ok := s.filePath(id)
+	if !ok {
+		s.notFoundResponse(w, r)
+		return
+	}
+	if !s.available(id) {
+		s.goneResponse(w, r)
+		return
+	}
+
+	key := filePath
+	open := func() (io.ReadCloser, error) { return os.Open(filePath) }
+	var stat fs.FileInfo
+	var err error
+	if rel := strings.Trim(r.PathValue("path"), "/"); rel != "" {
+		// an image inside a shared directory, reached through os.Root, see Server.serveTreeHandler
+		if !fs.ValidPath(rel) {
+			s.notFoundResponse(w, r)
+			return
+		}
+		var root *os.Root
+		if root, err = os.OpenRoot(filePath); err == nil {
+			stat, err = root.Stat(rel)
+			root.Close()
+		}
+		key = filepath.Join(filePath, filepath.FromSlash(rel))
+		open = func() (io.ReadCloser, error) { return openInRoot(filePath, rel) }
+	} else {
+		stat, err = os.Stat(filePath)
+	}
+	if err != nil || stat.IsDir() || !hasThumbnail(stat.Name()) || stat.Size() > maxThumbSource {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	data, err := s.thumbnail(key, stat, open)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	// revalidated against Last-Modified, so a changed image gets a new thumbnail
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", stat.ModTime(), bytes.NewReader(data))
+}
+
+// thumbnail returns the cached thumbnail of the image at key, generating it
+// through open if it is not cached or the image changed since.
+func (s *Server) thumbnail(key string, stat fs.FileInfo, open func() (io.ReadCloser, error)) ([]byte, error) {
+	t := s.thumbnails
+	cached := func() []byte {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if e, ok := t.entries[key]; ok && e.size == stat.Size() && e.modTime.Equal(stat.ModTime()) {
+			return e.data
+		}
+		return nil
+	}
+	if data := cached(); data != nil {
+		return data, nil
+	}
+
+	v, err, _ := t.group.Do(key, func() (any, error) {
+		if data := cached(); data != nil { // generated while waiting
+			return data, nil
+		}
+		select {
+		case t.sem <- struct{}{}:
+			defer func() { <-t.sem }()
+		case <-s.StopCtx.Done():
+			return nil, s.StopCtx.Err()
+		}
+		data, err := generateThumbnail(open)
+		if err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if len(t.entries) >= maxThumbCache {
+			for k := range t.entries { // evict any, the map iteration order is random
+				delete(t.entries, k)
+				break
+			}
+		}
+		t.entries[key] = &thumbEntry{size: stat.Size(), modTime: stat.ModTime(), data: data}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// generateThumbnail decodes the image read through open, scales it down to fit thumbSize,
+// rotates it upright as per its EXIF orientation, if any, & encodes it as JPEG.
+func generateThumbnail(open func() (io.ReadCloser, error)) ([]byte, error) {
+	f, err := open()
+	if err != nil {
+		return nil, err
+	}
+	// the dimensions are checked before decoding, decoding allocates all the pixels
+	cfg, format, err := image.DecodeConfig(f)
+	f.Close()
+	if err != nil {
+		return nil, errors.Join(errNoThumbnail, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxThumbPixels {
+		return nil, errNoThumbnail
+	}
+
+	if f, err = open(); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// the EXIF header of JPEGs comes before the pixels, within the head of the file
+	head := make([]byte, 1<<16)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+	var orientation int
+	if format == "jpeg" {
+		orientation = exifOrientation(head)
+	}
+	src, _, err := image.Decode(io.MultiReader(bytes.NewReader(head), f))
+	if err != nil {
+		return nil, errors.Join(errNoThumbnail, err)
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > thumbSize || h > thumbSize { // never scale up
+		if w >= h {
+			w, h = thumbSize, max(1, h*thumbSize/w)
+		} else {
+			w, h = max(1, w*thumbSize/h), thumbSize
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(thumbBackground), image.Point{}, draw.Src)
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	out := new(bytes.Buffer)
+	if err = jpeg.Encode(out, orient(dst, orientation), &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// hasThumbnail reports if thumbnails are generated for the file, judging by its extension.
+func hasThumbnail(name string) bool {
+	return slices.Contains(thumbExts, strings.ToLower(filepath.Ext(name)))
+}
+
+// exifOrientation returns the EXIF orientation (1-8) of the JPEG in b,
+// 0 if b has no EXIF header or it cannot be parsed within b.
+func exifOrientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 { // SOI
+		return 0
+	}
+	b = b[2:]
+	for len(b) >= 4 && b[0] == 0xff {
+		marker, size := b[1], int(binary.BigEndian.Uint16(b[2:4]))
+		if size < 2 || len(b) < 2+size {
+			return 0
+		}
+		seg := b[4 : 2+size]
+		b = b[2+size:]
+		if marker == 0xda { // SOS, the pixels follow, the metadata is over
+			return 0
+		}
+		if marker != 0xe1 || len(seg) < 14 || string(seg[:6]) != "Exif\x00\x00" {
+			continue
+		}
+
+		tiff := seg[6:]
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 0
+		}
+		ifd := int(order.Uint32(tiff[4:8]))
+		if ifd+2 > len(tiff) {
+			return 0
+		}
+		n := int(order.Uint16(tiff[ifd:]))
+		for i := range n {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return 0
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 { // Orientation tag, a SHORT
+				if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+					return o
+				}
+				return 0
+			}
+		}
+		return 0
+	}
+	return 0
+}
+
+// orient transforms the image as per the EXIF orientation, so it is displayed upright.
+func orient(src *image.RGBA, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // rotated by 90°, the sides swap
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored horizontally, rotated 270° clockwise
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored horizontally, rotated 90° clockwise
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 270° clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
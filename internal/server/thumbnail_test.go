@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_ThumbnailHandler(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+	dir := t.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	img.Set(10, 10, color.NRGBA{R: 0xff, A: 0xff})
+	buf := new(bytes.Buffer)
+	require.NoError(t, png.Encode(buf, img))
+	photo, notes := filepath.Join(dir, "photo.png"), filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(photo, buf.Bytes(), 0o644))
+	require.NoError(t, os.WriteFile(notes, []byte("notes"), 0o644))
+	s.setFilePaths(photo, notes, dir)
+	h := s.routes()
+
+	serve := func(p, rel string) *httptest.ResponseRecorder {
+		id, _ := s.accessID(p)
+		r := httptest.NewRequest(http.MethodGet, "/thumb/"+id+rel, nil)
+		r.Host = "localhost"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(photo, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	thumb, err := jpeg.Decode(w.Body)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, thumbSize, thumbSize/2), thumb.Bounds(), "the aspect ratio must be kept")
+
+	w = serve(dir, "/photo.png")
+	assert.Equal(t, http.StatusOK, w.Code, "images inside shared directories have thumbnails as well")
+	assert.Len(t, s.thumbnails.entries, 1, "the same image must be cached once")
+
+	assert.Equal(t, http.StatusNotFound, serve(notes, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(dir, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(dir, "/..%2Fphoto.png").Code)
+}
+
+func TestExifOrientation(t *testing.T) {
+	exif := []byte{
+		0xff, 0xd8, // SOI
+		0xff, 0xe1, 0x00, 0x22, // APP1, 34 bytes
+		'E', 'x', 'i', 'f', 0, 0,
+		'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08, // big endian TIFF header, IFD0 at 8
+		0x00, 0x01, // 1 entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x00, 0x00, // Orientation: 6
+		0x00, 0x00, 0x00, 0x00, // no next IFD
+		0xff, 0xda, 0x00, 0x02, // SOS
+	}
+	assert.Equal(t, 6, exifOrientation(exif))
+	assert.Equal(t, 0, exifOrientation(exif[:20]), "truncated headers must be ignored")
+	assert.Equal(t, 0, exifOrientation([]byte("not a jpeg")))
+
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	src.SetRGBA(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	dst := orient(src, 6)
+	assert.Equal(t, image.Rect(0, 0, 2, 4), dst.Bounds(), "rotating by 90° swaps the sides")
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, dst.At(1, 0), "the top left corner ends up at the top right")
+}
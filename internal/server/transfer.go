@@ -0,0 +1,183 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// transferChunkSize is the bytes sent at once with sendfile, before the transfer & the speed limits are checked again.
+const transferChunkSize = 1 << 20
+
+var errKicked = errors.New("transfer was kicked by the host")
+
+// Transfer is a file being downloaded from the server, see Server.Transfers.
+type Transfer struct {
+	ID uint64
+	// ReqBy is the X-Requested-By header of the client, its IP for browsers, see requestedBy
+	ReqBy, IP string
+	File      string
+	// Sent is the bytes of the file the client has, a resumed download starts where it left off,
+	// Size is -1 for the archives, they are built on the fly, see Server.archiveHandler
+	Sent, Size int64
+	StartedAt  time.Time
+}
+
+// transfer is a Transfer in progress, Sent is updated as the response body is written.
+type transfer struct {
+	Transfer
+	sent   atomic.Int64
+	kicked atomic.Bool
+}
+
+// transfers are the downloads in progress, [K: Transfer.ID, V: transfer].
+type transfers struct {
+	mu     sync.Mutex
+	lastID uint64
+	active map[uint64]*transfer
+}
+
+func newTransfers() *transfers {
+	return &transfers{active: make(map[uint64]*transfer)}
+}
+
+// Transfers returns the downloads in progress, the oldest first.
+func (s *Server) Transfers() []Transfer {
+	t := s.transfers
+	t.mu.Lock()
+	active := slices.Collect(maps.Values(t.active))
+	t.mu.Unlock()
+	slices.SortFunc(active, func(a, b *transfer) int { return a.StartedAt.Compare(b.StartedAt) })
+	ts := make([]Transfer, len(active))
+	for i, tr := range active {
+		ts[i] = tr.Transfer
+		ts[i].Sent = tr.sent.Load()
+	}
+	return ts
+}
+
+// KickTransfer aborts the download in progress, the client may still download the file
+// again, or resume it, reports whether the transfer was in progress.
+func (s *Server) KickTransfer(id uint64) bool {
+	t := s.transfers
+	t.mu.Lock()
+	tr, ok := t.active[id]
+	t.mu.Unlock()
+	if !ok || tr.kicked.Swap(true) {
+		return false
+	}
+	s.log.info("Transfer was kicked", "File", tr.File, "ReqBy", tr.ReqBy)
+	return true
+}
+
+// trackTransfer tracks the download of the file as a Transfer, the response body must be written
+// through the returned writer, done must be called once the file is served.
+// HEAD requests transfer nothing, they are not tracked.
+func (s *Server) trackTransfer(w http.ResponseWriter, r *http.Request, file string, size int64) (tw http.ResponseWriter, done func()) {
+	if r.Method != http.MethodGet {
+		return w, func() {}
+	}
+	t := s.transfers
+	t.mu.Lock()
+	t.lastID++
+	tr := &transfer{Transfer: Transfer{
+		ID:        t.lastID,
+		ReqBy:     requestedBy(r),
+		IP:        remoteIP(r),
+		File:      file,
+		Size:      size,
+		StartedAt: time.Now(),
+	}}
+	t.active[tr.ID] = tr
+	t.mu.Unlock()
+	if offset, ok := resumeOffset(r); ok && offset < size {
+		tr.sent.Store(offset)
+	}
+	return &transferWriter{ResponseWriter: w, t: tr}, func() {
+		t.mu.Lock()
+		delete(t.active, tr.ID)
+		t.mu.Unlock()
+	}
+}
+
+// transferWriter counts the bytes sent for the transfer, & fails the writes once the transfer is kicked,
+// the connection is then closed, as the response is shorter than its Content-Length.
+type transferWriter struct {
+	http.ResponseWriter
+	t *transfer
+}
+
+func (tw *transferWriter) Write(p []byte) (int, error) {
+	if tw.t.kicked.Load() {
+		return 0, errKicked
+	}
+	n, err := tw.ResponseWriter.Write(p)
+	tw.t.sent.Add(int64(n))
+	return n, err
+}
+
+// ReadFrom keeps the sendfile optimization of the underlying http.ResponseWriter, the file is sent
+// in chunks of transferChunkSize, so the transfer still makes progress, & can be kicked in between.
+func (tw *transferWriter) ReadFrom(src io.Reader) (int64, error) {
+	rf, ok := tw.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		return io.Copy(struct{ io.Writer }{tw}, src)
+	}
+	return copyInChunks(src, func(chunk io.Reader) (int64, error) {
+		if tw.t.kicked.Load() {
+			return 0, errKicked
+		}
+		n, err := rf.ReadFrom(chunk)
+		tw.t.sent.Add(n)
+		return n, err
+	})
+}
+
+// copyInChunks copies src through copyChunk, transferChunkSize bytes at a time, so the writers can
+// act in between, while the chunks can still be sent with sendfile, it only takes a file, or a file
+// behind a single io.LimitedReader, e.g. the range http.ServeContent sends, so src is unwrapped.
+func copyInChunks(src io.Reader, copyChunk func(chunk io.Reader) (int64, error)) (int64, error) {
+	r, remain := src, int64(math.MaxInt64)
+	lr, limited := src.(*io.LimitedReader)
+	if limited {
+		r, remain = lr.R, lr.N
+	}
+	var sent int64
+	for remain > 0 {
+		chunk := min(remain, transferChunkSize)
+		n, err := copyChunk(&io.LimitedReader{R: r, N: chunk})
+		sent += n
+		remain -= n
+		if limited {
+			lr.N = remain
+		}
+		if err != nil || n < chunk { // n < chunk is the EOF of src
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func (tw *transferWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// resumeOffset returns the offset a resumed download (Range: bytes=N-) starts at,
+// reports false for any other range, or if there is none.
+func resumeOffset(r *http.Request) (int64, bool) {
+	spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes=")
+	start, end, _ := strings.Cut(spec, "-")
+	if !ok || end != "" { // multiple or bounded ranges are never the whole rest of the file
+		return 0, false
+	}
+	offset, err := strconv.ParseInt(start, 10, 64)
+	return offset, err == nil
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_TrackTransfer(t *testing.T) {
+	s := New(config.ShareConfig{}, make(chan Log, 10), make(chan int, 10))
+	defer s.StopCtxCancel()
+
+	r := httptest.NewRequest(http.MethodGet, "/1234", nil)
+	r.RemoteAddr = "198.51.100.7:5000"
+	r.Header.Set("X-Requested-By", "alice")
+	r.Header.Set("Range", "bytes=100-")
+	w, done := s.trackTransfer(httptest.NewRecorder(), r, "movie.mkv", 1000)
+	_, err := w.Write(make([]byte, 50))
+	require.NoError(t, err)
+
+	ts := s.Transfers()
+	require.Len(t, ts, 1)
+	assert.Equal(t, "alice", ts[0].ReqBy)
+	assert.Equal(t, "198.51.100.7", ts[0].IP)
+	assert.Equal(t, "movie.mkv", ts[0].File)
+	assert.Equal(t, int64(150), ts[0].Sent, "a resumed download starts where it left off")
+
+	assert.True(t, s.KickTransfer(ts[0].ID))
+	assert.False(t, s.KickTransfer(ts[0].ID), "a transfer is kicked once")
+	_, err = w.Write(make([]byte, 50))
+	assert.ErrorIs(t, err, errKicked)
+	done()
+	assert.Empty(t, s.Transfers())
+	assert.False(t, s.KickTransfer(ts[0].ID))
+
+	head := httptest.NewRequest(http.MethodHead, "/1234", nil)
+	_, done = s.trackTransfer(httptest.NewRecorder(), head, "movie.mkv", 1000)
+	defer done()
+	assert.Empty(t, s.Transfers(), "HEAD requests transfer nothing")
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// serveTreeHandler serves the directory trees shared natively, rel is the slash separated path
+// relative to the shared directory, all access goes through os.Root, so nothing outside
+// the shared directory can be reached, not even through symlinks.
+//
+// Returns:
+//   - Success (200 OK): The file, or the directory listing if rel is a directory
+//   - Error (404 Not Found): If rel does not exist or escapes the shared directory
+func (s *Server) serveTreeHandler(w http.ResponseWriter, r *http.Request, accessID string, dir, rel string) {
+	rel = strings.Trim(rel, "/")
+	if rel == "" {
+		rel = "."
+	}
+	if !fs.ValidPath(rel) {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		s.serverErrorResponse(w, r)
+		return
+	}
+	defer root.Close()
+
+	stat, err := root.Stat(rel)
+	if err != nil {
+		// escaping the root is reported as an error as well, it is just not there for the client
+		s.notFoundResponse(w, r)
+		return
+	}
+	if stat.IsDir() && isPreview(r) { // directories can't be previewed
+		s.notFoundResponse(w, r)
+		return
+	}
+	if stat.IsDir() {
+		s.indexDir(w, r, accessID, root, filepath.Base(dir), rel)
+		return
+	}
+
+	f, err := root.Open(rel)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+	defer f.Close()
+
+	k := fmt.Sprint(accessID, "/", rel, ":", remoteIP(r))
+	if shouldLogReq(r.RemoteAddr) && r.Method == http.MethodGet && s.firstLog(k) {
+		s.log.info("Serving file", "File", path.Join(filepath.Base(dir), rel), "ReqBy", requestedBy(r))
+	}
+	s.incActiveConn()       // this doesn't block
+	defer s.decActiveConn() // this blocks
+
+	auditFile(r, path.Join(filepath.Base(dir), rel))
+	w.Header().Set("Content-Disposition", contentDisposition(r, stat.Name()))
+	setDigestHeaders(w, s.digest(filepath.Join(dir, filepath.FromSlash(rel)), stat, func() (io.ReadCloser, error) {
+		return openInRoot(dir, rel)
+	}))
+	setValidators(w, stat)
+	w, done := s.trackTransfer(w, r, path.Join(filepath.Base(dir), rel), stat.Size())
+	defer done()
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+}
+
+// indexDir lists the directory rel inside the root, JSON clients may ask for
+// the whole subtree with the "recursive" query parameter, to download it at once.
+func (s *Server) indexDir(w http.ResponseWriter, r *http.Request, accessID string, root *os.Root, rootName, rel string) {
+	preferJSON := r.Header.Get("Accept") == "application/json"
+	q, err := parseIndexQuery(r.URL.Query(), perPage(preferJSON))
+	if err != nil {
+		s.badRequestResponse(w, r, err.Error())
+		return
+	}
+	recursive := r.URL.Query().Has("recursive")
+	fsInfos, err := listDir(root.FS(), accessID, rel, recursive)
+	if err != nil {
+		s.serverErrorResponse(w, r)
+		return
+	}
+	matched, page := queryIndex(fsInfos, q)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(matched)))
+
+	location := rootName
+	if rel != "." {
+		location = path.Join(rootName, rel)
+	}
+	if shouldLogReq(r.RemoteAddr) && !recursive {
+		s.log.info("Folder was browsed", "Folder", location, "ReqBy", requestedBy(r))
+	}
+
+	if preferJSON {
+		if err = s.writeJSON(w, envelop{"version": domain.IndexVersion, "fileIndexes": page}, http.StatusOK, nil); err != nil {
+			s.serverErrorResponse(w, r)
+		}
+		return
+	}
+
+	parent := "/"
+	if rel != "." {
+		parent = fileURL(&domain.FileInfo{AccessID: accessID, Path: path.Dir(rel), IsDir: true})
+	}
+	cfg := getConfig()
+	data := indexFileTemplateData{
+		HostUsername: cfg.Personal.Username,
+		TotalFiles:   len(fsInfos),
+		TotalSize:    getTotalFileSize(fsInfos),
+		Files:        page,
+		AllowUploads: cfg.Share.AllowUploads,
+		Location:     location,
+		ParentURL:    parent,
+		ExpiresAt:    s.expiresAt,
+	}
+	data.setQuery(q, matched)
+	if err = s.render(w, http.StatusOK, "fileIndexes", data); err != nil {
+		s.serverErrorResponse(w, r)
+	}
+}
+
+// listDir lists the entries of the directory rel in fsys, or all the entries
+// beneath it if recursive, entries that cannot be stat'ed are skipped.
+func listDir(fsys fs.FS, accessID string, rel string, recursive bool) ([]*domain.FileInfo, error) {
+	fsInfos := make([]*domain.FileInfo, 0)
+	add := func(p string, d fs.DirEntry) {
+		info, err := d.Info()
+		if err != nil {
+			return
+		}
+		fi := &domain.FileInfo{
+			Name:     d.Name(),
+			AccessID: accessID,
+			Path:     p,
+			IsDir:    d.IsDir(),
+			ModTime:  info.ModTime(),
+		}
+		if !fi.IsDir {
+			fi.Size = info.Size()
+		}
+		fsInfos = append(fsInfos, fi)
+	}
+
+	if !recursive {
+		entries, err := fs.ReadDir(fsys, rel)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			add(path.Join(rel, e.Name()), e)
+		}
+		return fsInfos, nil
+	}
+
+	err := fs.WalkDir(fsys, rel, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == rel {
+				return err
+			}
+			if errors.Is(err, fs.ErrPermission) {
+				return nil // skip what we can't read, instead of failing the whole tree
+			}
+			return err
+		}
+		if p != rel {
+			add(p, d)
+		}
+		return nil
+	})
+	return fsInfos, err
+}
+
+// fileURL returns the URL path the file is served at, each path segment is escaped.
+func fileURL(fi *domain.FileInfo) string {
+	u := "/" + fi.AccessID
+	if fi.Path == "" || fi.Path == "." {
+		if fi.IsDir {
+			u += "/"
+		}
+		return u
+	}
+	segments := strings.Split(fi.Path, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return u + "/" + strings.Join(segments, "/")
+}
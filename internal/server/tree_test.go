@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestListDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":         {Data: []byte("a")},
+		"src/b.go":      {Data: []byte("bb")},
+		"src/pkg/c.go":  {Data: []byte("ccc")},
+		"src/pkg/d.txt": {Data: []byte("dddd")},
+	}
+
+	fInfos, err := listDir(fsys, "k7", ".", false)
+	assert.NoError(t, err)
+	assert.Len(t, fInfos, 2, "only direct children must be listed")
+	paths := make(map[string]bool)
+	for _, fi := range fInfos {
+		assert.Equal(t, "k7", fi.AccessID)
+		paths[fi.Path] = fi.IsDir
+	}
+	assert.Equal(t, map[string]bool{"a.txt": false, "src": true}, paths)
+
+	fInfos, err = listDir(fsys, "k7", "src", true)
+	assert.NoError(t, err)
+	paths = make(map[string]bool)
+	for _, fi := range fInfos {
+		paths[fi.Path] = fi.IsDir
+	}
+	want := map[string]bool{"src/b.go": false, "src/pkg": true, "src/pkg/c.go": false, "src/pkg/d.txt": false}
+	assert.Equal(t, want, paths, "recursive listing must include the whole subtree, relative to the shared directory")
+}
+
+func TestFileURL(t *testing.T) {
+	assert.Equal(t, "/k7", fileURL(&domain.FileInfo{AccessID: "k7"}))
+	assert.Equal(t, "/k7/", fileURL(&domain.FileInfo{AccessID: "k7", IsDir: true}))
+	assert.Equal(t, "/k7/src/a%20b%3F%23/x.txt", fileURL(&domain.FileInfo{AccessID: "k7", Path: "src/a b?#/x.txt"}))
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/file"
+	"github.com/dustin/go-humanize"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// uploadHandler streams multipart file parts straight into the ReceiveConfig.DownloadFolder,
+// nothing is buffered in memory or temp files, so uploads up to ShareConfig.MaxUploadMB are accepted.
+// Progress of each file is relayed to the tui through tlog.progress, the file being received when the
+// upload fails is removed, the files received before it are kept.
+//
+// Returns:
+//   - Success (201 Created): JSON with uploaded file names, if requested as JSON
+//   - Success (303 See Other): Redirects browsers back to the index page
+//   - Error (400 Bad Request): When the multipart body is malformed, or the client aborts the upload
+//   - Error (403 Forbidden): When uploads are disabled
+//   - Error (413 Request Entity Too Large): When the upload exceeds ShareConfig.MaxUploadMB
+func (s *Server) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
+	if !cfg.Share.AllowUploads {
+		s.uploadsNotAllowedResponse(w, r)
+		return
+	}
+
+	if err := liftReadDeadline(w); err != nil {
+		s.serverErrorResponse(w, r)
+		return
+	}
+
+	if cfg.Share.MaxUploadMB > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.Share.MaxUploadMB)<<20)
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		s.badRequestResponse(w, r, "the request body must be multipart/form-data")
+		return
+	}
+
+	s.incActiveConn()       // this doesn't block
+	defer s.decActiveConn() // this blocks
+
+	reqBy := requestedBy(r)
+	uploaded := make([]string, 0)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			s.uploadErrorResponse(w, r, err, "malformed multipart body")
+			return
+		}
+		// skip regular form fields
+		if part.FileName() == "" {
+			_ = part.Close()
+			continue
+		}
+		name, err := s.receiveFile(part, cfg.Receive.DownloadFolder, reqBy, r.ContentLength)
+		_ = part.Close()
+		if err != nil {
+			s.log.info("Upload failed", "File", part.FileName(), "ReqBy", reqBy)
+			s.uploadErrorResponse(w, r, err, "")
+			return
+		}
+		uploaded = append(uploaded, name)
+		auditFile(r, uploaded...)
+	}
+
+	if len(uploaded) == 0 {
+		s.badRequestResponse(w, r, "no files were found in the request body")
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		if err = s.writeJSON(w, envelop{"uploaded": uploaded}, http.StatusCreated, nil); err != nil {
+			s.serverErrorResponse(w, r)
+		}
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// uploadErrorResponse responds to the failed upload, the client aborting it, or exceeding
+// ShareConfig.MaxUploadMB is on the client, message is sent for any other client error,
+// without a message these are the server's fault.
+func (s *Server) uploadErrorResponse(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		s.uploadTooLargeResponse(w, r)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, context.Canceled), r.Context().Err() != nil:
+		s.badRequestResponse(w, r, "the upload was interrupted")
+	case message != "":
+		s.badRequestResponse(w, r, message)
+	default:
+		s.serverErrorResponse(w, r)
+	}
+}
+
+// receiveFile writes the file part into dir, it never overwrites any existing file.
+// total is the size of the whole request body, -1 if unknown.
+//
+// Returns:
+//   - string: The name of the file it is saved with.
+//   - error: If the file cannot be created or the transfer is interrupted, the partial file is removed.
+func (s *Server) receiveFile(p *multipart.Part, dir, reqBy string, total int64) (string, error) {
+	name := filepath.Base(p.FileName())
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid filename %q", p.FileName())
+	}
+
+	f, err := createUploadFile(dir, name)
+	if err != nil {
+		return "", err
+	}
+	name = filepath.Base(f.Name())
+	logID := "upload:" + f.Name()
+	s.log.progress(logID, "Receiving file", "File", name, "ReqBy", reqBy)
+
+	pw := &progressWriter{
+		w: f,
+		report: func(n int64) {
+			progress := humanize.Bytes(uint64(n))
+			if total > 0 {
+				progress = fmt.Sprint(progress, " / ", humanize.Bytes(uint64(total)))
+			}
+			s.log.progress(logID, "Receiving file", "File", name, "ReqBy", reqBy, "Received", progress)
+		},
+	}
+	b := make([]byte, 1<<20) // 1 MiB buffer
+	_, err = io.CopyBuffer(pw, p, b)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name()) // do not leave partial files behind
+		return "", fmt.Errorf("receiving file %q: %w", name, err)
+	}
+	s.log.progress(logID, "File received", "File", name, "ReqBy", reqBy, "Size", humanize.Bytes(uint64(pw.n)))
+	return name, nil
+}
+
+// createUploadFile exclusively creates the file in dir, if a file with the same name
+// already exists, a unique name is generated for it.
+func createUploadFile(dir, name string) (*os.File, error) {
+	p := filepath.Join(dir, name)
+	for range 10 {
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			p = file.UniqueName(filepath.Join(dir, name))
+			continue
+		}
+		return f, err
+	}
+	return nil, fmt.Errorf("unable to create a unique file for %q", name)
+}
+
+// progressWriter reports the written bytes at most once every second.
+type progressWriter struct {
+	w      io.Writer
+	n      int64
+	at     time.Time
+	report func(n int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.n += int64(n)
+	if time.Since(pw.at) > time.Second {
+		pw.at = time.Now()
+		pw.report(pw.n)
+	}
+	return n, err
+}
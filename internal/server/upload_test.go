@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateUploadFile(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "report.pdf")
+	assert.NoError(t, os.WriteFile(existing, []byte("original"), 0o644))
+
+	f, err := createUploadFile(dir, "report.pdf")
+	assert.NoError(t, err, "creating upload file")
+	defer f.Close()
+
+	assert.NotEqual(t, existing, f.Name(), "existing file must not be reused")
+	assert.Equal(t, dir, filepath.Dir(f.Name()), "upload file must be created inside dir")
+	assert.Equal(t, ".pdf", filepath.Ext(f.Name()), "extension must be preserved")
+
+	b, err := os.ReadFile(existing)
+	assert.NoError(t, err)
+	assert.Equal(t, "original", string(b), "existing file must not be overwritten")
+}
+
+func TestServer_UploadHandler(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	cfg := config.Config{
+		Share:   config.ShareConfig{AllowUploads: true, MaxUploadMB: 1},
+		Receive: config.ReceiveConfig{DownloadFolder: dir},
+	}
+	require.NoError(t, config.Save(cfg))
+
+	s := New(cfg.Share, make(chan Log, 100), make(chan int, 10))
+	defer s.StopCtxCancel()
+
+	upload := func(name string, size int, truncate bool) int {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("files", name)
+		require.NoError(t, err)
+		_, err = fw.Write(bytes.Repeat([]byte("x"), size))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+		b := body.Bytes()
+		if truncate {
+			b = b[:len(b)/2] // the client went away mid-upload
+		}
+		r := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(b))
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set("Accept", "application/json")
+		r.RemoteAddr = "198.51.100.7:5000"
+		w := httptest.NewRecorder()
+		s.uploadHandler(w, r)
+		return w.Code
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	assert.Equal(t, http.StatusCreated, upload("notes.txt", 1024, false))
+	assert.True(t, exists("notes.txt"), "uploaded file must be saved")
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("video.mp4", 2<<20, false))
+	assert.False(t, exists("video.mp4"), "partial file must be removed once the size limit is exceeded")
+
+	assert.Equal(t, http.StatusBadRequest, upload("report.pdf", 64<<10, true))
+	assert.False(t, exists("report.pdf"), "partial file must be removed once the client aborts")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, strings.HasPrefix(e.Name(), "video") || strings.HasPrefix(e.Name(), "report"),
+			"no partial file must be left behind, found %q", e.Name())
+	}
+}
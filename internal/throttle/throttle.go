@@ -0,0 +1,119 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// chunkSize caps the bytes a single write waits for, so the throughput stays smooth,
+// instead of large buffers passing in bursts followed by long pauses.
+const chunkSize = 32 << 10
+
+// Limiter is a token bucket limiting the throughput of all the writers sharing it,
+// its rate can be changed at any time, even while writers are waiting on it.
+// A nil Limiter or a rate of 0 means unlimited.
+type Limiter struct {
+	mu sync.Mutex
+	// rate in bytes per second, also the burst size
+	rate int64
+	// tokens are the bytes allowed to pass right away, negative when writers are waiting
+	tokens float64
+	// last time the tokens were refilled
+	last time.Time
+}
+
+// NewLimiter creates a Limiter allowing rate bytes per second, 0 for unlimited.
+func NewLimiter(rate int64) *Limiter {
+	return &Limiter{rate: max(0, rate), last: time.Now()}
+}
+
+// SetRate changes the rate in bytes per second, 0 for unlimited.
+func (l *Limiter) SetRate(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = max(0, rate)
+	l.tokens = min(l.tokens, float64(l.rate))
+}
+
+// Rate returns the rate in bytes per second, 0 if unlimited.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// WaitN blocks until n bytes may pass the Limiter, or the ctx is done.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+	if l.rate == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens earned since the last refill, the caller must hold Limiter.mu.
+func (l *Limiter) refill(now time.Time) {
+	if l.rate == 0 {
+		l.tokens = 0 // nothing is owed, once limited again, start afresh
+	} else {
+		l.tokens = min(float64(l.rate), l.tokens+now.Sub(l.last).Seconds()*float64(l.rate))
+	}
+	l.last = now
+}
+
+type writer struct {
+	ctx      context.Context
+	w        io.Writer
+	limiters []*Limiter
+}
+
+// NewWriter returns a writer passing the writes to w, as fast as the slowest of the limiters allow,
+// writes are cut short with the ctx error, once the ctx is done.
+func NewWriter(ctx context.Context, w io.Writer, limiters ...*Limiter) io.Writer {
+	return &writer{ctx: ctx, w: w, limiters: limiters}
+}
+
+func (tw *writer) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p[:min(len(p), chunkSize)]
+		for _, l := range tw.limiters {
+			if err = l.WaitN(tw.ctx, len(chunk)); err != nil {
+				return n, err
+			}
+		}
+		var written int
+		written, err = tw.w.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[written:]
+	}
+	return n, nil
+}
@@ -0,0 +1,40 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	const rate = 256 << 10 // 256 KiB/s
+	l := NewLimiter(rate)
+	buf := new(bytes.Buffer)
+	w := NewWriter(t.Context(), buf, l, nil) // a nil limiter is unlimited
+
+	start := time.Now()
+	n, err := w.Write(make([]byte, rate/2))
+	elapsed := time.Since(start)
+	assert.NoError(t, err, "writing should not return an error")
+	assert.Equal(t, rate/2, n)
+	assert.Equal(t, rate/2, buf.Len())
+	assert.InDelta(t, 500*time.Millisecond, elapsed, float64(200*time.Millisecond), "took %s", elapsed)
+
+	t.Run("Test unlimited", func(t *testing.T) {
+		l.SetRate(0)
+		start = time.Now()
+		_, err = w.Write(make([]byte, 4*rate))
+		assert.NoError(t, err, "writing should not return an error")
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("Test cancel", func(t *testing.T) {
+		l.SetRate(1 << 10)
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		_, err = NewWriter(ctx, buf, l).Write(make([]byte, 64<<10))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
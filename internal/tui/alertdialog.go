@@ -5,6 +5,7 @@ import (
 	"github.com/charmbracelet/bubbles/timer"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"slices"
 	"time"
 )
 
@@ -15,34 +16,37 @@ const (
 	none alertCursor = iota - 1
 	negative
 	positive
+	neutral // shown between the others, only if neutralBtnTxt is set
 )
 
 type alertDialogMsg struct {
 	header, body string
 	// which btn to be active
 	positiveBtnTxt, negativeBtnTxt string
-	cursor                         alertCursor
+	// neutralBtnTxt is the optional third button, e.g. to allow just once
+	neutralBtnTxt string
+	cursor        alertCursor
 	// alertDuration is used to set the timer for the alert dialog
 	// it defaults to 5 seconds
 	// takes effect only if positiveBtnTxt and negativeBtnTxt are nil
-	alertDuration                       time.Duration
-	positiveFunc, negativeFunc, escFunc func() tea.Cmd
+	alertDuration                                    time.Duration
+	positiveFunc, negativeFunc, neutralFunc, escFunc func() tea.Cmd
 }
 
 type alertDialogModel struct {
 	// header and body of the dialog box
 	header, body string
 	// buttons text
-	positiveBtnTxt, negativeBtnTxt string
-	cursor                         alertCursor
-	timer                          timer.Model
+	positiveBtnTxt, negativeBtnTxt, neutralBtnTxt string
+	cursor                                        alertCursor
+	timer                                         timer.Model
 	// prevFocus remembers the previous focused child
 	// and releases it accordingly
 	prevFocus focusSpace
 	// active signals this model's view must be rendered
 	active, disableKeymap bool
 	// functions to all on appropriate buttons
-	positiveFunc, negativeFunc, escFunc func() tea.Cmd
+	positiveFunc, negativeFunc, neutralFunc, escFunc func() tea.Cmd
 }
 
 func initialAlertDialogModel() alertDialogModel {
@@ -77,24 +81,30 @@ func (m alertDialogModel) Update(msg tea.Msg) (alertDialogModel, tea.Cmd) {
 
 		case "enter":
 			var cmd tea.Cmd
-			if m.cursor == 1 && m.positiveFunc != nil {
+			if m.cursor == positive && m.positiveFunc != nil {
 				cmd = m.positiveFunc()
+			} else if m.cursor == neutral && m.neutralFunc != nil {
+				cmd = m.neutralFunc()
 			} else if m.negativeFunc != nil {
 				cmd = m.negativeFunc()
 			}
 			return m, tea.Batch(m.hide(), cmd)
 
 		case "tab":
-			m.cursor = (m.cursor + 1) % 2
+			btns := m.buttons()
+			m.cursor = btns[(slices.Index(btns, m.cursor)+1)%len(btns)]
 
 		case "shift+tab":
-			m.cursor = (m.cursor - 1 + 2) % 2
+			btns := m.buttons()
+			m.cursor = btns[(slices.Index(btns, m.cursor)-1+len(btns))%len(btns)]
 
 		case "left", "h":
-			m.cursor = 0
+			btns := m.buttons()
+			m.cursor = btns[max(0, slices.Index(btns, m.cursor)-1)]
 
 		case "right", "l":
-			m.cursor = 1
+			btns := m.buttons()
+			m.cursor = btns[min(len(btns)-1, slices.Index(btns, m.cursor)+1)]
 
 		case "esc": // works same as pressing negative btn
 			var cmd tea.Cmd
@@ -111,7 +121,9 @@ func (m alertDialogModel) Update(msg tea.Msg) (alertDialogModel, tea.Cmd) {
 		m.header, m.body = msg.header, msg.body
 		m.positiveBtnTxt = msg.positiveBtnTxt
 		m.negativeBtnTxt = msg.negativeBtnTxt
+		m.neutralBtnTxt = msg.neutralBtnTxt
 		m.positiveFunc, m.negativeFunc, m.escFunc = msg.positiveFunc, msg.negativeFunc, msg.escFunc
+		m.neutralFunc = msg.neutralFunc
 		m.cursor = msg.cursor
 		m.active = true
 		if currentFocus != alert { // in-case multiple alert dialogs become active
@@ -171,22 +183,23 @@ func (m alertDialogModel) View() string {
 	c := alertDialogContainerStyle.Width(m.getDialogWidth())
 	h := alertDialogHeaderStyle.Render(m.header)
 	b := alertDialogBodyStyle.Render(m.body)
-	negStyle := alertDialogBtnStyle  // inactive
-	posStyle := alertDialogBtnStyle. //active
-						Background(highlightColor).
-						Foreground(subduedHighlightColor).
-						Faint(true)
-
-	if m.cursor == 0 {
-		negStyle = posStyle
-		posStyle = alertDialogBtnStyle
-	}
+	activeStyle := alertDialogBtnStyle.
+		Background(highlightColor).
+		Foreground(subduedHighlightColor).
+		Faint(true)
 
 	var view string
 	if !m.isTimerAlert() {
-		negBtn := negStyle.Render(m.negativeBtnTxt)
-		posBtn := posStyle.Render(m.positiveBtnTxt)
-		btns := lipgloss.JoinHorizontal(lipgloss.Center, negBtn, posBtn)
+		btnTxt := map[alertCursor]string{negative: m.negativeBtnTxt, neutral: m.neutralBtnTxt, positive: m.positiveBtnTxt}
+		var rendered []string
+		for _, btn := range m.buttons() {
+			style := alertDialogBtnStyle // inactive
+			if btn == m.cursor {
+				style = activeStyle
+			}
+			rendered = append(rendered, style.Render(btnTxt[btn]))
+		}
+		btns := lipgloss.JoinHorizontal(lipgloss.Center, rendered...)
 		btns = lipgloss.PlaceHorizontal(c.GetWidth()-alertDialogBtnStyle.GetHorizontalPadding(), lipgloss.Right, btns)
 		view = lipgloss.JoinVertical(lipgloss.Left, h, b, btns)
 	} else {
@@ -220,11 +233,20 @@ func (m *alertDialogModel) updateKeymap(disable bool) {
 func (m *alertDialogModel) hide() tea.Cmd {
 	m.active = false
 	m.header, m.body = "", ""
-	m.positiveFunc, m.negativeFunc = nil, nil
+	m.positiveFunc, m.negativeFunc, m.neutralFunc = nil, nil, nil
+	m.neutralBtnTxt = ""
 	currentFocus = m.prevFocus
 	return msgToCmd(spaceFocusSwitchMsg{})
 }
 
+// buttons returns the buttons of the dialog, in the order they are shown.
+func (m alertDialogModel) buttons() []alertCursor {
+	if m.neutralBtnTxt == "" {
+		return []alertCursor{negative, positive}
+	}
+	return []alertCursor{negative, neutral, positive}
+}
+
 func (m alertDialogModel) isTimerAlert() bool {
 	return m.positiveBtnTxt == "" && m.negativeBtnTxt == ""
 }
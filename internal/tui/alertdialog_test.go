@@ -0,0 +1,52 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAlertDialogModel_neutralBtn(t *testing.T) {
+	defer func(f focusSpace) { currentFocus = f }(currentFocus)
+
+	var pressed string
+	press := func(btn string) func() tea.Cmd {
+		return func() tea.Cmd { pressed = btn; return nil }
+	}
+	show := func() alertDialogModel {
+		m, _ := initialAlertDialogModel().Update(alertDialogMsg{
+			header:         "ALLOW ACCESS?",
+			positiveBtnTxt: "ALWAYS ALLOW",
+			neutralBtnTxt:  "ALLOW ONCE",
+			negativeBtnTxt: "DENY",
+			cursor:         negative,
+			positiveFunc:   press("positive"),
+			neutralFunc:    press("neutral"),
+			negativeFunc:   press("negative"),
+		})
+		return m
+	}
+	keys := func(m alertDialogModel, keys ...tea.KeyMsg) {
+		for _, k := range keys {
+			m, _ = m.Update(k)
+		}
+	}
+	right := tea.KeyMsg{Type: tea.KeyRight}
+	left := tea.KeyMsg{Type: tea.KeyLeft}
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	keys(show(), right, enter)
+	assert.Equal(t, "neutral", pressed, "the neutral button is shown between the others")
+	keys(show(), right, right, right, enter)
+	assert.Equal(t, "positive", pressed)
+	keys(show(), right, right, left, enter)
+	assert.Equal(t, "neutral", pressed)
+	keys(show(), tab, tab, tab, enter)
+	assert.Equal(t, "negative", pressed, "tab wraps around")
+
+	m := show()
+	assert.Contains(t, m.View(), "ALLOW ONCE")
+	m, _ = m.Update(enter)
+	assert.Equal(t, []alertCursor{negative, positive}, m.buttons(), "the neutral button is gone once hidden")
+}
@@ -15,6 +15,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -93,6 +94,8 @@ type dirNavModel struct {
 	prevSelectedStack itemSelectionStack
 	// Toggle for help display and keymap disable
 	showHelp, disableKeymap bool
+	// sharing is set while any share is running, esc then goes back to the shares
+	sharing bool
 }
 
 func initialDirNavModel() dirNavModel {
@@ -120,6 +123,8 @@ func (m dirNavModel) capturesKeyEvent(msg tea.KeyMsg) bool {
 		return m.dirList.FilterState() == list.Unfiltered
 	case " ", "?":
 		return true
+	case "esc":
+		return m.sharing && m.dirList.FilterState() == list.Unfiltered
 	default:
 		return false
 	}
@@ -189,11 +194,19 @@ func (m dirNavModel) Update(msg tea.Msg) (dirNavModel, tea.Cmd) {
 				}
 			}
 
+		case "esc": // back to the running shares, without adding anything
+			if m.sharing && m.dirList.FilterState() == list.Unfiltered {
+				return m, msgToCmd(localChildSwitchMsg{child: send, focus: true})
+			}
+
 		case "?":
 			m.showHelp = !m.showHelp
 			m.updateDimensions()
 		}
 
+	case shareModeMsg:
+		m.sharing = msg.sharing
+
 	case dirEntryMsg:
 		if msg.action != noop {
 			m.curDirPath = m.getCurDirPath(msg.action)
@@ -245,7 +258,7 @@ func (m dirNavModel) View() string {
 	} else {
 		m.dirList.SetShowStatusBar(true)
 	}
-	ht := customDirListHelpTable(m.showHelp).Width(m.dirList.Width())
+	ht := customDirListHelpTable(m.showHelp, m.sharing).Width(m.dirList.Width())
 	tail := "..."
 	subW := m.dirList.Styles.TitleBar.GetHorizontalFrameSize() // subtract Width
 	subW += lipgloss.Width(tail)
@@ -350,7 +363,7 @@ func customDelegate() list.ItemDelegate {
 	return d
 }
 
-func customDirListHelpTable(show bool) *table.Table {
+func customDirListHelpTable(show, sharing bool) *table.Table {
 	baseStyle := lipgloss.NewStyle()
 	var rows [][]string
 	if !show {
@@ -365,6 +378,9 @@ func customDirListHelpTable(show bool) *table.Table {
 			{"esc", "exit filtering"},
 			{"?", "hide help"},
 		}
+		if sharing {
+			rows = slices.Insert(rows, len(rows)-1, []string{"esc", "back to the shares"})
+		}
 	}
 	return table.New().
 		Border(lipgloss.HiddenBorder()).
@@ -386,7 +402,7 @@ func customDirListHelpTable(show bool) *table.Table {
 func (m *dirNavModel) updateDimensions() {
 	// sub '1' height for some buggy behaviour of pagination when transitioning from filtering to normal list state,
 	// if the pagination is visible afterward, it adds '1' height to the list till the next update is called
-	helpHeight := lipgloss.Height(customDirListHelpTable(m.showHelp).String())
+	helpHeight := lipgloss.Height(customDirListHelpTable(m.showHelp, m.sharing).String())
 	h := termH - (mainContainerStyle.GetVerticalFrameSize() + smallContainerStyle.GetVerticalFrameSize() + helpHeight + 1)
 	w := smallContainerW() - (smallContainerStyle.GetHorizontalFrameSize())
 	m.dirList.SetSize(w, h)
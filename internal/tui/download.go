@@ -81,7 +81,10 @@ type fileDownload struct {
 	// used when deleting the file
 	filename string
 	// accessID is the file ID on the server
-	accessID uint32
+	accessID string
+	// path of the file inside the shared directory identified by accessID,
+	// empty if the accessID identifies the file itself
+	path string
 	// createdAt is the time when the download was initiated
 	// it is used to sort the downloads
 	createdAt, completedAt time.Time
@@ -173,10 +176,12 @@ func initialDownloadModel() downloadModel {
 		maxDownloads: cfg.Receive.ConcurrentDownloads,
 		activeDowns:  new(atomic.Int32),
 	}
+	c := client.Get()
+	c.SetRateLimit(cfg.Receive.RateLimit)
 	return downloadModel{
 		vp:            vp,
 		dm:            dm,
-		client:        client.Get(),
+		client:        c,
 		disableKeymap: true,
 	}
 }
@@ -318,6 +323,12 @@ func (m downloadModel) Update(msg tea.Msg) (downloadModel, tea.Cmd) {
 		m.tabIdx = int(downloading) // switch to downloading tab
 		return m, m.startDownloads(ids...)
 
+	case preferencesSavedMsg:
+		// the speed limit applies live, even to the downloads in progress
+		if cfg, err := config.Get(); err == nil {
+			m.client.SetRateLimit(cfg.Receive.RateLimit)
+		}
+
 	case client.ProgressMsg:
 		d := m.dm.downloads[msg.ID]
 		d.prog = msg.P
@@ -507,6 +518,12 @@ func (m downloadModel) constructProgressStatus(fd *fileDownload) string {
 	s := spaceReplacer.Replace(humanize.Bytes(uint64(fd.prog.S)))
 	percent := calculatePercent(fd.prog.D, fd.prog.T)
 	prog := fmt.Sprintf("%s/%s • %s/s • %s", d, t, s, percent)
+	// the file changed on the host since the download was paused, so it started over
+	var note string
+	if fd.prog.Restarted {
+		note = " • File changed, restarted"
+		prog += note
+	}
 
 	switch downloadState(m.tabIdx) {
 	case all:
@@ -514,7 +531,7 @@ func (m downloadModel) constructProgressStatus(fd *fileDownload) string {
 		case downloading:
 			return prog
 		case completed:
-			return fmt.Sprintf("%s • %s • Completed", t, fd.completedAt.Sub(fd.createdAt).Round(time.Second))
+			return fmt.Sprintf("%s • %s • Completed%s", t, fd.completedAt.Sub(fd.createdAt).Round(time.Second), note)
 		case paused:
 			return fmt.Sprintf("%s/%s • %s", d, t, "Paused")
 		case failed:
@@ -532,7 +549,7 @@ func (m downloadModel) constructProgressStatus(fd *fileDownload) string {
 		case all, completed, paused, failed, added, deleted: // noop
 		}
 	case completed:
-		return fmt.Sprintf("%s • %s", t, fd.completedAt.Sub(fd.createdAt).Round(time.Second))
+		return fmt.Sprintf("%s • %s%s", t, fd.completedAt.Sub(fd.createdAt).Round(time.Second), note)
 	case paused, failed:
 		return fmt.Sprintf("%s/%s • %s", d, t, percent)
 	default: // noop
@@ -643,6 +660,7 @@ func (m *downloadModel) addDownloads(instance string, ds []downloadSelection) {
 			name:      d.name,
 			instance:  instance,
 			accessID:  d.accessID,
+			path:      d.path,
 			createdAt: time.Now(),
 			state:     added,
 		}
@@ -674,9 +692,15 @@ func (m *downloadModel) startDownloads(ids ...int) tea.Cmd {
 			d.createdAt = time.Now()
 		}
 
-		name := filepath.Join(m.dm.downloadPath, d.name)
+		// name is slash separated, files from shared directories recreate their tree
+		name := filepath.Join(m.dm.downloadPath, filepath.FromSlash(d.name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+			d.mu.Unlock()
+			return msgToCmd(errMsg{errHeader: "UNKNOWN ERROR", errStr: unwrapErr(err).Error()})
+		}
 		dt, err := client.NewDownloadTracker(id, name, m.dm.progCh)
 		if err != nil {
+			d.mu.Unlock()
 			return msgToCmd(errMsg{errHeader: "UNKNOWN ERROR", errStr: unwrapErr(err).Error()})
 		}
 		d.DownloadTracker = dt
@@ -730,7 +754,7 @@ func (m downloadModel) deleteDownloads(ids ...int) tea.Cmd {
 				d.filename = d.Filename()
 				decrementIfPositive(m.dm.activeDowns)
 			}
-			_ = os.Remove(d.filename)
+			_ = client.RemoveDownload(d.filename)
 			d.state = deleted
 			d.DownloadTracker = nil // dereference the tracker
 			d.mu.Unlock()
@@ -797,7 +821,7 @@ func (m downloadModel) downloadFile(fd *fileDownload) tea.Cmd {
 			return nil
 		}
 
-		status, err := m.client.DownloadFile(fd.DownloadTracker, fd.instance, fd.accessID)
+		status, err := m.client.DownloadFile(fd.DownloadTracker, fd.instance, fd.accessID, fd.path)
 		em := errMsg{}
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -819,7 +843,14 @@ func (m downloadModel) downloadFile(fd *fileDownload) tea.Cmd {
 		switch status {
 		case http.StatusOK, http.StatusPartialContent:
 			if dtExists {
-				_ = fd.Close()
+				if err = fd.Close(); errors.Is(err, client.ErrChecksumMismatch) {
+					// the corrupted file is already removed, retrying downloads it afresh
+					em.errHeader = "DOWNLOAD CORRUPTED"
+					em.errStr = "Download failed, the file's checksum doesn't match the host's, it was removed, retry to download it again."
+					fd.state = failed
+					fd.DownloadTracker = nil // dereference the tracker
+					return downloadFailedMsg(em)
+				}
 				fd.filename = fd.Filename()
 			}
 
@@ -831,8 +862,20 @@ func (m downloadModel) downloadFile(fd *fileDownload) tea.Cmd {
 		case http.StatusRequestTimeout:
 			em.errStr = "Download failed, the server instance is not responding, it might be down."
 
+		case http.StatusUnauthorized:
+			em.errStr = "Download failed, the share secret is missing or no longer valid."
+
+		case http.StatusForbidden:
+			em.errStr = "Download failed, the host did not approve your access to the share."
+
+		case http.StatusGone:
+			em.errStr = "Download failed, the share has expired or the file reached its download limit."
+
 		case http.StatusNotFound:
-			em.errStr = fmt.Sprintf("Download Failed, file with access id %q not found on the server.", strconv.Itoa(int(fd.accessID)))
+			em.errStr = fmt.Sprintf("Download Failed, file with access id %q not found on the server.", fd.accessID)
+			if fd.path != "" {
+				em.errStr = fmt.Sprintf("Download Failed, file %q not found on the server.", fd.path)
+			}
 
 		default:
 			if err == nil { // is nil
@@ -874,12 +917,14 @@ func (m downloadModel) deletePartailDownloads() tea.Cmd {
 			}
 			d.mu.Unlock()
 		}
-		// then delete the partial downloads
-		e, _ := os.ReadDir(m.dm.downloadPath)
-		for _, f := range e {
-			if strings.HasSuffix(f.Name(), client.IncompleteDownloadKey) {
-				_ = os.Remove(filepath.Join(m.dm.downloadPath, f.Name()))
+		// then delete the partial downloads, at the paths they were written to, the files of the shared
+		// directories are written to the subdirectories, along with the validators to resume them
+		for _, d := range m.dm.downloads {
+			d.mu.Lock()
+			if strings.HasSuffix(d.filename, client.IncompleteDownloadKey) {
+				_ = client.RemoveDownload(d.filename)
 			}
+			d.mu.Unlock()
 		}
 		return nil
 	}
@@ -1,9 +1,15 @@
 package tui
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/bgtask"
 	"github.com/MuhamedUsman/letshare/internal/client"
+	"github.com/MuhamedUsman/letshare/internal/domain"
 	"github.com/MuhamedUsman/letshare/internal/tui/table"
+	"github.com/aymanbagabas/go-osc52/v2"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -11,7 +17,10 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/mattn/go-runewidth"
 	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -19,8 +28,33 @@ import (
 
 type fileIndex struct {
 	name, ext, size string
-	accessID        uint32
-	selection       bool
+	accessID        string
+	// path inside the shared directory, see domain.FileInfo.Path
+	path             string
+	isDir, selection bool
+	// snippet is set for the text snippets shared by the host, see domain.TypeSnippet
+	snippet bool
+}
+
+// filename returns the name of the file with its extension,
+// snippets are named after their text, so they are saved as snippet-<accessID>.txt
+func (f fileIndex) filename() string {
+	if f.snippet {
+		return "snippet-" + f.accessID + ".txt"
+	}
+	if f.isDir || f.ext == "---" || f.ext == "" {
+		return f.name
+	}
+	return fmt.Sprintf("%s.%s", f.name, f.ext)
+}
+
+// remoteDir is a directory being browsed inside a shared directory of the instance.
+type remoteDir struct {
+	accessID string
+	// path inside the shared directory, empty for the shared directory itself
+	path string
+	// location is displayed in the title, e.g. "build/src"
+	location string
 }
 
 type fileIndexes struct {
@@ -29,15 +63,27 @@ type fileIndexes struct {
 }
 
 type extReceiveModel struct {
-	client                                 *client.Client
-	instance, fetchFailedStatus            string
+	client                      *client.Client
+	instance, fetchFailedStatus string
+	// cwd is the directory being browsed, nil for the instance's shared files
+	cwd                                    *remoteDir
 	extFileIndexTable                      table.Model
-	filter                                 textinput.Model
+	filter, secretInput                    textinput.Model
 	titleStyle                             lipgloss.Style
 	filterState                            filterState
 	files                                  fileIndexes
 	showHelp, disableKeymap                bool
 	allSelected, isFetching, filterChanged bool
+	// promptingSecret is set, when the instance is protected with a share secret
+	promptingSecret, wrongSecret bool
+	// events pushed by the instance subscribedTo, nil until subscribed, see extReceiveModel.subscribeEvents
+	events       <-chan domain.Event
+	unsubscribe  context.CancelFunc
+	subscribedTo string
+	// staleIndex is set, when the host changed the shared files while they were being filtered
+	staleIndex bool
+	// query sorts the files on the host, see domain.IndexQuery
+	query domain.IndexQuery
 }
 
 func initialExtReceiveModel() extReceiveModel {
@@ -49,6 +95,7 @@ func initialExtReceiveModel() extReceiveModel {
 		client:            client.Get(),
 		extFileIndexTable: t,
 		filter:            newFilterInputModel(),
+		secretInput:       newSecretInputModel(),
 		titleStyle:        titleStyle,
 		disableKeymap:     true,
 	}
@@ -56,7 +103,7 @@ func initialExtReceiveModel() extReceiveModel {
 
 func (m extReceiveModel) capturesKeyEvent(msg tea.KeyMsg) bool {
 	// textInput model captures keys, so we need to handle it here
-	if m.filterState == filtering && msg.String() != "ctrl+c" {
+	if (m.filterState == filtering || m.promptingSecret) && msg.String() != "ctrl+c" {
 		return true
 	}
 	switch msg.String() {
@@ -65,8 +112,16 @@ func (m extReceiveModel) capturesKeyEvent(msg tea.KeyMsg) bool {
 			(m.isValidTableShortcut() && m.filterState != filtering && m.getSelectionCount() > 0)
 	case "up", "down", "?", "ctrl+a":
 		return true
-	case "/", "shift+up", "shift+down":
+	case "ctrl+r":
+		return m.instance != "" && !m.isFetching
+	case "o", "O":
+		return m.isValidTableShortcut() && !m.isFetching
+	case "y":
+		return m.isValidTableShortcut() && m.fileAtCursor().snippet
+	case "/", "shift+up", "shift+down", "right", "l":
 		return m.isValidTableShortcut()
+	case "left", "h":
+		return m.isValidTableShortcut() || m.cwd != nil
 	case "esc":
 		return m.filterState != unfiltered || m.getSelectionCount() > 0
 	default:
@@ -88,6 +143,9 @@ func (m extReceiveModel) Update(msg tea.Msg) (extReceiveModel, tea.Cmd) {
 		if m.disableKeymap {
 			return m, nil
 		}
+		if m.promptingSecret {
+			return m, m.handleSecretPrompt(msg)
+		}
 		switch msg.String() {
 
 		case "enter":
@@ -128,11 +186,63 @@ func (m extReceiveModel) Update(msg tea.Msg) (extReceiveModel, tea.Cmd) {
 			m.allSelected = !m.allSelected
 			m.selectAll(m.allSelected)
 
+		case "right", "l": // into the directory at cursor
+			if m.isValidTableShortcut() && m.filterState != filtering {
+				if f := m.fileAtCursor(); f.isDir {
+					location := f.name
+					if m.cwd != nil {
+						location = m.cwd.location + "/" + f.name
+					}
+					return m, m.confirmDiscardSelThen(openRemoteDirMsg{f.accessID, f.path, location})
+				}
+			}
+
+		case "left", "h": // out of the directory
+			if m.cwd != nil && m.filterState != filtering {
+				var parent openRemoteDirMsg
+				if m.cwd.path != "" {
+					parent.accessID = m.cwd.accessID
+					parent.path = path.Dir(m.cwd.path)
+					if parent.path == "." {
+						parent.path = ""
+					}
+					parent.location = path.Dir(m.cwd.location)
+				}
+				return m, m.confirmDiscardSelThen(parent)
+			}
+
 		case "ctrl+s":
 			if m.filterState != filtering && m.getSelectionCount() > 0 {
 				return m, m.confirmDownload()
 			}
 
+		case "ctrl+r": // refetch the files, the host may have added or removed some while sharing
+			if m.instance != "" && !m.isFetching && m.filterState != filtering {
+				m.isFetching = true
+				m.resetFilter()
+				m.extFileIndexTable.Focus()
+				return m, m.fetchFileIndexes()
+			}
+
+		case "o", "O": // sort the files on the host, by the next key or in the reverse order
+			if m.isValidTableShortcut() && !m.isFetching && m.filterState != filtering {
+				if msg.String() == "o" {
+					m.query.Sort = nextSortKey(m.query.Sort)
+				} else {
+					m.query.Desc = !m.query.Desc
+				}
+				m.isFetching = true
+				m.resetFilter()
+				return m, m.fetchFileIndexes()
+			}
+
+		case "y": // copy the text snippet at cursor
+			if m.isValidTableShortcut() {
+				if f := m.fileAtCursor(); f.snippet {
+					return m, m.copySnippet(f)
+				}
+			}
+
 		case "/":
 			if m.isValidTableShortcut() {
 				m.filterState = filtering
@@ -151,6 +261,10 @@ func (m extReceiveModel) Update(msg tea.Msg) (extReceiveModel, tea.Cmd) {
 				m.resetFilter()
 				m.extFileIndexTable.Focus()
 				m.populateTable(m.files.indexes)
+				if m.staleIndex && !m.isFetching {
+					m.isFetching = true
+					return m, m.fetchFileIndexes()
+				}
 			} else if m.getSelectionCount() > 0 {
 				return m, m.confirmDiacardSel(home)
 			}
@@ -167,21 +281,79 @@ func (m extReceiveModel) Update(msg tea.Msg) (extReceiveModel, tea.Cmd) {
 		} else {
 			m.updateTitleStyleAsFocus(false)
 			m.resetFilter()
+			m.resetSecretPrompt()
 			m.extFileIndexTable.Blur()
 		}
 
 	case fetchFileIndexesMsg:
+		if string(msg) != m.subscribedTo {
+			m.stopEvents()
+		}
 		m.instance = string(msg)
+		m.cwd = nil
 		m.isFetching = true
+		m.resetSecretPrompt()
+		m.clearFiles()
+		return m, m.fetchFileIndexes()
+
+	case openRemoteDirMsg:
+		m.cwd = nil
+		if msg.location != "" {
+			m.cwd = &remoteDir{accessID: msg.accessID, path: msg.path, location: msg.location}
+		}
+		m.isFetching = true
+		m.allSelected = false
+		m.resetFilter()
 		m.clearFiles()
 		return m, m.fetchFileIndexes()
 
+	case shareSecretRequiredMsg:
+		m.isFetching = false
+		m.fetchFailedStatus = ""
+		m.promptingSecret = true
+		m.wrongSecret = bool(msg)
+		m.clearFiles()
+		m.extFileIndexTable.Blur()
+		return m, m.secretInput.Focus()
+
 	case fileIndexesMsg:
 		m.isFetching = false
-		m.files.indexes = msg
+		refreshed := len(m.files.indexes) > 0
+		m.files.indexes = keepSelections(m.files.indexes, msg)
 		m.fetchFailedStatus = ""
 		m.populateTable(m.files.indexes)
-		m.extFileIndexTable.GotoTop()
+		if !refreshed {
+			m.extFileIndexTable.GotoTop()
+		}
+		m.staleIndex = false
+		if m.subscribedTo != m.instance {
+			m.subscribedTo = m.instance
+			return m, tea.Batch(m.subscribeEvents(m.instance), m.handleInfoTableUpdate(msg))
+		}
+
+	case hostEventsMsg:
+		if msg.events == nil { // not subscribed, the files are only refreshed on demand
+			if msg.instance == m.subscribedTo {
+				m.subscribedTo = ""
+			}
+			return m, nil
+		}
+		if msg.instance != m.subscribedTo { // we already left the instance
+			msg.cancel()
+			return m, nil
+		}
+		m.events, m.unsubscribe = msg.events, msg.cancel
+		return m, waitForHostEvent(m.events)
+
+	case hostEventMsg:
+		if msg.events != m.events { // from a feed we already left
+			return m, nil
+		}
+		if msg.closed {
+			m.stopEvents()
+			return m, nil
+		}
+		return m, tea.Batch(waitForHostEvent(m.events), m.handleHostEvent(msg.event))
 
 	case fetchFileFailedMsg:
 		m.isFetching = false
@@ -194,13 +366,20 @@ func (m extReceiveModel) Update(msg tea.Msg) (extReceiveModel, tea.Cmd) {
 		m.handleFiltering()
 	}
 
-	return m, tea.Batch(m.handleInfoTableUpdate(msg), m.handleFilterInputUpdate(msg))
+	return m, tea.Batch(
+		m.handleInfoTableUpdate(msg),
+		m.handleFilterInputUpdate(msg),
+		m.handleSecretInputUpdate(msg),
+	)
 }
 
 func (m extReceiveModel) View() string {
 	help := customExtReceiveTableHelp(m.showHelp)
 	help.Width(m.extFileIndexTable.Width() - 2)
 	title := "Extended Instance: " + m.instance
+	if m.cwd != nil {
+		title = fmt.Sprintf("%s: %s", m.instance, m.cwd.location)
+	}
 	tail := "…"
 	w := largeContainerW() - (lipgloss.Width(tail) + titleStyle.GetHorizontalPadding() + lipgloss.Width(tail))
 	title = runewidth.Truncate(title, w, tail)
@@ -209,6 +388,10 @@ func (m extReceiveModel) View() string {
 	status := m.getStatus()
 	status = extStatusBarStyle.Render(status)
 
+	if m.secretInput.Focused() {
+		c := extDirNavTableFilterContainerStyle.Width(m.secretInput.Width)
+		return lipgloss.JoinVertical(lipgloss.Center, c.Render(m.secretInput.View()), status, m.extFileIndexTable.View(), help.Render())
+	}
 	if m.filter.Focused() {
 		filter := m.filter.View()
 		c := extDirNavTableFilterContainerStyle.Width(m.filter.Width)
@@ -226,6 +409,7 @@ func (m *extReceiveModel) updateDimensions() {
 	w := largeContainerW() - largeContainerStyle.GetHorizontalFrameSize()
 	m.extFileIndexTable.SetWidth(w + 2)
 	m.filter.Width = (w * 60) / 100 // 60% of available width
+	m.secretInput.Width = m.filter.Width
 	helpHeight := lipgloss.Height(customExtReceiveTableHelp(m.showHelp).String())
 	statusBarHeight := extStatusBarStyle.GetHeight() + extStatusBarStyle.GetVerticalFrameSize()
 	titleHeight := m.titleStyle.GetHeight() + m.titleStyle.GetVerticalFrameSize()
@@ -259,6 +443,39 @@ func (m *extReceiveModel) handleFilterInputUpdate(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+func (m *extReceiveModel) handleSecretInputUpdate(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.secretInput, cmd = m.secretInput.Update(msg)
+	return cmd
+}
+
+// handleSecretPrompt handles keys while the share secret is being prompted,
+// on enter the secret is handed to the client & the files are fetched again.
+func (m *extReceiveModel) handleSecretPrompt(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		secret := m.secretInput.Value()
+		if secret == "" {
+			return nil
+		}
+		m.client.SetSecret(m.instance, secret)
+		return msgToCmd(fetchFileIndexesMsg(m.instance))
+	case "esc":
+		m.resetSecretPrompt()
+		m.fetchFailedStatus = "This share is protected, select the instance again to enter the secret…"
+		m.extFileIndexTable.Focus()
+		return nil
+	}
+	return m.handleSecretInputUpdate(msg)
+}
+
+func (m *extReceiveModel) resetSecretPrompt() {
+	m.secretInput.Reset()
+	m.secretInput.Blur()
+	m.promptingSecret = false
+	m.wrongSecret = false
+}
+
 func (m *extReceiveModel) populateTable(idx []fileIndex) {
 	// case of filtering && there is some input to filter against
 	if m.filterState != unfiltered && utf8.RuneCountInString(m.filter.Value()) > 0 {
@@ -285,6 +502,14 @@ func (m *extReceiveModel) populateTable(idx []fileIndex) {
 	m.extFileIndexTable.SetRows(rows)
 }
 
+func (m extReceiveModel) fileAtCursor() fileIndex {
+	sel := max(0, m.extFileIndexTable.Cursor())
+	if m.filterState != unfiltered {
+		sel = m.files.filtered[sel]
+	}
+	return m.files.indexes[sel]
+}
+
 func (m extReceiveModel) isValidTableShortcut() bool {
 	return currentFocus == extension && m.extFileIndexTable.Focused() && len(m.extFileIndexTable.Rows()) > 0
 }
@@ -347,9 +572,20 @@ func (m extReceiveModel) getStatus() string {
 	if m.isFetching {
 		return runewidth.Truncate(status, largeContainerW()-4, "…")
 	}
+	if m.promptingSecret {
+		status = "This share is protected, enter the secret to continue…"
+		if m.wrongSecret {
+			status = "Wrong secret, ask the host for the right one & retry…"
+		}
+		return runewidth.Truncate(status, largeContainerW()-4, "…")
+	}
 	if m.fetchFailedStatus != "" {
 		return runewidth.Truncate(m.fetchFailedStatus, largeContainerW()-4, "…")
 	}
+	if m.staleIndex && m.filterState != filtering {
+		status = "The host changed the shared files, clear the filter or hit “ctrl+r” to refresh…"
+		return runewidth.Truncate(status, largeContainerW()-4, "…")
+	}
 	// unfiltered
 	status = fmt.Sprintf("%d Total", len(m.files.indexes))
 	selectCount := m.getSelectionCount()
@@ -357,6 +593,7 @@ func (m extReceiveModel) getStatus() string {
 		status = fmt.Sprintf("%d Selected • %d Total", selectCount, len(m.files.indexes))
 	}
 	if utf8.RuneCountInString(m.filter.Value()) == 0 {
+		status += m.sortStatus()
 		return runewidth.Truncate(status, largeContainerW()-4, "…") // -4 for tail, extensionContainer & statusBar frame size
 	}
 	matches := "Nothing matched"
@@ -374,6 +611,26 @@ func (m extReceiveModel) getStatus() string {
 	return runewidth.Truncate(status, largeContainerW()-4, "…") // -4 for tail, extensionContainer & statusBar frame size
 }
 
+// sortStatus describes the order of the files, empty for the default order, i.e. by name ascending.
+func (m extReceiveModel) sortStatus() string {
+	key := cmp.Or(m.query.Sort, domain.SortByName)
+	if key == domain.SortByName && !m.query.Desc {
+		return ""
+	}
+	arrow := "↑"
+	if m.query.Desc {
+		arrow = "↓"
+	}
+	return fmt.Sprintf(" • By %s %s", key, arrow)
+}
+
+// nextSortKey returns the sort key after k, cycling through name, size, type & date.
+func nextSortKey(k string) string {
+	keys := []string{domain.SortByName, domain.SortBySize, domain.SortByType, domain.SortByDate}
+	i := max(0, slices.Index(keys, k))
+	return keys[(i+1)%len(keys)]
+}
+
 func (m extReceiveModel) getSelectionCount() int {
 	count := 0
 	for _, content := range m.files.indexes {
@@ -384,23 +641,68 @@ func (m extReceiveModel) getSelectionCount() int {
 	return count
 }
 
-func (m extReceiveModel) selectedFilesAsDownloadMsg() []downloadSelection {
-	files := make([]downloadSelection, 0, len(m.files.indexes))
+func (m extReceiveModel) getSelectedFiles() []fileIndex {
+	sel := make([]fileIndex, 0, len(m.files.indexes))
 	for _, idx := range m.files.indexes {
-		if !idx.selection {
+		if idx.selection {
+			sel = append(sel, idx)
+		}
+	}
+	return sel
+}
+
+// selectedFilesAsDownloadMsg expands the selected files into downloadSelections,
+// the selected directories are listed recursively, so their tree is recreated in the download folder.
+func (m extReceiveModel) selectedFilesAsDownloadMsg(sel []fileIndex) tea.Msg {
+	files := make([]downloadSelection, 0, len(sel))
+	for _, idx := range sel {
+		if !idx.isDir {
+			ds := downloadSelection{
+				name:     idx.filename(),
+				size:     idx.size,
+				accessID: idx.accessID,
+				path:     idx.path,
+			}
+			files = append(files, ds)
 			continue
 		}
-		if idx.ext != "---" && idx.ext != "" {
-			idx.name = fmt.Sprintf("%s.%s", idx.name, idx.ext)
+
+		fInfos, status, err := m.client.IndexDir(m.instance, idx.accessID, idx.path, true, domain.IndexQuery{})
+		if err != nil {
+			return errMsg{errHeader: "UNKNOWN ERROR", errStr: unwrapErr(err).Error()}
 		}
-		ds := downloadSelection{
-			name:     idx.name,
-			size:     idx.size,
-			accessID: idx.accessID,
+		if status != http.StatusOK {
+			return errMsg{
+				errHeader: "LISTING FOLDER FAILED",
+				errStr:    fmt.Sprintf("Server returned status code %q while listing folder %q.", strconv.Itoa(status), idx.name),
+			}
 		}
-		files = append(files, ds)
+		for _, f := range fInfos {
+			if f.IsDir {
+				continue
+			}
+			rel := f.Path
+			if idx.path != "" {
+				rel = strings.TrimPrefix(rel, idx.path+"/")
+			}
+			name := idx.name + "/" + rel
+			// never trust the server with paths, it must not escape the download folder
+			if !filepath.IsLocal(filepath.FromSlash(name)) {
+				continue
+			}
+			ds := downloadSelection{
+				name:     name,
+				size:     humanize.Bytes(uint64(f.Size)),
+				accessID: f.AccessID,
+				path:     f.Path,
+			}
+			files = append(files, ds)
+		}
+	}
+	if len(files) == 0 {
+		return alertDialogMsg{header: "NOTHING TO DOWNLOAD", body: "The selected folders are empty."}
 	}
-	return files
+	return downloadSelectionsMsg{m.instance, files}
 }
 
 func (m *extReceiveModel) confirmDiacardSel(space extChild) tea.Cmd {
@@ -430,15 +732,36 @@ func (m *extReceiveModel) confirmDiacardSel(space extChild) tea.Cmd {
 	})
 }
 
+// confirmDiscardSelThen sends the msg, after confirming that the selections will be lost.
+func (m *extReceiveModel) confirmDiscardSelThen(msg tea.Msg) tea.Cmd {
+	if m.getSelectionCount() == 0 {
+		return msgToCmd(msg)
+	}
+	return msgToCmd(alertDialogMsg{
+		header:         "ARE YOU SURE?",
+		body:           "All the selections will be lost...",
+		cursor:         positive,
+		positiveBtnTxt: "YUP!",
+		negativeBtnTxt: "NOPE",
+		positiveFunc:   func() tea.Cmd { return msgToCmd(msg) },
+	})
+}
+
 func (m *extReceiveModel) confirmDownload() tea.Cmd {
-	sd := m.selectedFilesAsDownloadMsg() // selected downloads
 	selBtn := positive
 	header := "PROCEED?"
-	body := fmt.Sprintf(`Selected “%d file/s” will be downloaded as per preferences. To change preferences, press “esc” & “ctrl+p”.`, len(sd))
+	body := fmt.Sprintf(`Selected “%d file/s” will be downloaded as per preferences. To change preferences, press “esc” & “ctrl+p”.`, m.getSelectionCount())
+	for _, idx := range m.files.indexes {
+		if idx.selection && idx.isDir {
+			body = fmt.Sprintf(`Selected “%d item/s” will be downloaded as per preferences, folders with all their contents. To change preferences, press “esc” & “ctrl+p”.`, m.getSelectionCount())
+			break
+		}
+	}
+	sel := m.getSelectedFiles() // copied, as the selections are reset before the download starts
 	positiveFunc := func() tea.Cmd {
 		return tea.Batch(
 			msgToCmd(resetExtFileIndexTableSelectionsMsg{}),
-			msgToCmd(downloadSelectionsMsg{m.instance, sd}),
+			func() tea.Msg { return m.selectedFilesAsDownloadMsg(sel) },
 			msgToCmd(extensionChildSwitchMsg{download, true}),
 		)
 	}
@@ -467,6 +790,12 @@ func customExtReceiveTableHelp(show bool) *lipTable.Table {
 			{"shift+↓/↑", "make/undo selection"},
 			{"ctrl+a", "select/deselect all"},
 			{"ctrl+s", "save selected files"},
+			{"→/l", "into folder"},
+			{"←/h", "out of folder"},
+			{"ctrl+r", "refresh files"},
+			{"o", "sort by name/size/type/date"},
+			{"O", "reverse the order"},
+			{"y", "copy text snippet"},
 			{"esc", "exit filtering"},
 			{"/", "filter"},
 			{"?", "hide help"},
@@ -488,6 +817,84 @@ func customExtReceiveTableHelp(show bool) *lipTable.Table {
 		}).Rows(rows...)
 }
 
+func newSecretInputModel() textinput.Model {
+	f := newFilterInputModel()
+	f.Placeholder = "Share Secret"
+	f.EchoMode = textinput.EchoPassword
+	f.EchoCharacter = '•'
+	f.CharLimit = 64
+	return f
+}
+
+// keepSelections carries the selections over to the refetched indexes, the files gone from the share are dropped.
+func keepSelections(prev, curr []fileIndex) []fileIndex {
+	type key struct {
+		accessID   string
+		path, name string
+	}
+	selected := make(map[key]struct{})
+	for _, f := range prev {
+		if f.selection {
+			selected[key{f.accessID, f.path, f.name}] = struct{}{}
+		}
+	}
+	for i, f := range curr {
+		_, curr[i].selection = selected[key{f.accessID, f.path, f.name}]
+	}
+	return curr
+}
+
+// subscribeEvents subscribes to the events of the instance, so the files are fetched again as the host
+// changes the share, if it fails, e.g. the instance is older & has no events, the files are refreshed on demand.
+func (m extReceiveModel) subscribeEvents(instance string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithCancel(bgtask.Get().ShutdownCtx())
+		events, status, err := m.client.Events(ctx, instance)
+		if err != nil || status != http.StatusOK {
+			cancel()
+			return hostEventsMsg{instance: instance}
+		}
+		return hostEventsMsg{instance: instance, events: events, cancel: cancel}
+	}
+}
+
+func waitForHostEvent(events <-chan domain.Event) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-events
+		return hostEventMsg{events: events, event: e, closed: !ok}
+	}
+}
+
+// stopEvents unsubscribes from the events of the instance, if subscribed.
+func (m *extReceiveModel) stopEvents() {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
+	m.events, m.unsubscribe, m.subscribedTo = nil, nil, ""
+}
+
+func (m *extReceiveModel) handleHostEvent(e domain.Event) tea.Cmd {
+	switch e.Type {
+	case domain.EventIndex:
+		if m.isFetching || m.promptingSecret {
+			return nil
+		}
+		if m.filterState != unfiltered {
+			// the filtered indices would go stale, so wait till the filter is cleared
+			m.staleIndex = true
+			return nil
+		}
+		m.isFetching = true
+		return m.fetchFileIndexes()
+	case domain.EventMessage:
+		return msgToCmd(alertDialogMsg{header: "MESSAGE FROM THE HOST", body: e.Message})
+	case domain.EventShutdown:
+		m.fetchFailedStatus = "The host stopped sharing, downloads in progress may be cut short…"
+		return msgToCmd(alertDialogMsg{header: "SHARE STOPPED!", body: e.Message})
+	}
+	return nil
+}
+
 func (m *extReceiveModel) clearFiles() {
 	m.files = fileIndexes{}
 	m.populateTable(m.files.indexes)
@@ -495,13 +902,42 @@ func (m *extReceiveModel) clearFiles() {
 
 func (m extReceiveModel) fetchFileIndexes() tea.Cmd {
 	return func() tea.Msg {
-		fInfos, status, err := m.client.IndexFiles(m.instance)
+		var fInfos []*domain.FileInfo
+		var status int
+		var err error
+		if m.cwd == nil {
+			fInfos, status, err = m.client.IndexFiles(m.instance, m.query)
+		} else {
+			fInfos, status, err = m.client.IndexDir(m.instance, m.cwd.accessID, m.cwd.path, false, m.query)
+		}
+		if errors.Is(err, client.ErrIndexVersion) {
+			return fetchFileFailedMsg{
+				status: "The host runs an incompatible version of letshare…",
+				errMsg: errMsg{
+					errHeader: "INCOMPATIBLE VERSION",
+					errStr:    "The host runs a different version of letshare, update it on both ends to share files.",
+				},
+			}
+		}
 		if err != nil {
 			return fetchFileFailedMsg{
 				status: "Fetching files failed, you may want to retry…",
 				errMsg: errMsg{errHeader: "UNKNOWN ERROR", errStr: unwrapErr(err).Error()},
 			}
 		}
+		if status == http.StatusUnauthorized {
+			// the secret is wrong, if we already had one for the instance
+			return shareSecretRequiredMsg(m.client.HasSecret(m.instance))
+		}
+		if status == http.StatusForbidden {
+			return fetchFileFailedMsg{
+				status: "The host denied you access to the share…",
+				errMsg: errMsg{
+					errHeader: "ACCESS DENIED!",
+					errStr:    "The host did not approve your access to the shared files.",
+				},
+			}
+		}
 		if status == http.StatusRequestTimeout {
 			return fetchFileFailedMsg{
 				status: "Fetching files failed, you may want to retry…",
@@ -529,6 +965,27 @@ func (m extReceiveModel) fetchFileIndexes() tea.Cmd {
 
 		indexes := make([]fileIndex, len(fInfos))
 		for i, f := range fInfos {
+			if f.IsDir {
+				indexes[i] = fileIndex{
+					name:     f.Name,
+					ext:      "dir",
+					size:     "---",
+					accessID: f.AccessID,
+					path:     f.Path,
+					isDir:    true,
+				}
+				continue
+			}
+			if f.Type == domain.TypeSnippet {
+				indexes[i] = fileIndex{
+					name:     f.Name,
+					ext:      "text",
+					accessID: f.AccessID,
+					size:     humanize.Bytes(uint64(f.Size)),
+					snippet:  true,
+				}
+				continue
+			}
 			ext := filepath.Ext(f.Name)
 			if ext == f.Name { // .gitignore or similar files
 				ext = ""
@@ -543,9 +1000,43 @@ func (m extReceiveModel) fetchFileIndexes() tea.Cmd {
 				name:     name,
 				ext:      ext,
 				accessID: f.AccessID,
+				path:     f.Path,
 				size:     humanize.Bytes(uint64(f.Size)),
 			}
 		}
 		return fileIndexesMsg(indexes)
 	}
 }
+
+// copySnippet fetches the text snippet & copies it to the clipboard through OSC52, so it works
+// over SSH as well, as long as the terminal supports it, the text is shown in case it does not.
+func (m extReceiveModel) copySnippet(f fileIndex) tea.Cmd {
+	return func() tea.Msg {
+		text, status, err := m.client.Snippet(m.instance, f.accessID)
+		if err != nil {
+			return errMsg{errHeader: "UNKNOWN ERROR", errStr: unwrapErr(err).Error()}
+		}
+		if status != http.StatusOK {
+			h := strings.ToUpper(http.StatusText(status))
+			if h == "" {
+				h = "UNKNOWN ERROR"
+			}
+			return errMsg{
+				errHeader: h,
+				errStr:    fmt.Sprintf("Server returned status code %q while fetching the text snippet.", strconv.Itoa(status)),
+			}
+		}
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		// stderr is the same terminal, without racing the renderer writing to stdout
+		_, _ = seq.WriteTo(os.Stderr)
+		return alertDialogMsg{
+			header: "COPIED TO CLIPBOARD",
+			body:   runewidth.Truncate(strings.Join(strings.Fields(text), " "), 280, "…"),
+		}
+	}
+}
@@ -66,6 +66,8 @@ type extDirNavModel struct {
 	dirContents                                                        dirContents
 	dirPath                                                            string
 	allSelected, filterChanged, focusOnExtend, showHelp, disableKeymap bool
+	// sharing is set while the share shown is running, the selections are then added to it, see shareModeMsg
+	sharing bool
 }
 
 func initialExtDirNavModel() extDirNavModel {
@@ -231,6 +233,9 @@ func (m extDirNavModel) Update(msg tea.Msg) (extDirNavModel, tea.Cmd) {
 		m.allSelected = false
 		m.selectAll(m.allSelected)
 
+	case shareModeMsg:
+		m.sharing = msg.adding
+
 	case spaceFocusSwitchMsg:
 		if currentFocus == extension {
 			m.updateTitleStyleAsFocus(true)
@@ -580,6 +585,11 @@ func (m *extDirNavModel) confirmSend() tea.Cmd {
 	header := "PROCEED?"
 	body := fmt.Sprintf(`Selected “%s%s%s” will be processed as per preferences. To change preferences, press “esc” & “ctrl+p”.`,
 		dirStr, space, fileStr)
+	if m.sharing {
+		header = "ADD TO SHARE?"
+		body = fmt.Sprintf(`Selected “%s%s%s” will be processed as per preferences & added to the running share, receivers see them once they refresh.`,
+			dirStr, space, fileStr)
+	}
 	positiveFunc := func() tea.Cmd {
 		cmd := msgToCmd(processSelectionsMsg{
 			parentPath: m.dirPath,
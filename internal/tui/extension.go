@@ -113,7 +113,7 @@ func (m extensionSpaceModel) Update(msg tea.Msg) (extensionSpaceModel, tea.Cmd)
 		}
 
 	case serverLogsTimeoutMsg:
-		if m.activeChild != extDirNav {
+		if msg.last && m.activeChild != extDirNav {
 			return m, msgToCmd(extensionChildSwitchMsg{child: extDirNav, focus: false})
 		}
 
@@ -1,20 +1,28 @@
 package tui
 
 import (
+	"cmp"
 	"fmt"
+	"github.com/MuhamedUsman/letshare/internal/audit"
 	"github.com/MuhamedUsman/letshare/internal/server"
 	"github.com/charmbracelet/bubbles/timer"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	lipTable "github.com/charmbracelet/lipgloss/table"
+	"github.com/dustin/go-humanize"
 	"github.com/mattn/go-runewidth"
 	"github.com/muesli/reflow/truncate"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type logHandler struct {
-	logs     []string
+	logs []string
+	// ids[i] is the server.Log ID of logs[i]
+	ids      []string
 	logCh    <-chan server.Log
 	portSize int
 }
@@ -22,14 +30,24 @@ type logHandler struct {
 func newLogHandler(logCh <-chan server.Log) *logHandler {
 	return &logHandler{
 		logs:     make([]string, 0),
+		ids:      make([]string, 0),
 		portSize: 10,
 		logCh:    logCh,
 	}
 }
 
-func (h *logHandler) appendLog(l string) {
+// appendLog prepends the log, if a log with the same non-empty id
+// is still in the port, it is replaced in place instead.
+func (h *logHandler) appendLog(id, l string) {
+	if id != "" {
+		if i := slices.Index(h.ids, id); i >= 0 {
+			h.logs[i] = l
+			return
+		}
+	}
 	copy(h.logs[1:], h.logs[:len(h.logs)-1])
-	h.logs[0] = l
+	copy(h.ids[1:], h.ids[:len(h.ids)-1])
+	h.logs[0], h.ids[0] = l, id
 }
 
 func (h *logHandler) setLogsLength(l int) {
@@ -40,22 +58,65 @@ func (h *logHandler) setLogsLength(l int) {
 	newLogs := make([]string, l)
 	copy(newLogs, h.logs)
 	h.logs = newLogs
+	newIds := make([]string, l)
+	copy(newIds, h.ids)
+	h.ids = newIds
+}
+
+const (
+	// maxTransferRows is the number of transfers listed at once, the list scrolls with the cursor
+	maxTransferRows = 5
+	// maxAuditEntries is the number of the latest audit log entries loaded into the viewer
+	maxAuditEntries = 1000
+)
+
+// transferSample is the bytes a transfer had sent at a poll, to measure its rate by the next one.
+type transferSample struct {
+	sent int64
+	at   time.Time
+	// rate in bytes per second, since the previous poll
+	rate int64
+}
+
+// shareLogs are the logs & transfers of a share, see extSendModel.shares
+type shareLogs struct {
+	escTimer     timer.Model
+	lh           *logHandler
+	activeDownCh <-chan int
+	activeCons   int
+	instance     string
+	// server is polled for its transfers every second, nil once it shuts down
+	server    *server.Server
+	transfers []server.Transfer
+	// samples of the transfers, [K: server.Transfer.ID, V: sample]
+	samples map[uint64]transferSample
+	// cursor is the index of the selected transfer, to be kicked
+	cursor int
+}
+
+func newShareLogs() *shareLogs {
+	return &shareLogs{lh: &logHandler{}} // avoiding nil pointers
 }
 
 // extSendModel is the model to read & view logs when server is running
 // such as who is connected, what files are being downloaded, etc.
 type extSendModel struct {
-	escTimer                timer.Model
-	lh                      *logHandler
-	activeDownCh            <-chan int
-	titleStyle              lipgloss.Style
-	activeCons              int
-	disableKeymap, showHelp bool
+	// shareLogs of the share shown in the local space, see activeShareMsg
+	*shareLogs
+	// shares are the logs of the running shares, [K: shareSession.id, V: logs]
+	shares map[int]*shareLogs
+	// audit log entries of all the shares, the latest first, shown instead of the logs, see showAudit
+	audit []audit.Entry
+	// auditOffset is the index of the first audit entry shown, the list scrolls with it
+	auditOffset                        int
+	titleStyle                         lipgloss.Style
+	disableKeymap, showHelp, showAudit bool
 }
 
 func initialExtSendModel() extSendModel {
 	return extSendModel{
-		lh:            &logHandler{}, // avoiding nil pointers
+		shareLogs:     newShareLogs(),
+		shares:        make(map[int]*shareLogs),
 		titleStyle:    titleStyle,
 		disableKeymap: true,
 	}
@@ -63,8 +124,12 @@ func initialExtSendModel() extSendModel {
 
 func (m extSendModel) capturesKeyEvent(msg tea.KeyMsg) bool {
 	switch msg.String() {
-	case "esc", "q":
+	case "esc", "q", "a":
 		return !m.disableKeymap
+	case "up", "down", "r":
+		return !m.disableKeymap && (len(m.transfers) > 0 || m.showAudit)
+	case "x":
+		return !m.disableKeymap && len(m.transfers) > 0 && !m.showAudit
 	default:
 		return false
 	}
@@ -82,47 +147,130 @@ func (m extSendModel) Update(msg tea.Msg) (extSendModel, tea.Cmd) {
 		}
 		switch msg.String() {
 		case "esc":
+			if m.showAudit {
+				m.showAudit = false
+				m.updateLogsDimesions()
+				return m, nil
+			}
 			return m, msgToCmd(extensionChildSwitchMsg{child: home, focus: true})
+		case "a":
+			m.showAudit = !m.showAudit
+			m.updateLogsDimesions()
+			if m.showAudit {
+				return m, m.loadAudit()
+			}
+		case "r":
+			if m.showAudit {
+				return m, m.loadAudit()
+			}
+		case "up":
+			if m.showAudit {
+				m.auditOffset = max(0, m.auditOffset-1)
+				break
+			}
+			m.cursor = max(0, m.cursor-1)
+		case "down":
+			if m.showAudit {
+				m.auditOffset = max(0, min(len(m.audit)-m.auditRows(), m.auditOffset+1))
+				break
+			}
+			m.cursor = min(len(m.transfers)-1, m.cursor+1)
+		case "x":
+			if m.cursor < len(m.transfers) && !m.showAudit {
+				return m, m.confirmKick(m.transfers[m.cursor])
+			}
 		case "?":
 			m.showHelp = !m.showHelp
+			m.updateLogsDimesions()
 		}
 
 	case tea.WindowSizeMsg:
 		m.updateLogsDimesions()
 
 	case handleExtSendCh:
-		m.lh = newLogHandler(msg.logCh)
-		m.activeDownCh = msg.activeDownCh
+		sl := &shareLogs{
+			lh:           newLogHandler(msg.logCh),
+			activeDownCh: msg.activeDownCh,
+			instance:     msg.instance,
+			server:       msg.server,
+		}
+		m.shares[msg.id], m.shareLogs = sl, sl
+		m.updateLogsDimesions()
+		return m, tea.Batch(m.trackLogs(msg.id), m.trackActiveDowns(msg.id), m.pollTransfers(msg.id), msgToCmd(extensionChildSwitchMsg{child: extSend}))
+
+	case auditLoadedMsg:
+		if msg.err != nil {
+			return m, msgToCmd(errMsg{errHeader: "AUDIT LOG UNAVAILABLE!", errStr: "Unable to read the audit log.", err: msg.err})
+		}
+		m.audit, m.auditOffset = msg.entries, 0
+
+	case activeShareMsg:
+		if sl, ok := m.shares[msg.id]; ok {
+			m.shareLogs = sl
+		} else {
+			m.shareLogs = newShareLogs() // being set up, nothing to show yet
+		}
 		m.updateLogsDimesions()
-		return m, tea.Batch(m.trackLogs(), m.trackActiveDowns(), msgToCmd(extensionChildSwitchMsg{child: extSend}))
+
+	case transfersTickMsg:
+		sl, ok := m.shares[msg.id]
+		if !ok || sl.server == nil || msg.server != sl.server {
+			return m, nil
+		}
+		if sl.refreshTransfers() && sl == m.shareLogs {
+			m.updateLogsDimesions()
+		}
+		return m, m.pollTransfers(msg.id)
 
 	case serverLogMsg:
-		s := m.parseLog(server.Log(msg))
-		m.lh.appendLog(s)
-		return m, m.trackLogs()
+		sl, ok := m.shares[msg.id]
+		if !ok {
+			return m, nil
+		}
+		s := m.parseLog(msg.log)
+		sl.lh.appendLog(msg.log.ID, s)
+		return m, m.trackLogs(msg.id)
 
 	case activeDownsMsg:
-		m.activeCons = int(msg)
-		return m, m.trackActiveDowns()
+		sl, ok := m.shares[msg.id]
+		if !ok {
+			return m, nil
+		}
+		sl.activeCons = msg.n
+		return m, m.trackActiveDowns(msg.id)
 
 	case instanceShutdownMsg:
-		m.escTimer = timer.NewWithInterval(2*time.Second, 100*time.Millisecond)
-		return m, m.escTimer.Init()
+		sl, ok := m.shares[msg.id]
+		if !ok {
+			return m, nil
+		}
+		sl.server, sl.transfers, sl.samples, sl.cursor = nil, nil, nil, 0
+		if sl == m.shareLogs {
+			m.updateLogsDimesions()
+		}
+		sl.escTimer = timer.NewWithInterval(2*time.Second, 100*time.Millisecond)
+		return m, sl.escTimer.Init()
 
 	case spaceFocusSwitchMsg:
 		m.updateTitleStyleAsFocus()
 
 	case timer.TickMsg:
-		if msg.ID == m.escTimer.ID() {
-			var cmd tea.Cmd
-			m.escTimer, cmd = m.escTimer.Update(msg)
-			return m, cmd
+		for _, sl := range m.shares {
+			if msg.ID == sl.escTimer.ID() {
+				var cmd tea.Cmd
+				sl.escTimer, cmd = sl.escTimer.Update(msg)
+				return m, cmd
+			}
 		}
 
 	case timer.TimeoutMsg:
-		if msg.ID == m.escTimer.ID() {
-			m.activeCons = 0
-			return m, msgToCmd(serverLogsTimeoutMsg{}) // extensionSpaceModel handles this & switch back to extDirNav
+		for id, sl := range m.shares {
+			if msg.ID == sl.escTimer.ID() {
+				delete(m.shares, id)
+				last := !slices.ContainsFunc(slices.Collect(maps.Values(m.shares)), func(sl *shareLogs) bool { return sl.server != nil })
+				// extensionSpaceModel handles this & switch back to extDirNav, if it's the last share
+				return m, msgToCmd(serverLogsTimeoutMsg{id, last})
+			}
 		}
 	}
 
@@ -134,19 +282,44 @@ func (m extSendModel) View() string {
 	statusBar := m.renderStatusBar()
 	logs := m.renderLogs()
 	help := customExtSendHelp(m.showHelp).Width(largeContainerW() - 2)
-	v := lipgloss.JoinVertical(lipgloss.Center, title, statusBar, logs, help.Render())
+	views := []string{title, statusBar}
+	if m.showAudit {
+		views = append(views, m.renderAudit(), help.Render())
+		v := lipgloss.JoinVertical(lipgloss.Center, views...)
+		return lipgloss.PlaceHorizontal(largeContainerW(), lipgloss.Center, v)
+	}
+	if len(m.transfers) > 0 {
+		views = append(views, m.renderTransfers())
+	}
+	views = append(views, logs, help.Render())
+	v := lipgloss.JoinVertical(lipgloss.Center, views...)
 	return lipgloss.PlaceHorizontal(largeContainerW(), lipgloss.Center, v)
 }
 
 func (m extSendModel) renderTitle() string {
 	title, tail := "Server Logs", "…"
+	if m.instance != "" {
+		title = fmt.Sprintf("Server Logs • %s", m.instance)
+	}
+	if m.showAudit {
+		title = "Audit Log • All Shares"
+	}
 	w := largeContainerW() - (lipgloss.Width(tail) + titleStyle.GetHorizontalPadding() + lipgloss.Width(tail))
 	title = runewidth.Truncate(title, w, tail)
 	return m.titleStyle.Render(title)
 }
 
 func (m extSendModel) renderStatusBar() string {
-	s := fmt.Sprintf("Active Download Connections: %d", m.activeCons)
+	s := fmt.Sprintf("Active Transfers: %d", m.activeCons)
+	if idleIn, ok := m.idleIn(); ok && idleIn > 0 {
+		s += fmt.Sprintf(" • Idle in ~%s", idleIn)
+	}
+	if m.showAudit {
+		s = fmt.Sprintf("Entries: %d", len(m.audit))
+		if p, err := audit.Path(); err == nil {
+			s += " • " + p
+		}
+	}
 	if m.escTimer.Running() {
 		s = fmt.Sprintf("Escaping in %.1f...", m.escTimer.Timeout.Seconds())
 	}
@@ -169,6 +342,164 @@ func (m extSendModel) renderLogs() string {
 	return lipgloss.NewStyle().Width(largeContainerW() - 2).Align(lipgloss.Center).Render(s)
 }
 
+// renderTransfers lists the transfers in progress, who is downloading what, how fast & for how long.
+func (m extSendModel) renderTransfers() string {
+	start := max(0, m.cursor-maxTransferRows+1)
+	end := min(len(m.transfers), start+maxTransferRows)
+	rows := make([][]string, 0, end-start)
+	for _, t := range m.transfers[start:end] {
+		progress := humanize.Bytes(uint64(t.Sent))
+		if t.Size > 0 {
+			progress = fmt.Sprintf("%s / %s (%d%%)", progress, humanize.Bytes(uint64(t.Size)), t.Sent*100/t.Size)
+		}
+		speed, eta := "…", "…"
+		if rate := m.samples[t.ID].rate; rate > 0 {
+			speed = humanize.Bytes(uint64(rate)) + "/s"
+			if t.Size > 0 { // archives have no size to estimate with
+				eta = (time.Duration((t.Size-t.Sent)/rate) * time.Second).String()
+			}
+		}
+		rows = append(rows, []string{t.ReqBy, t.File, progress, speed, eta, time.Since(t.StartedAt).Truncate(time.Second).String()})
+	}
+
+	baseStyle := lipgloss.NewStyle().Padding(0, 1)
+	return lipTable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(subduedHighlightColor)).
+		BorderTop(false).BorderBottom(false).BorderLeft(false).BorderRight(false).BorderColumn(false).
+		Width(largeContainerW()-2).
+		Wrap(false).
+		Headers("CLIENT", "FILE", "PROGRESS", "SPEED", "ETA", "ELAPSED").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == lipTable.HeaderRow:
+				return baseStyle.Foreground(highlightColor).Faint(true)
+			case start+row == m.cursor && currentFocus == extension:
+				return baseStyle.Foreground(subduedHighlightColor).Background(highlightColor)
+			default:
+				return baseStyle.Foreground(midHighlightColor)
+			}
+		}).
+		Rows(rows...).
+		String()
+}
+
+// renderAudit lists the audit log entries, in place of the transfers & the logs, so they have the same height.
+func (m extSendModel) renderAudit() string {
+	start := min(m.auditOffset, len(m.audit))
+	end := min(len(m.audit), start+m.auditRows())
+	rows := make([][]string, 0, end-start)
+	for _, e := range m.audit[start:end] {
+		at := e.Time.Local().Format("Jan 02 15:04:05")
+		client := cmp.Or(e.ReqBy, e.IP)
+		rows = append(rows, []string{
+			at, client, e.IP, cmp.Or(e.File, e.Path), strconv.Itoa(e.Status),
+			humanize.Bytes(uint64(e.Bytes)), (time.Duration(e.DurationMs) * time.Millisecond).String(),
+		})
+	}
+	if len(rows) == 0 {
+		rows = append(rows, []string{"Nothing served yet", "", "", "", "", "", ""})
+	}
+
+	baseStyle := lipgloss.NewStyle().Padding(0, 1)
+	t := lipTable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(subduedHighlightColor)).
+		BorderTop(false).BorderBottom(false).BorderLeft(false).BorderRight(false).BorderColumn(false).
+		Width(largeContainerW()-2).
+		Wrap(false).
+		Headers("TIME", "CLIENT", "IP", "FILE", "STATUS", "SIZE", "TOOK").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == lipTable.HeaderRow {
+				return baseStyle.Foreground(highlightColor).Faint(true)
+			}
+			return baseStyle.Foreground(midHighlightColor)
+		}).
+		Rows(rows...).
+		String()
+	return lipgloss.NewStyle().Height(m.lh.portSize).Render(t)
+}
+
+// auditRows is the number of audit entries shown at once, the height of the logs, minus the table header.
+func (m extSendModel) auditRows() int {
+	return max(1, m.lh.portSize-2)
+}
+
+// loadAudit reads the latest entries of the audit log, of all the shares.
+func (m extSendModel) loadAudit() tea.Cmd {
+	return func() tea.Msg {
+		p, err := audit.Path()
+		if err != nil {
+			return auditLoadedMsg{err: err}
+		}
+		entries, err := audit.Tail(p, maxAuditEntries)
+		return auditLoadedMsg{entries, err}
+	}
+}
+
+// idleIn estimates how long until all the transfers complete, & the server can be shut down
+// without cutting anyone off, reports false if any of the transfers has yet to be measured, or has no size.
+func (m extSendModel) idleIn() (time.Duration, bool) {
+	var longest time.Duration
+	for _, t := range m.transfers {
+		rate := m.samples[t.ID].rate
+		if rate <= 0 || t.Size < 0 {
+			return 0, false
+		}
+		longest = max(longest, time.Duration((t.Size-t.Sent)/rate)*time.Second)
+	}
+	return longest, len(m.transfers) > 0
+}
+
+// pollTransfers refreshes the transfers of the server of the share every second, till it shuts down.
+func (m extSendModel) pollTransfers(id int) tea.Cmd {
+	sl, ok := m.shares[id]
+	if !ok || sl.server == nil {
+		return nil
+	}
+	srv := sl.server
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return transfersTickMsg{id, srv} })
+}
+
+// refreshTransfers fetches the transfers in progress & measures their rates since the previous poll,
+// reports whether the number of transfers changed, so the logs are to be resized.
+func (sl *shareLogs) refreshTransfers() bool {
+	now := time.Now()
+	rows := len(sl.transfers)
+	sl.transfers = sl.server.Transfers()
+	samples := make(map[uint64]transferSample, len(sl.transfers))
+	for _, t := range sl.transfers {
+		s := transferSample{sent: t.Sent, at: now}
+		if prev, ok := sl.samples[t.ID]; ok {
+			if d := now.Sub(prev.at).Seconds(); d > 0 {
+				s.rate = int64(float64(t.Sent-prev.sent) / d)
+			}
+		}
+		samples[t.ID] = s
+	}
+	sl.samples = samples
+	sl.cursor = max(0, min(sl.cursor, len(sl.transfers)-1))
+	return rows != len(sl.transfers)
+}
+
+// confirmKick asks the host before aborting the transfer, the client may download the file again.
+func (m extSendModel) confirmKick(t server.Transfer) tea.Cmd {
+	srv := m.server
+	return msgToCmd(alertDialogMsg{
+		header:         "KICK TRANSFER?",
+		body:           fmt.Sprintf("Stop sending %q to %q? They can still download it again.", t.File, t.ReqBy),
+		cursor:         negative,
+		positiveBtnTxt: "KICK",
+		negativeBtnTxt: "NOPE",
+		positiveFunc: func() tea.Cmd {
+			if srv != nil {
+				srv.KickTransfer(t.ID)
+			}
+			return nil
+		},
+	})
+}
+
 func (m *extSendModel) updateKeymap(b bool) {
 	m.disableKeymap = b
 }
@@ -178,6 +509,9 @@ func (m *extSendModel) updateLogsDimesions() {
 		lipgloss.Height(m.renderTitle()) +
 		lipgloss.Height(m.renderStatusBar()) +
 		lipgloss.Height(customExtSendHelp(m.showHelp).String())
+	if len(m.transfers) > 0 && !m.showAudit {
+		subL += lipgloss.Height(m.renderTransfers())
+	}
 	m.lh.setLogsLength(workableH() - subL)
 }
 
@@ -193,19 +527,21 @@ func (m *extSendModel) updateTitleStyleAsFocus() {
 	}
 }
 
-func (m extSendModel) trackLogs() tea.Cmd {
+func (m extSendModel) trackLogs(id int) tea.Cmd {
+	logCh := m.shares[id].lh.logCh
 	return func() tea.Msg {
-		for l := range m.lh.logCh {
-			return serverLogMsg(l)
+		for l := range logCh {
+			return serverLogMsg{id, l}
 		}
 		return nil
 	}
 }
 
-func (m extSendModel) trackActiveDowns() tea.Cmd {
+func (m extSendModel) trackActiveDowns(id int) tea.Cmd {
+	activeDownCh := m.shares[id].activeDownCh
 	return func() tea.Msg {
-		for n := range m.activeDownCh {
-			return activeDownsMsg(n)
+		for n := range activeDownCh {
+			return activeDownsMsg{id, n}
 		}
 		return nil
 	}
@@ -252,6 +588,11 @@ func customExtSendHelp(show bool) *lipTable.Table {
 		rows = [][]string{{"?", "help"}}
 	} else {
 		rows = [][]string{
+			{"↑/↓", "select transfer / scroll"},
+			{"x", "kick transfer"},
+			{"a", "toggle audit log"},
+			{"r", "reload audit log"},
+			{"esc", "back"},
 			{"?", "hide help"},
 		}
 	}
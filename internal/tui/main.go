@@ -225,8 +225,8 @@ func (m *MainModel) updateKeymapsByFocus() {
 func (m MainModel) getAppShutdownState() appShutdownState {
 	zipState := m.localSpace.processFiles.zipTracker.state
 	zipInProgress := zipState == processing || zipState == canceling
-	servingInProgress := m.localSpace.send.server != nil && m.localSpace.send.server.ActiveDowns > 0
-	serverIdle := m.localSpace.send.isServing && !servingInProgress
+	servingInProgress := m.localSpace.send.activeDowns() > 0
+	serverIdle := m.localSpace.send.isServing() && !servingInProgress
 	dirtyDowns := m.extensionSpace.download.hasPartialDownloads()
 
 	switch {
@@ -254,21 +254,21 @@ func (m MainModel) getMsgAndCmd(s appShutdownState) (string, tea.Cmd) {
 	case clean: // no-op
 	case servAndDown:
 		return "Are you sure, it will close all the active server connections, and will delete all the partial downloads.",
-			tea.Batch(m.localSpace.send.shutdownServer(true), m.extensionSpace.download.deletePartailDownloads())
+			tea.Batch(m.localSpace.send.shutdownServers(), m.extensionSpace.download.deletePartailDownloads())
 	case idleAndDown:
 		return "Are you sure, it will stop the idle server, and will delete all the partial downloads.",
-			tea.Batch(m.localSpace.send.shutdownServer(true), m.extensionSpace.download.deletePartailDownloads())
+			tea.Batch(m.localSpace.send.shutdownServers(), m.extensionSpace.download.deletePartailDownloads())
 	case zipAndDown:
 		return "Are you sure, it will stop the file zipping, and will delete all the partial downloads.",
 			tea.Batch(m.localSpace.processFiles.stopZipping(true), m.extensionSpace.download.deletePartailDownloads())
 	case idleServer:
-		return "", m.localSpace.send.shutdownServer(true)
+		return "", m.localSpace.send.shutdownServers()
 	case zippingFiles:
 		return "Do you want to stop zipping the files, progress will be lost.",
 			m.localSpace.processFiles.stopZipping(true)
 	case servingFiles:
 		return "The server instance is being shutdown forcefully, all active downloads will abruptly halt.",
-			m.localSpace.send.shutdownServer(true)
+			m.localSpace.send.shutdownServers()
 	case partialDowns:
 		return "All the partially downloaded files will be deleted from the disk. If you want them to complete them make sure to keep the app running.",
 			m.extensionSpace.download.deletePartailDownloads()
@@ -1,6 +1,9 @@
 package tui
 
 import (
+	"context"
+	"github.com/MuhamedUsman/letshare/internal/audit"
+	"github.com/MuhamedUsman/letshare/internal/domain"
 	"github.com/MuhamedUsman/letshare/internal/server"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -64,8 +67,11 @@ type processSelectionsMsg struct {
 }
 
 type downloadSelection struct {
+	// name is the slash separated path, the file is saved at, relative to the download folder
 	name, size string
-	accessID   uint32
+	accessID   string
+	// path of the file inside the shared directory, see domain.FileInfo.Path
+	path string
 }
 
 type downloadSelectionsMsg struct {
@@ -102,28 +108,91 @@ type zippingErrMsg error
 
 type zippingCanceledMsg struct{}
 
-type serverLogMsg server.Log
+// serverLogMsg is a log of the server of the share, see shareSession.id
+type serverLogMsg struct {
+	id  int
+	log server.Log
+}
 
-type serverStartupErrMsg errMsg
+// serverStartupErrMsg signals the share failed to start, see shareSession.id
+type serverStartupErrMsg struct {
+	id  int
+	err errMsg
+}
 
 type rerenderPreferencesMsg struct{}
 
 type sendFilesMsg []string
 
-type instanceServingMsg struct{}
+// instanceServingMsg & the rest of the share messages carry the id of the share they are about, see shareSession.id
+type instanceServingMsg struct{ id int }
+
+type instanceShutdownMsg struct{ id int }
+
+// instanceStateMsg is the state of the instance the share wants to use, see requiredInstanceState
+type instanceStateMsg struct {
+	id    int
+	state requiredInstanceState
+}
+
+// stopReqMsg asks the host to shut down the server, on behalf of another user on the LAN
+type stopReqMsg struct {
+	id  int
+	req server.StopReq
+}
+
+// stopReqDecidedMsg signals the stop request being asked is decided, so the next one can be asked
+type stopReqDecidedMsg struct{ id int }
+
+// approvalReqMsg asks the host to allow a new client, when sharing with "ask before sharing"
+type approvalReqMsg struct {
+	id  int
+	req server.ApprovalReq
+}
+
+// approvalDecidedMsg signals the approval request being asked is decided, so the next one can be asked
+type approvalDecidedMsg struct{ id int }
+
+// shareCanceledMsg cancels the share before it is served, its processed files are deleted
+type shareCanceledMsg struct{ id int }
 
-type instanceShutdownMsg struct{}
+// activeShareMsg signals the share shown in the local space changed, so its logs are shown as well
+type activeShareMsg struct{ id int }
 
-type shutdownReqWhenNotIdleMsg string
+// shareModeMsg tells the file selection what the selected files are for, sharing is set while any share
+// is running, adding is set if they are added to the running share shown, instead of starting a new one
+type shareModeMsg struct{ sharing, adding bool }
 
 type handleExtSendCh struct {
+	id           int
+	instance     string
 	logCh        chan server.Log
 	activeDownCh <-chan int
+	// server whose transfers are shown, see extSendModel.refreshTransfers
+	server *server.Server
 }
 
-type activeDownsMsg int
+// transfersTickMsg polls the transfers of the server, stale ticks of a previous server are ignored.
+type transfersTickMsg struct {
+	id     int
+	server *server.Server
+}
+
+type activeDownsMsg struct {
+	id, n int
+}
 
-type serverLogsTimeoutMsg struct{}
+// auditLoadedMsg carries the latest entries of the audit log, the latest first, see extSendModel.loadAudit
+type auditLoadedMsg struct {
+	entries []audit.Entry
+	err     error
+}
+
+// serverLogsTimeoutMsg signals the logs of the share are gone, last is set if no other share is running
+type serverLogsTimeoutMsg struct {
+	id   int
+	last bool
+}
 
 type instanceAvailabilityMsg bool
 
@@ -131,6 +200,31 @@ type fetchFileIndexesMsg string // string hold the instance name
 
 type fileIndexesMsg []fileIndex
 
+// openRemoteDirMsg browses the directory inside the shared directory of the instance,
+// the zero value goes back to the instance's shared files.
+type openRemoteDirMsg struct {
+	accessID       string
+	path, location string
+}
+
+// shareSecretRequiredMsg is sent when the instance is protected with a share secret,
+// true if the secret we tried is wrong.
+type shareSecretRequiredMsg bool
+
+// hostEventsMsg carries the event feed of the instance, once subscribed, see client.Client.Events
+type hostEventsMsg struct {
+	instance string
+	events   <-chan domain.Event
+	cancel   context.CancelFunc
+}
+
+// hostEventMsg is an event pushed by the instance, closed is set once the feed ends
+type hostEventMsg struct {
+	events <-chan domain.Event
+	event  domain.Event
+	closed bool
+}
+
 type fetchFileFailedMsg struct {
 	status string
 	errMsg errMsg
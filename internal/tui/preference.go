@@ -3,6 +3,8 @@ package tui
 import (
 	"fmt"
 	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/MuhamedUsman/letshare/internal/network"
+	"github.com/MuhamedUsman/letshare/internal/server"
 	"github.com/MuhamedUsman/letshare/internal/tui/overlay"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -11,10 +13,14 @@ import (
 	lipTable "github.com/charmbracelet/lipgloss/table"
 	"github.com/mattn/go-runewidth"
 	"log/slog"
+	"math"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -52,22 +58,54 @@ const (
 	username preferenceKey = iota
 	instanceName
 	stoppableInstance
+	trustedPeers
+	stopToken
+	allowUploads
+	maxUploadSize
+	shareSecret
+	askBeforeSharing
+	allowedClients
+	blockedClients
+	serveHTTPS
+	shareExpiry
+	maxDownloads
+	serverRateLimit
+	clientRateLimit
+	networkInterfaces
+	serverPort
 	zipFiles
 	compression
 	sharedZipName
 	downloadFolder
 	concurrentDownloads
+	downloadRateLimit
 )
 
 var prefKeyNames = []string{
 	"USERNAME",
 	"INSTANCE NAME",
 	"STOPPABLE INSTANCE",
+	"TRUSTED PEERS",
+	"STOP TOKEN",
+	"ALLOW UPLOADS?",
+	"MAX UPLOAD SIZE",
+	"SHARE SECRET",
+	"ASK BEFORE SHARING?",
+	"ALLOWED CLIENTS",
+	"BLOCKED CLIENTS",
+	"SERVE OVER HTTPS?",
+	"SHARE EXPIRY",
+	"MAX DOWNLOADS",
+	"SERVER SPEED LIMIT",
+	"PER CLIENT SPEED LIMIT",
+	"NETWORK INTERFACES",
+	"SERVER PORT",
 	"ZIP FILES?",
 	"COMPRESSED ZIP?",
 	"SHARED ZIP NAME",
 	"DOWNLOAD FOLDER",
 	"CONCURRENT DOWNLOADS",
+	"DOWNLOAD SPEED LIMIT",
 }
 
 func (pk preferenceKey) string() string {
@@ -171,6 +209,9 @@ func (m preferenceModel) Update(msg tea.Msg) (preferenceModel, tea.Cmd) {
 				if preferenceKey(m.cursor) == instanceName {
 					s = strings.ReplaceAll(s, " ", "-") // ensure no spaces in instance name
 				}
+				if k := preferenceKey(m.cursor); slices.Contains([]preferenceKey{networkInterfaces, trustedPeers, allowedClients, blockedClients}, k) {
+					s = strings.Join(splitList(s), ", ")
+				}
 				m.preferenceQues[m.cursor].input = s
 				m.renderViewport()
 				m.resetInsertMode()
@@ -522,6 +563,36 @@ func (m *preferenceModel) resetToSavedState() {
 			m.preferenceQues[i].input = cfg.Share.InstanceName
 		case stoppableInstance:
 			m.preferenceQues[i].check = cfg.Share.StoppableInstance
+		case trustedPeers:
+			m.preferenceQues[i].input = strings.Join(cfg.Share.TrustedPeers, ", ")
+		case stopToken:
+			m.preferenceQues[i].input = cfg.Share.StopToken
+		case allowUploads:
+			m.preferenceQues[i].check = cfg.Share.AllowUploads
+		case maxUploadSize:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Share.MaxUploadMB)
+		case shareSecret:
+			m.preferenceQues[i].input = cfg.Share.Secret
+		case askBeforeSharing:
+			m.preferenceQues[i].check = cfg.Share.AskBeforeSharing
+		case allowedClients:
+			m.preferenceQues[i].input = strings.Join(cfg.Share.AllowList, ", ")
+		case blockedClients:
+			m.preferenceQues[i].input = strings.Join(cfg.Share.DenyList, ", ")
+		case serveHTTPS:
+			m.preferenceQues[i].check = cfg.Share.HTTPS
+		case shareExpiry:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Share.ExpiryMinutes)
+		case maxDownloads:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Share.MaxDownloads)
+		case serverRateLimit:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Share.RateLimit)
+		case clientRateLimit:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Share.ClientRateLimit)
+		case networkInterfaces:
+			m.preferenceQues[i].input = strings.Join(cfg.Share.Interfaces, ", ")
+		case serverPort:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Share.Port)
 		case zipFiles:
 			m.preferenceQues[i].check = cfg.Share.ZipFiles
 		case compression:
@@ -532,6 +603,8 @@ func (m *preferenceModel) resetToSavedState() {
 			m.preferenceQues[i].input = cfg.Receive.DownloadFolder
 		case concurrentDownloads:
 			m.preferenceQues[i].input = strconv.Itoa(cfg.Receive.ConcurrentDownloads)
+		case downloadRateLimit:
+			m.preferenceQues[i].input = strconv.Itoa(cfg.Receive.RateLimit)
 		}
 	}
 }
@@ -553,6 +626,36 @@ func (m preferenceModel) savePreferences(exit bool) tea.Cmd {
 			cfg.Share.InstanceName = q.input
 		case stoppableInstance:
 			cfg.Share.StoppableInstance = q.check
+		case trustedPeers:
+			cfg.Share.TrustedPeers = splitList(q.input)
+		case stopToken:
+			cfg.Share.StopToken = q.input
+		case allowUploads:
+			cfg.Share.AllowUploads = q.check
+		case maxUploadSize:
+			cfg.Share.MaxUploadMB, _ = strconv.Atoi(q.input)
+		case shareSecret:
+			cfg.Share.Secret = q.input
+		case askBeforeSharing:
+			cfg.Share.AskBeforeSharing = q.check
+		case allowedClients:
+			cfg.Share.AllowList = splitList(q.input)
+		case blockedClients:
+			cfg.Share.DenyList = splitList(q.input)
+		case serveHTTPS:
+			cfg.Share.HTTPS = q.check
+		case shareExpiry:
+			cfg.Share.ExpiryMinutes, _ = strconv.Atoi(q.input)
+		case maxDownloads:
+			cfg.Share.MaxDownloads, _ = strconv.Atoi(q.input)
+		case serverRateLimit:
+			cfg.Share.RateLimit, _ = strconv.Atoi(q.input)
+		case clientRateLimit:
+			cfg.Share.ClientRateLimit, _ = strconv.Atoi(q.input)
+		case networkInterfaces:
+			cfg.Share.Interfaces = splitList(q.input)
+		case serverPort:
+			cfg.Share.Port, _ = strconv.Atoi(q.input)
 		case zipFiles:
 			cfg.Share.ZipFiles = q.check
 		case compression:
@@ -563,6 +666,8 @@ func (m preferenceModel) savePreferences(exit bool) tea.Cmd {
 			cfg.Receive.DownloadFolder = q.input
 		case concurrentDownloads:
 			cfg.Receive.ConcurrentDownloads, _ = strconv.Atoi(q.input)
+		case downloadRateLimit:
+			cfg.Receive.RateLimit, _ = strconv.Atoi(q.input)
 		}
 	}
 	return func() tea.Msg {
@@ -591,6 +696,36 @@ func (m preferenceModel) isUnsavedState() bool {
 			unsaved = q.input != cfg.Share.InstanceName
 		case stoppableInstance:
 			unsaved = q.check != cfg.Share.StoppableInstance
+		case trustedPeers:
+			unsaved = q.input != strings.Join(cfg.Share.TrustedPeers, ", ")
+		case stopToken:
+			unsaved = q.input != cfg.Share.StopToken
+		case allowUploads:
+			unsaved = q.check != cfg.Share.AllowUploads
+		case maxUploadSize:
+			unsaved = q.input != strconv.Itoa(cfg.Share.MaxUploadMB)
+		case shareSecret:
+			unsaved = q.input != cfg.Share.Secret
+		case askBeforeSharing:
+			unsaved = q.check != cfg.Share.AskBeforeSharing
+		case allowedClients:
+			unsaved = q.input != strings.Join(cfg.Share.AllowList, ", ")
+		case blockedClients:
+			unsaved = q.input != strings.Join(cfg.Share.DenyList, ", ")
+		case serveHTTPS:
+			unsaved = q.check != cfg.Share.HTTPS
+		case shareExpiry:
+			unsaved = q.input != strconv.Itoa(cfg.Share.ExpiryMinutes)
+		case maxDownloads:
+			unsaved = q.input != strconv.Itoa(cfg.Share.MaxDownloads)
+		case serverRateLimit:
+			unsaved = q.input != strconv.Itoa(cfg.Share.RateLimit)
+		case clientRateLimit:
+			unsaved = q.input != strconv.Itoa(cfg.Share.ClientRateLimit)
+		case networkInterfaces:
+			unsaved = q.input != strings.Join(cfg.Share.Interfaces, ", ")
+		case serverPort:
+			unsaved = q.input != strconv.Itoa(cfg.Share.Port)
 		case zipFiles:
 			unsaved = q.check != cfg.Share.ZipFiles
 		case compression:
@@ -601,6 +736,8 @@ func (m preferenceModel) isUnsavedState() bool {
 			unsaved = q.input != cfg.Receive.DownloadFolder
 		case concurrentDownloads:
 			unsaved = q.input != strconv.Itoa(cfg.Receive.ConcurrentDownloads)
+		case downloadRateLimit:
+			unsaved = q.input != strconv.Itoa(cfg.Receive.RateLimit)
 		}
 	}
 	return unsaved
@@ -637,6 +774,52 @@ func (m preferenceModel) validateInput(in string) (bool, string) {
 	case instanceName:
 		return utf8.RuneCountInString(in) >= 3 && utf8.RuneCountInString(in) <= 16,
 			"Instance name must be 3-16 characters long."
+	case trustedPeers:
+		for _, peer := range splitList(in) {
+			if _, err := netip.ParseAddr(peer); err != nil {
+				return false, "Trusted peers must be comma separated IP addresses, e.g. “192.168.1.7, 192.168.1.9”, or empty to trust no one."
+			}
+		}
+		return true, ""
+	case allowedClients, blockedClients:
+		err := server.ValidateAccessRules(splitList(in))
+		return err == nil, "Clients must be comma separated subnets, e.g. “10.1.0.0/16”, IP addresses or usernames, or empty for none."
+	case stopToken:
+		n := utf8.RuneCountInString(in)
+		return n == 0 || (n >= 8 && n <= 64 && !strings.ContainsFunc(in, unicode.IsSpace)),
+			"Stop token must be 8-64 characters long without spaces, or empty for no token."
+	case shareSecret:
+		n := utf8.RuneCountInString(in)
+		return n == 0 || (n >= 4 && n <= 64 && strings.TrimSpace(in) == in),
+			"Share secret must be 4-64 characters long without leading/trailing spaces, or empty for no secret."
+	case shareExpiry:
+		n, err := strconv.Atoi(in)
+		return err == nil && n >= 0 && n <= config.MaxExpiryMinutes,
+			fmt.Sprintf("Share expiry must be a number of minutes between 0 and %d, 0 for no expiry.", config.MaxExpiryMinutes)
+	case maxUploadSize:
+		n, err := strconv.Atoi(in)
+		return err == nil && n >= 0 && n <= config.MaxUploadMB,
+			fmt.Sprintf("Max upload size must be a number of MB between 0 and %d, 0 for no limit.", config.MaxUploadMB)
+	case maxDownloads:
+		n, err := strconv.Atoi(in)
+		return err == nil && n >= 0 && n <= config.MaxDownloadsPerFile,
+			fmt.Sprintf("Max downloads must be a number between 0 and %d, 0 for unlimited downloads.", config.MaxDownloadsPerFile)
+	case serverRateLimit, clientRateLimit, downloadRateLimit:
+		n, err := strconv.Atoi(in)
+		return err == nil && n >= 0 && n <= config.MaxRateLimit,
+			fmt.Sprintf("Speed limit must be a number of KB/s between 0 and %d, 0 for no limit.", config.MaxRateLimit)
+	case networkInterfaces:
+		specs := splitList(in)
+		if len(specs) == 0 {
+			return true, ""
+		}
+		_, err := network.Select(specs)
+		return err == nil, "Network interfaces must be the comma separated names or addresses of interfaces that are up, " +
+			"or empty to pick one on its own."
+	case serverPort:
+		n, err := strconv.Atoi(in)
+		return err == nil && n >= 0 && n <= math.MaxUint16,
+			fmt.Sprintf("Server port must be a number between 0 and %d, 0 for the default port.", math.MaxUint16)
 	case sharedZipName:
 		return utf8.RuneCountInString(in) >= 3 && utf8.RuneCountInString(in) <= 30 && strings.HasSuffix(in, ".zip"),
 			"Shared ZIP name must be 3-30 characters long & ends with “.zip”"
@@ -676,14 +859,131 @@ func populatePreferencesFromConfig(cfg config.Config) []preferenceQue {
 		},
 		{
 			title: stoppableInstance,
-			desc:  "Allow others on the same LAN to shutdown your server instance when no downloads are active.",
+			desc:  "Allow others on the same LAN to request the shutdown of your server instance, you decide on each request.",
 			pType: option,
 			pSec:  share,
 			check: cfg.Share.StoppableInstance,
 		},
+		{
+			title:  trustedPeers,
+			desc:   "Comma separated IP addresses of the peers that shut down your idle instance without asking, others wait for your decision.",
+			prompt: "IPs: ",
+			pType:  input,
+			pSec:   share,
+			input:  strings.Join(cfg.Share.TrustedPeers, ", "),
+		},
+		{
+			title:  stopToken,
+			desc:   "Token that lets its holders shut down your idle instance without asking, give it to the ones you trust, e.g. across a lab.",
+			prompt: "Token: ",
+			pType:  input,
+			pSec:   share,
+			input:  cfg.Share.StopToken,
+		},
+		{
+			title: allowUploads,
+			desc:  "Allow others on the same LAN to upload files into your download folder through the web UI.",
+			pType: option,
+			pSec:  share,
+			check: cfg.Share.AllowUploads,
+		},
+		{
+			title:  maxUploadSize,
+			desc:   "Maximum size of a single upload in MB, larger uploads are refused before they fill your disk, 0 for no limit.",
+			prompt: "MB: ",
+			pType:  input,
+			pSec:   share,
+			input:  strconv.Itoa(cfg.Share.MaxUploadMB),
+		},
+		{
+			title:  shareSecret,
+			desc:   "PIN or password others must enter to access your shared files, leave empty to share without one.",
+			prompt: "Secret: ",
+			pType:  input,
+			pSec:   share,
+			input:  cfg.Share.Secret,
+		},
+		{
+			title: askBeforeSharing,
+			desc:  "Ask for your approval when a new device requests your shared files, those you deny get nothing.",
+			pType: option,
+			pSec:  share,
+			check: cfg.Share.AskBeforeSharing,
+		},
+		{
+			title:  allowedClients,
+			desc:   "Comma separated subnets, IP addresses or usernames allowed to access your share, e.g. the lab's “10.1.0.0/16”, leave empty to allow everyone.",
+			prompt: "Clients: ",
+			pType:  input,
+			pSec:   share,
+			input:  strings.Join(cfg.Share.AllowList, ", "),
+		},
+		{
+			title:  blockedClients,
+			desc:   "Comma separated subnets, IP addresses or usernames denied access to your share, even if allowed, e.g. the guest network. Usernames can be faked, prefer subnets.",
+			prompt: "Clients: ",
+			pType:  input,
+			pSec:   share,
+			input:  strings.Join(cfg.Share.DenyList, ", "),
+		},
+		{
+			title: serveHTTPS,
+			desc:  "Encrypt transfers with a self-signed certificate, letshare clients pin it, browsers will warn about it once.",
+			pType: option,
+			pSec:  share,
+			check: cfg.Share.HTTPS,
+		},
+		{
+			title:  shareExpiry,
+			desc:   "Minutes after which the share expires & the server shuts down on its own, 0 to share until you stop it.",
+			prompt: "Minutes: ",
+			pType:  input,
+			pSec:   share,
+			input:  strconv.Itoa(cfg.Share.ExpiryMinutes),
+		},
+		{
+			title:  maxDownloads,
+			desc:   "Completed downloads allowed per file, the server shuts down once every file is used up, 0 for unlimited.",
+			prompt: "Downloads: ",
+			pType:  input,
+			pSec:   share,
+			input:  strconv.Itoa(cfg.Share.MaxDownloads),
+		},
+		{
+			title:  serverRateLimit,
+			desc:   "Maximum upload speed of the server in KB/s, shared by all clients, keeps the Wi-Fi usable for others, 0 for no limit.",
+			prompt: "KB/s: ",
+			pType:  input,
+			pSec:   share,
+			input:  strconv.Itoa(cfg.Share.RateLimit),
+		},
+		{
+			title:  clientRateLimit,
+			desc:   "Maximum upload speed to each client in KB/s, so one client can't hog the bandwidth, 0 for no limit.",
+			prompt: "KB/s: ",
+			pType:  input,
+			pSec:   share,
+			input:  strconv.Itoa(cfg.Share.ClientRateLimit),
+		},
+		{
+			title:  networkInterfaces,
+			desc:   interfacesDesc(),
+			prompt: "Interfaces: ",
+			pType:  input,
+			pSec:   share,
+			input:  strings.Join(cfg.Share.Interfaces, ", "),
+		},
+		{
+			title:  serverPort,
+			desc:   "Port the server binds to, 0 for the default (80, or 443 over HTTPS), a free one is picked if it is unavailable, e.g. without admin privileges.",
+			prompt: "Port: ",
+			pType:  input,
+			pSec:   share,
+			input:  strconv.Itoa(cfg.Share.Port),
+		},
 		{
 			title: zipFiles,
-			desc:  "Combine all selected files into a single zip archive. When disabled, each directory will be zipped separately.",
+			desc:  "Combine all selected files into a single zip archive. When disabled, directories are shared as browsable folders.",
 			pType: option,
 			pSec:  share,
 			check: cfg.Share.ZipFiles,
@@ -719,7 +1019,41 @@ func populatePreferencesFromConfig(cfg config.Config) []preferenceQue {
 			pSec:   receive,
 			input:  strconv.Itoa(cfg.Receive.ConcurrentDownloads),
 		},
+		{
+			title:  downloadRateLimit,
+			desc:   "Maximum speed of all downloads combined in KB/s, 0 for no limit.",
+			prompt: "KB/s: ",
+			pType:  input,
+			pSec:   receive,
+			input:  strconv.Itoa(cfg.Receive.RateLimit),
+		},
+	}
+}
+
+// interfacesDesc describes the network interfaces preference, along with the interfaces that are up.
+func interfacesDesc() string {
+	desc := "Comma separated names or addresses of the network interfaces to share on, " +
+		"leave empty to pick the one with internet, or all of them on networks without it."
+	ifaces, err := network.Interfaces()
+	if err != nil || len(ifaces) == 0 {
+		return desc
+	}
+	names := make([]string, len(ifaces))
+	for i, iface := range ifaces {
+		names[i] = iface.String()
+	}
+	return desc + " Up: " + strings.Join(names, ", ")
+}
+
+// splitList splits the comma separated list, e.g. of network interfaces, dropping the blank entries.
+func splitList(in string) []string {
+	var specs []string
+	for spec := range strings.SplitSeq(in, ",") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			specs = append(specs, spec)
+		}
 	}
+	return specs
 }
 
 func truncateRenderedTitle(title string) string {
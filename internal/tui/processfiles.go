@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/MuhamedUsman/letshare/internal/bgtask"
 	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/MuhamedUsman/letshare/internal/file"
 	"github.com/MuhamedUsman/letshare/internal/zipr"
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
@@ -168,7 +169,8 @@ func (m processFilesModel) Update(msg tea.Msg) (processFilesModel, tea.Cmd) {
 			cfg, _ = config.Load()
 		}
 
-		if !cfg.Share.ZipFiles && msg.dirs == 0 {
+		// directories are shared as browsable trees, so there is nothing to process
+		if !cfg.Share.ZipFiles {
 			files := make(sendFilesMsg, len(msg.filenames))
 			for i, f := range msg.filenames {
 				files[i] = filepath.Join(msg.parentPath, f)
@@ -381,23 +383,21 @@ func (m *processFilesModel) processFiles(
 		defer func() { _ = zipper.Close() }()
 		var err error
 
+		// zipping the files added to a running share must not overwrite the archive being served
+		name := cfg.Share.SharedZipName
+		if _, statErr := os.Stat(filepath.Join(os.TempDir(), name)); statErr == nil {
+			name = file.UniqueName(name)
+		}
+
 		bgtask.Get().RunAndBlock(func(_ context.Context) {
-			if cfg.Share.ZipFiles {
-				var archive string
-				archive, err = zipper.CreateArchive(
-					os.TempDir(),
-					cfg.Share.SharedZipName,
-					msg.parentPath,
-					msg.filenames...,
-				)
-				archives = []string{archive}
-			} else {
-				archives, err = zipDirsAndCollectWithFiles(
-					zipper,
-					msg.parentPath,
-					msg.filenames...,
-				)
-			}
+			var archive string
+			archive, err = zipper.CreateArchive(
+				os.TempDir(),
+				name,
+				msg.parentPath,
+				msg.filenames...,
+			)
+			archives = []string{archive}
 		})
 
 		// if the zipping is canceled, we need to wait for the cancellation to finish
@@ -475,37 +475,6 @@ func (m processFilesModel) grantExtSpaceSwitch() bool {
 	return true
 }
 
-func zipDirsAndCollectWithFiles(zipper *zipr.Zipr, root string, filenames ...string) ([]string, error) {
-	dirs, files, err := splitToDirsAndFiles(root, filenames...)
-	if err != nil {
-		return nil, err
-	}
-	archives, err := zipper.CreateArchives(os.TempDir(), root, dirs...)
-	if err != nil {
-		return nil, err
-	}
-	for _, f := range files {
-		archives = append(archives, filepath.Join(root, f))
-	}
-	return archives, nil
-}
-
-func splitToDirsAndFiles(root string, filenames ...string) (dirs, files []string, err error) {
-	for _, filename := range filenames {
-		var info os.FileInfo
-		info, err = os.Lstat(filepath.Join(root, filename))
-		if err != nil {
-			return nil, nil, err
-		}
-		if info.IsDir() {
-			dirs = append(dirs, filename)
-		} else {
-			files = append(files, filename)
-		}
-	}
-	return dirs, files, nil
-}
-
 func customProcessFilesHelp(show bool) *table.Table {
 	baseStyle := lipgloss.NewStyle()
 	var rows [][]string
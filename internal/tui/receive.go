@@ -1,7 +1,6 @@
 package tui
 
 import (
-	"fmt"
 	"github.com/MuhamedUsman/letshare/internal/mdns"
 	"github.com/MuhamedUsman/letshare/internal/server"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -15,7 +14,7 @@ import (
 	"sync/atomic"
 )
 
-var instanceExtractor = strings.NewReplacer("http://", "", ".local", "")
+var instanceExtractor = strings.NewReplacer("https://", "", "http://", "", ".local", "")
 
 type receiveModel struct {
 	mdns                                                    *mdns.MDNS
@@ -243,14 +242,15 @@ func (m receiveModel) renderInstanceConnectionInfo() string {
 	sb.WriteString(baseStyle.Foreground(midHighlightColor).Render(s))
 	sb.WriteRune('\n')
 
-	ip := m.mdns.Entries()[*m.trackInstance.Load()].IP
-	ip = "http://" + ip
+	entry := m.mdns.Entries()[*m.trackInstance.Load()]
+	secure := entry.Fingerprint != ""
+	ip := "http://" + server.URLHost(entry.IP, int(entry.Port), secure)
+	if secure {
+		ip = "https://" + server.URLHost(entry.IP, int(entry.Port), secure)
+	}
 	qr := m.generateQR(ip)
 	qr = baseStyle.Render(qr)
 
-	if server.GetPort() == server.TestHTTPPort {
-		ip = fmt.Sprintf("%s:%d", ip, server.TestHTTPPort)
-	}
 	ip = baseStyle.Underline(true).Italic(true).Render(ip)
 
 	titleH := lipgloss.Height(m.renderTitle())
@@ -387,7 +387,7 @@ func customReceiveHelp(show bool) *lipTable.Table {
 }
 
 func makeURL(s string) string {
-	hasHTTP := strings.HasPrefix(s, "http://")
+	hasHTTP := strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
 	hasLocal := strings.HasSuffix(s, ".local")
 	if hasHTTP && hasLocal {
 		return s // already a valid URL
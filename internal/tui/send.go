@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"github.com/MuhamedUsman/letshare/internal/config"
 	"github.com/MuhamedUsman/letshare/internal/mdns"
 	"github.com/MuhamedUsman/letshare/internal/server"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -48,12 +51,11 @@ const (
 	idle requiredInstanceState = iota
 	// we're requesting the instance
 	requesting
-	// the instance is serving files and is not idle
-	// the owner will be notified for our request
+	// the owner did not decide on our request in time, the instance keeps serving
 	serving
 	// the instance became available for us to use
 	available
-	// the owner doesn't allow the server to be shutdown by others
+	// the owner doesn't allow the server to be shutdown by others, or declined our request
 	requestRejected
 	// the instance will start graceful shutdown
 	requestAccepted
@@ -61,26 +63,99 @@ const (
 	notResponding
 )
 
-func instanceStateCmd(state requiredInstanceState) tea.Cmd {
+func instanceStateCmd(id int, state requiredInstanceState) tea.Cmd {
 	return func() tea.Msg {
-		return state
+		return instanceStateMsg{id, state}
+	}
+}
+
+// shareSession is a share, with its own server, mDNS instance, files & stoppable flag,
+// several of them may run at once, e.g. the slides under the default instance & a private
+// folder under a custom one, see sendModel.shares
+type shareSession struct {
+	// id tells the messages of the shares apart, as they all go through sendModel.Update
+	id                                int
+	server                            *server.Server
+	files                             []string
+	customInstance, secret, limits    string
+	btnIdx, selected                  instanceBtn
+	instanceState                     requiredInstanceState
+	isSelected, isServing, isShutdown bool
+	// stoppable lets others on the same LAN shut down the share when it is idle, see server.Server.SetStoppable
+	stoppable bool
+	// approvals queued for the host, the first one is being asked
+	approvals []server.ApprovalReq
+	// stop requests queued for the host, the first one is being asked
+	stopReqs []server.StopReq
+	// snippets is the number of texts shared in the share
+	snippets int
+}
+
+// instance returns the mDNS instance the share is published as, once selected.
+func (sh shareSession) instance() string {
+	switch sh.selected {
+	case defaultInstance:
+		return mdns.DefaultInstance
+	case customInstance:
+		return sh.customInstance
+	default:
+		return ""
+	}
+}
+
+// canEnterStopToken reports whether the instance the share wants is still served by another, after asking for it.
+func (sh shareSession) canEnterStopToken() bool {
+	return !sh.isServing && (sh.instanceState == requestRejected || sh.instanceState == serving)
+}
+
+// status describes the state of the share in the list of shares.
+func (sh shareSession) status() string {
+	switch {
+	case sh.isShutdown:
+		return "shutting down"
+	case sh.isServing:
+		return "serving"
+	case sh.instanceState != idle:
+		return "requesting"
+	default:
+		return "setting up"
 	}
 }
 
 type sendModel struct {
-	server                              *server.Server
-	client                              *client.Client
-	mdns                                *mdns.MDNS
-	files                               []string
-	customInstance                      string
-	titleStyle                          lipgloss.Style
-	btnIdx, selected                    instanceBtn
-	instanceState                       requiredInstanceState
-	isSelected, showHelp, disableKeymap bool
-	isServing, isShutdown               bool
+	client     *client.Client
+	mdns       *mdns.MDNS
+	titleStyle lipgloss.Style
+	// shares running or being set up, the active one is shown & managed, see sendModel.cur
+	shares []shareSession
+	active int
+	// lastID is the id of the latest share, see shareSession.id
+	lastID int
+	// newShare is set once the host asks for another share, the files selected next
+	// start a new share, instead of being added to the active one
+	newShare                bool
+	showHelp, disableKeymap bool
+	// msgInput is focused while the host writes a message to the receivers, see server.Server.Broadcast
+	msgInput textinput.Model
+	// snippetInput is focused while the host writes a text to share alongside the files, see server.Server.AddSnippet
+	snippetInput textarea.Model
+	// stopTokenInput is focused while the host enters the stop token of the instance they want, see client.Client.SetStopToken
+	stopTokenInput textinput.Model
 }
 
 func initialSendModel() sendModel {
+	return sendModel{
+		msgInput:       newSendInput("Message to the receivers…", 280),
+		snippetInput:   newSnippetInput(),
+		stopTokenInput: newSendInput("Stop token of the instance, given by its owner…", 64),
+		client:         client.Get(),
+		mdns:           mdns.Get(),
+		titleStyle:     titleStyle.Margin(0, 2),
+		disableKeymap:  true, // initially dirNavModel will handle key events
+	}
+}
+
+func newSendInput(placeholder string, limit int) textinput.Model {
 	t := textinput.New()
 	t.Prompt = ""
 	t.ShowSuggestions = true
@@ -89,23 +164,66 @@ func initialSendModel() sendModel {
 	t.Cursor.TextStyle = t.Cursor.Style.Foreground(highlightColor)
 	t.Cursor.Style = t.Cursor.TextStyle.Reverse(true)
 	t.PlaceholderStyle = t.PlaceholderStyle.Foreground(subduedHighlightColor)
+	t.Placeholder = placeholder
+	t.CharLimit = limit
+	return t
+}
 
-	return sendModel{
-		client:        client.Get(),
-		mdns:          mdns.Get(),
-		titleStyle:    titleStyle.Margin(0, 2),
-		disableKeymap: true, // initially dirNavModel will handle key events
+// newSnippetInput returns the multi-line input of the texts to share, enter shares the text,
+// so new lines are inserted with alt+enter or ctrl+j, pasted texts keep theirs.
+func newSnippetInput() textarea.Model {
+	t := textarea.New()
+	t.Prompt = ""
+	t.ShowLineNumbers = false
+	t.MaxHeight = 0 // the text is only capped by its size
+	t.CharLimit = server.MaxSnippetSize
+	t.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	t.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	t.FocusedStyle.Text = t.FocusedStyle.Text.Foreground(highlightColor)
+	t.FocusedStyle.Placeholder = t.FocusedStyle.Placeholder.Foreground(subduedHighlightColor)
+	t.FocusedStyle.EndOfBuffer = t.FocusedStyle.EndOfBuffer.Foreground(subduedHighlightColor)
+	t.Cursor.TextStyle = t.Cursor.Style.Foreground(highlightColor)
+	t.Cursor.Style = t.Cursor.TextStyle.Reverse(true)
+	t.Placeholder = "Text to share, a link, a token… alt+enter for a new line"
+	t.SetHeight(5)
+	return t
+}
+
+// cur returns the active share, changes made through it are kept, as the shares are shared by the copies of the model.
+func (m sendModel) cur() *shareSession {
+	if m.active < 0 || m.active >= len(m.shares) {
+		return new(shareSession)
+	}
+	return &m.shares[m.active]
+}
+
+// shareByID returns the share with the id, reports false if it is gone.
+func (m sendModel) shareByID(id int) (*shareSession, bool) {
+	i := slices.IndexFunc(m.shares, func(sh shareSession) bool { return sh.id == id })
+	if i < 0 {
+		return nil, false
 	}
+	return &m.shares[i], true
 }
 
 func (m sendModel) capturesKeyEvent(msg tea.KeyMsg) bool {
+	if (m.msgInput.Focused() || m.snippetInput.Focused() || m.stopTokenInput.Focused()) && msg.String() != "ctrl+c" {
+		return true
+	}
+	sh := m.cur()
 	switch msg.String() {
 	case "left", "right", "h", "l", "enter", " ", "q", "Q", "?":
 		return !m.disableKeymap
 	case "ctrl+r":
-		return m.isSelected && m.isServing
+		return sh.isSelected && sh.isServing
+	case "a", "A", "m", "M", "t", "T", "n", "N", "s", "S":
+		return sh.isServing && !m.disableKeymap
+	case "k", "K":
+		return sh.canEnterStopToken() && !m.disableKeymap
+	case "[", "]":
+		return len(m.shares) > 1 && !m.disableKeymap
 	case "esc":
-		return m.instanceState == idle && !m.disableKeymap
+		return sh.instanceState == idle && !m.disableKeymap
 	default:
 		return false
 	}
@@ -120,39 +238,48 @@ func (m sendModel) Update(msg tea.Msg) (sendModel, tea.Cmd) {
 
 	case tea.KeyMsg:
 		// we're not respecting disableKeymap here, making sure we don't consume that keyEvent by send it back
-		if msg.Type == tea.KeyCtrlC && len(m.files) > 0 {
+		if msg.Type == tea.KeyCtrlC && slices.ContainsFunc(m.shares, func(sh shareSession) bool { return len(sh.files) > 0 }) {
 			return m, tea.Batch(m.deleteTempFiles(), msgToCmd(tea.KeyMsg{Type: tea.KeyCtrlC}))
 		}
 
 		if m.disableKeymap {
 			return m, nil
 		}
+		if m.msgInput.Focused() {
+			return m, m.handleMsgInput(msg)
+		}
+		if m.snippetInput.Focused() {
+			return m, m.handleSnippetInput(msg)
+		}
+		if m.stopTokenInput.Focused() {
+			return m, m.handleStopTokenInput(msg)
+		}
+		sh := m.cur()
 		switch msg.String() {
 		case "left", "h":
-			if !m.isSelected {
-				m.btnIdx = 0
+			if !sh.isSelected {
+				sh.btnIdx = 0
 			}
 
 		case "right", "l":
-			if !m.isSelected {
-				m.btnIdx = 1
+			if !sh.isSelected {
+				sh.btnIdx = 1
 			}
 
 		case "enter":
-			if !m.isSelected {
-				m.isSelected = true
-				m.selected = m.btnIdx
-				conf := m.getConfig()
-				m.customInstance = conf.Share.InstanceName
-				lch, dch := make(chan server.Log, 20), make(chan int, 20)
-				m.server = server.New(conf.Share.StoppableInstance, lch, dch)
-				extSendChCmd := msgToCmd(handleExtSendCh{lch, dch})
-				return m, tea.Sequence(extSendChCmd, m.publishInstanceAndStartServer())
+			if !sh.isSelected {
+				sh.selected = sh.btnIdx
+				sh.customInstance = m.getConfig().Share.InstanceName
+				if m.isOwnInstance(sh.instance()) {
+					return m, m.showOwnInstanceAlert(sh.instance())
+				}
+				sh.isSelected = true
+				return m, tea.Sequence(m.newServer(sh), m.publishInstanceAndStartServer(*sh))
 			}
 
 		case "Q", "q":
-			if m.isServing {
-				return m, m.shutdownServer(false)
+			if sh.isServing {
+				return m, m.shutdownServer(*sh, false)
 			}
 
 		case " ":
@@ -161,13 +288,58 @@ func (m sendModel) Update(msg tea.Msg) (sendModel, tea.Cmd) {
 			}
 
 		case "ctrl+r":
-			if m.isSelected && m.isServing {
-				m.mdns.ReloadPublisher()
+			if sh.isSelected && sh.isServing {
+				m.mdns.ReloadPublisher(sh.instance())
+			}
+
+		case "a", "A": // select more files to share, without restarting the server
+			if sh.isServing {
+				m.newShare = false
+				return m, tea.Batch(m.shareMode(), msgToCmd(localChildSwitchMsg{child: dirNav, focus: true}))
+			}
+
+		case "n", "N": // select the files of another share, the running ones keep running
+			if sh.isServing {
+				m.newShare = true
+				return m, tea.Batch(m.shareMode(), msgToCmd(localChildSwitchMsg{child: dirNav, focus: true}))
+			}
+
+		case "[":
+			if len(m.shares) > 1 {
+				m.active = (m.active - 1 + len(m.shares)) % len(m.shares)
+				return m, m.sharesChanged()
+			}
+
+		case "]":
+			if len(m.shares) > 1 {
+				m.active = (m.active + 1) % len(m.shares)
+				return m, m.sharesChanged()
+			}
+
+		case "m", "M":
+			if sh.isServing {
+				return m, m.msgInput.Focus()
+			}
+
+		case "t", "T":
+			if sh.isServing {
+				return m, m.snippetInput.Focus()
+			}
+
+		case "k", "K":
+			if sh.canEnterStopToken() {
+				return m, m.stopTokenInput.Focus()
+			}
+
+		case "s", "S":
+			if sh.isServing {
+				sh.stoppable = !sh.stoppable
+				sh.server.SetStoppable(sh.stoppable)
 			}
 
 		case "esc":
-			if !m.isServing && !m.isShutdown {
-				return m, m.confirmEsc()
+			if !sh.isServing && !sh.isShutdown {
+				return m, m.confirmEsc(*sh)
 			}
 
 		case "?":
@@ -178,70 +350,291 @@ func (m sendModel) Update(msg tea.Msg) (sendModel, tea.Cmd) {
 		m.updateTitleStyleAsFocus()
 
 	case sendFilesMsg:
-		m.files = msg
+		if sh := m.cur(); sh.isServing && !m.newShare {
+			sh.server.AddFiles(msg...)
+			for _, f := range msg {
+				if !slices.Contains(sh.files, f) {
+					sh.files = append(sh.files, f)
+				}
+			}
+			return m, nil
+		}
+		// a new share, the running ones keep running in the background
+		m.lastID++
+		m.shares = append(m.shares, shareSession{id: m.lastID, files: msg, stoppable: m.getConfig().Share.StoppableInstance})
+		m.active = len(m.shares) - 1
+		m.newShare = false
+		return m, m.sharesChanged()
 
 	case instanceServingMsg:
-		m.isServing = true
+		if sh, ok := m.shareByID(msg.id); ok {
+			sh.isServing = true
+			return m, m.shareMode()
+		}
+
+	case preferencesSavedMsg:
+		// speed limits apply live, the rest of the preferences on the next share
+		for _, sh := range m.shares {
+			if sh.isServing {
+				sh.server.SetRateLimits(m.getConfig().Share)
+			}
+		}
 
 	case serverStartupErrMsg:
-		return m, tea.Batch(msgToCmd(instanceShutdownMsg{}), msgToCmd(errMsg(msg)))
+		if sh, ok := m.shareByID(msg.id); ok && sh.server != nil {
+			sh.server.ShutdownServer() // it may be up, but unreachable without the mDNS instance
+		}
+		return m, tea.Batch(msgToCmd(instanceShutdownMsg{msg.id}), msgToCmd(msg.err))
 
 	case instanceShutdownMsg:
-		m.isSelected, m.isServing, m.isShutdown = false, false, true
-		m.instanceState = idle
+		sh, ok := m.shareByID(msg.id)
+		if !ok {
+			return m, nil
+		}
+		sh.isSelected, sh.isServing, sh.isShutdown = false, false, true
+		sh.approvals, sh.stopReqs = nil, nil
+		sh.snippets = 0
+		sh.instanceState = idle
+		if sh == m.cur() {
+			m.msgInput.Reset()
+			m.msgInput.Blur()
+			m.snippetInput.Reset()
+			m.snippetInput.Blur()
+			m.stopTokenInput.Reset()
+			m.stopTokenInput.Blur()
+		}
+		return m, m.shareMode()
 
 	case serverLogsTimeoutMsg:
-		m.isShutdown = false
-		return m, msgToCmd(localChildSwitchMsg{child: dirNav, focus: currentFocus == local})
+		i := slices.IndexFunc(m.shares, func(sh shareSession) bool { return sh.id == msg.id })
+		if i < 0 {
+			return m, nil
+		}
+		return m, m.removeShare(i)
+
+	case shareCanceledMsg:
+		i := slices.IndexFunc(m.shares, func(sh shareSession) bool { return sh.id == msg.id })
+		if i < 0 {
+			return m, nil
+		}
+		files := m.shares[i].files
+		m.shares[i].files = nil
+		if srv := m.shares[i].server; srv != nil { // selected, but not serving yet
+			srv.ShutdownServer()
+			return m, tea.Batch(removeTempFiles(files), msgToCmd(instanceShutdownMsg{msg.id}))
+		}
+		return m, tea.Batch(removeTempFiles(files), m.removeShare(i))
+
+	case stopReqMsg:
+		sh, ok := m.shareByID(msg.id)
+		if !ok {
+			return m, nil
+		}
+		sh.stopReqs = append(sh.stopReqs, msg.req)
+		if len(sh.stopReqs) == 1 {
+			return m, tea.Batch(m.waitForStopReq(*sh), m.askStop(*sh, sh.stopReqs[0]))
+		}
+		return m, m.waitForStopReq(*sh)
+
+	case stopReqDecidedMsg:
+		sh, ok := m.shareByID(msg.id)
+		if !ok {
+			return m, nil
+		}
+		if len(sh.stopReqs) > 0 {
+			sh.stopReqs = sh.stopReqs[1:]
+		}
+		if len(sh.stopReqs) > 0 {
+			return m, m.askStop(*sh, sh.stopReqs[0])
+		}
 
-	case shutdownReqWhenNotIdleMsg:
-		return m, tea.Batch(m.notifyForShutdownReqWhenNotIdle(), m.showShutdownReqWhenNotIdleAlert(string(msg)))
+	case approvalReqMsg:
+		sh, ok := m.shareByID(msg.id)
+		if !ok {
+			return m, nil
+		}
+		sh.approvals = append(sh.approvals, msg.req)
+		if len(sh.approvals) == 1 {
+			return m, tea.Batch(m.waitForApprovalReq(*sh), m.askApproval(*sh, sh.approvals[0]))
+		}
+		return m, m.waitForApprovalReq(*sh)
+
+	case approvalDecidedMsg:
+		sh, ok := m.shareByID(msg.id)
+		if !ok {
+			return m, nil
+		}
+		if len(sh.approvals) > 0 {
+			sh.approvals = sh.approvals[1:]
+		}
+		if len(sh.approvals) > 0 {
+			return m, m.askApproval(*sh, sh.approvals[0])
+		}
 
-	case requiredInstanceState:
-		if m.isServing {
+	case instanceStateMsg:
+		sh, ok := m.shareByID(msg.id)
+		if !ok || sh.isServing {
 			// if our instance is already serving, ignore the state change of required instance
 			return m, nil
 		}
 
-		m.instanceState = msg
-		switch msg {
+		sh.instanceState = msg.state
+		if !sh.canEnterStopToken() && sh == m.cur() {
+			m.stopTokenInput.Reset()
+			m.stopTokenInput.Blur()
+		}
+		switch msg.state {
 		case requesting:
 		case idle:
-			m.isSelected = false
+			sh.isSelected = false
 
 		case available:
-			m.isSelected = true
-			lch, dch := make(chan server.Log, 20), make(chan int, 20)
-			m.server = server.New(m.getConfig().Share.StoppableInstance, lch, dch)
-			extSendChCmd := msgToCmd(handleExtSendCh{lch, dch})
-			return m, tea.Sequence(extSendChCmd, m.publishInstanceAndStartServer())
+			sh.isSelected = true
+			return m, tea.Sequence(m.newServer(sh), m.publishInstanceAndStartServer(*sh))
 
 		case notResponding:
-			m.isSelected = false
-			m.instanceState = idle
+			sh.isSelected = false
+			sh.instanceState = idle
 			return m, msgToCmd(errMsg{
 				errHeader: strings.ToUpper(http.StatusText(http.StatusRequestTimeout)),
 				errStr:    "Request failed, the server instance is not responding, it might be down.",
 			})
 
 		case requestRejected, serving:
-			m.isSelected = false
-			return m, m.notifyInstanceAvailability()
+			sh.isSelected = false
+			return m, m.notifyInstanceAvailability(*sh)
 
 		case requestAccepted:
-			return m, m.notifyInstanceAvailability()
+			return m, m.notifyInstanceAvailability(*sh)
 		}
 	}
 
+	if m.msgInput.Focused() { // keep the cursor blinking
+		var cmd tea.Cmd
+		m.msgInput, cmd = m.msgInput.Update(msg)
+		return m, cmd
+	}
+	if m.snippetInput.Focused() {
+		var cmd tea.Cmd
+		m.snippetInput, cmd = m.snippetInput.Update(msg)
+		return m, cmd
+	}
+	if m.stopTokenInput.Focused() {
+		var cmd tea.Cmd
+		m.stopTokenInput, cmd = m.stopTokenInput.Update(msg)
+		return m, cmd
+	}
 	return m, nil
 }
 
+// removeShare removes the share, once it is shut down or canceled, if it was shown, the next one is shown
+// instead, or the one before it, if it was the last, the file selection is shown once no share is left.
+func (m *sendModel) removeShare(i int) tea.Cmd {
+	m.shares = slices.Delete(m.shares, i, i+1)
+	if i < m.active || m.active >= len(m.shares) {
+		m.active = max(0, m.active-1)
+	}
+	if len(m.shares) == 0 {
+		m.newShare = false
+		return tea.Batch(m.sharesChanged(), msgToCmd(localChildSwitchMsg{child: dirNav, focus: currentFocus == local}))
+	}
+	return m.sharesChanged()
+}
+
+// sharesChanged tells the rest of the spaces about the active share, once the shares change.
+func (m *sendModel) sharesChanged() tea.Cmd {
+	m.msgInput.Blur()
+	m.snippetInput.Blur()
+	m.stopTokenInput.Reset()
+	m.stopTokenInput.Blur()
+	return tea.Batch(m.shareMode(), msgToCmd(activeShareMsg{m.cur().id}))
+}
+
+// shareMode tells the file selection whether the selected files are added to the active share.
+func (m sendModel) shareMode() tea.Cmd {
+	return msgToCmd(shareModeMsg{
+		sharing: m.isServing(),
+		adding:  m.cur().isServing && !m.newShare,
+	})
+}
+
+// handleMsgInput handles keys while the host writes a message, enter sends it to the receivers, esc discards it.
+func (m *sendModel) handleMsgInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		if text := strings.TrimSpace(m.msgInput.Value()); text != "" && m.cur().isServing {
+			m.cur().server.Broadcast(text)
+		}
+		fallthrough
+	case "esc":
+		m.msgInput.Reset()
+		m.msgInput.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.msgInput, cmd = m.msgInput.Update(msg)
+	return cmd
+}
+
+// handleSnippetInput handles keys while the host writes a text to share, enter shares it, esc discards it,
+// see newSnippetInput for the new lines.
+func (m *sendModel) handleSnippetInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		var cmd tea.Cmd
+		if sh := m.cur(); strings.TrimSpace(m.snippetInput.Value()) != "" && sh.isServing {
+			if _, err := sh.server.AddSnippet(m.snippetInput.Value()); err != nil {
+				cmd = msgToCmd(errMsg{errHeader: "TEXT NOT SHARED!", errStr: err.Error()})
+			} else {
+				sh.snippets++
+			}
+		}
+		m.snippetInput.Reset()
+		m.snippetInput.Blur()
+		return cmd
+	case "esc":
+		m.snippetInput.Reset()
+		m.snippetInput.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.snippetInput, cmd = m.snippetInput.Update(msg)
+	return cmd
+}
+
+// handleStopTokenInput handles keys while the host enters the stop token of the instance they want,
+// enter asks the instance to shut down again, with the token, esc discards it.
+func (m *sendModel) handleStopTokenInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		var cmd tea.Cmd
+		if sh := m.cur(); strings.TrimSpace(m.stopTokenInput.Value()) != "" && sh.canEnterStopToken() {
+			m.client.SetStopToken(sh.instance(), strings.TrimSpace(m.stopTokenInput.Value()))
+			sh.isSelected = true
+			cmd = tea.Batch(instanceStateCmd(sh.id, requesting), m.stopOwnedServerInstance(sh.id, sh.instance()))
+		}
+		m.stopTokenInput.Reset()
+		m.stopTokenInput.Blur()
+		return cmd
+	case "esc":
+		m.stopTokenInput.Reset()
+		m.stopTokenInput.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.stopTokenInput, cmd = m.stopTokenInput.Update(msg)
+	return cmd
+}
+
 func (m sendModel) View() string {
-	views := []string{
-		m.renderTitle(),
-		m.renderInstanceSelectionForm(),
-		customSendHelp(m.showHelp).Width(smallContainerW() - 2).Render(),
+	views := []string{m.renderTitle()}
+	if len(m.shares) > 1 {
+		views = append(views, m.renderShares())
 	}
+	views = append(views,
+		m.renderInstanceSelectionForm(),
+		customSendHelp(m.showHelp).Width(smallContainerW()-2).Render(),
+	)
 	return lipgloss.JoinVertical(lipgloss.Top, views...)
 }
 
@@ -267,10 +660,40 @@ func (m sendModel) renderTitle() string {
 	return m.titleStyle.Render(t)
 }
 
+// renderShares lists the shares, the active one highlighted, see sendModel.cur.
+func (m sendModel) renderShares() string {
+	baseStyle := lipgloss.NewStyle().Padding(0, 1)
+	rows := make([][]string, len(m.shares))
+	for i, sh := range m.shares {
+		name := sh.instance()
+		if !sh.isSelected && !sh.isServing && !sh.isShutdown {
+			name = "new share"
+		}
+		rows[i] = []string{fmt.Sprintf("%d.", i+1), name, sh.status()}
+	}
+	return lipTable.New().
+		Border(lipgloss.HiddenBorder()).
+		BorderTop(false).
+		Wrap(false).
+		Width(smallContainerW() - 2).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == m.active {
+				return baseStyle.Foreground(subduedHighlightColor).Background(highlightColor)
+			}
+			return baseStyle.Foreground(midHighlightColor)
+		}).
+		Rows(rows...).
+		String()
+}
+
 func (m sendModel) renderInstanceSelectionForm() string {
 	h := workableH() - 2 - lipgloss.Height(customSendHelp(m.showHelp).String())
+	if len(m.shares) > 1 {
+		h -= lipgloss.Height(m.renderShares())
+	}
+	sh := m.cur()
 	infoTxt := m.renderInfoText()
-	if m.isSelected || m.isShutdown {
+	if sh.isSelected || sh.isShutdown {
 		s := lipgloss.JoinVertical(lipgloss.Left, m.renderSelectedInstanceHeader(), infoTxt)
 		return lipgloss.PlaceVertical(h, lipgloss.Center, s)
 	}
@@ -293,25 +716,33 @@ func (m sendModel) renderFormTitle() string {
 func (m sendModel) renderInfoText() string {
 	baseStyle := lipgloss.NewStyle().Foreground(midHighlightColor).Align(lipgloss.Center)
 	sb := new(strings.Builder)
-	var port string
-	if server.GetPort() == server.TestHTTPPort {
-		port = fmt.Sprint(":", server.TestHTTPPort)
+	sh := m.cur()
+	cfg := m.getConfig().Share
+	scheme, port := "http", cmp.Or(cfg.Port, server.GetPort(cfg.HTTPS))
+	if cfg.HTTPS {
+		scheme = "https"
 	}
-	switch m.btnIdx {
+	if sh.isServing && sh.server != nil {
+		// the preferences may have changed since, the port is the one bound to, if the preferred one was unavailable
+		scheme, port = sh.server.Scheme(), sh.server.Port()
+	}
+	secure := scheme == "https"
+	switch sh.btnIdx {
 	case defaultInstance:
-		s := fmt.Sprintf("Public default address, “http://letshare.local%s”", port)
+		s := fmt.Sprintf("Public default address, “%s://%s”", scheme, server.URLHost("letshare.local", port, secure))
 		sb.WriteString(baseStyle.Render(s))
 	case customInstance:
 		s := baseStyle.Render("A custom address for privacy, to update hit “ctrl+p”")
-		if m.isSelected && m.btnIdx == customInstance {
-			s = fmt.Sprintf("Private custom address, “http://%s.local%s”", m.customInstance, port)
+		if sh.isSelected && sh.btnIdx == customInstance {
+			host := server.URLHost(sh.customInstance+".local", port, secure)
+			s = fmt.Sprintf("Private custom address, “%s://%s”", scheme, host)
 			s = baseStyle.Render(s)
 		}
 		sb.WriteString(s)
 	case noInstance:
 	}
 
-	if m.instanceState != idle || m.isServing || m.isShutdown {
+	if sh.instanceState != idle || sh.isServing || sh.isShutdown {
 		sb.WriteString("\n\n")
 		divider := strings.Repeat("—", max(0, smallContainerW()-4))
 		sb.WriteString(baseStyle.Foreground(subduedHighlightColor).Render(divider))
@@ -319,27 +750,58 @@ func (m sendModel) renderInfoText() string {
 		baseStyle = baseStyle.Foreground(highlightColor).Blink(true)
 	}
 
-	if m.isServing {
+	if sh.isServing {
 		sb.WriteString(baseStyle.UnsetBlink().Render("The server instance is up & running… Press “Q/q” to shutdown."))
-	} else if m.isShutdown {
+		if sh.secret != "" {
+			sb.WriteString("\n\n")
+			sb.WriteString(baseStyle.UnsetBlink().Foreground(yellowColor).Render(fmt.Sprintf("Protected with the share secret “%s”", sh.secret)))
+		}
+		if sh.limits != "" {
+			sb.WriteString("\n\n")
+			sb.WriteString(baseStyle.UnsetBlink().Foreground(yellowColor).Render(sh.limits))
+		}
+		if sh.stoppable {
+			sb.WriteString("\n\n")
+			sb.WriteString(baseStyle.UnsetBlink().Render("Others on the LAN can request the shutdown of the share"))
+		}
+		if sh.snippets > 0 {
+			sb.WriteString("\n\n")
+			sb.WriteString(baseStyle.UnsetBlink().Render(fmt.Sprintf("%d text snippet(s) shared alongside the files", sh.snippets)))
+		}
+		inputW := max(0, smallContainerW()-smallContainerStyle.GetHorizontalFrameSize()-4)
+		if m.msgInput.Focused() {
+			m.msgInput.Width = inputW
+			sb.WriteString("\n\n")
+			sb.WriteString(m.msgInput.View())
+		}
+		if m.snippetInput.Focused() {
+			m.snippetInput.SetWidth(inputW)
+			sb.WriteString("\n\n")
+			sb.WriteString(m.snippetInput.View())
+		}
+	} else if sh.isShutdown {
 		sb.WriteString(baseStyle.Foreground(highlightColor).Blink(true).Render("Shutting down the server instance, please wait…"))
 	} else {
-		switch m.instanceState {
+		switch sh.instanceState {
 		case idle, notResponding, available:
 		case requesting:
-			sb.WriteString(baseStyle.Foreground(yellowColor).Render("Instance already serving, requesting shutdown…"))
+			currentOwner := m.getInstanceOwner(sh.instance())
+			msg := fmt.Sprintf("Instance already serving, waiting for %q to decide on your shutdown request…", currentOwner)
+			sb.WriteString(baseStyle.Foreground(yellowColor).Render(msg))
 		case serving:
-			currentOwner := m.getInstanceOwner(m.getInstance())
-			msg := fmt.Sprintf("Server is currently serving files for %q. They're notified of your request, Please either wait or switch instance.", currentOwner)
+			currentOwner := m.getInstanceOwner(sh.instance())
+			msg := fmt.Sprintf("%q didn't decide on your shutdown request in time. Please either wait for availability or switch instance.", currentOwner)
 			sb.WriteString(baseStyle.Foreground(redColor).Render(msg))
+			sb.WriteString(m.renderStopTokenInput(baseStyle))
 		case requestAccepted:
-			currentOwner := m.getInstanceOwner(m.getInstance())
+			currentOwner := m.getInstanceOwner(sh.instance())
 			msg := fmt.Sprintf("Shutting down the server instance serving for %q please wait…", currentOwner)
 			sb.WriteString(baseStyle.Foreground(yellowColor).Render(msg))
 		case requestRejected:
-			currentOwner := m.getInstanceOwner(m.getInstance())
-			msg := fmt.Sprintf("Instance owner %q has blocked shutdown. Please either wait for availability or switch instance.", currentOwner)
+			currentOwner := m.getInstanceOwner(sh.instance())
+			msg := fmt.Sprintf("Instance owner %q has declined or blocked shutdown. Please either wait for availability or switch instance.", currentOwner)
 			sb.WriteString(baseStyle.Foreground(redColor).Render(msg))
+			sb.WriteString(m.renderStopTokenInput(baseStyle))
 		}
 	}
 
@@ -351,6 +813,15 @@ func (m sendModel) renderInfoText() string {
 		Render(sb.String())
 }
 
+// renderStopTokenInput renders the stop token input while it is focused, or a hint on how to focus it.
+func (m sendModel) renderStopTokenInput(baseStyle lipgloss.Style) string {
+	if !m.stopTokenInput.Focused() {
+		return "\n\n" + baseStyle.UnsetBlink().Render("Got its stop token from the owner? Press “K/k” to enter it.")
+	}
+	m.stopTokenInput.Width = max(0, smallContainerW()-smallContainerStyle.GetHorizontalFrameSize()-4)
+	return "\n\n" + m.stopTokenInput.View()
+}
+
 func (m sendModel) renderInstanceBtns() string {
 	inactiveStyle := lipgloss.NewStyle().
 		MarginBottom(2).
@@ -367,7 +838,7 @@ func (m sendModel) renderInstanceBtns() string {
 	defaultBtn := runewidth.Truncate(defaultInstance.string(), w, "…")
 	customBtn := runewidth.Truncate(customInstance.string(), w, "…")
 
-	switch m.btnIdx {
+	switch m.cur().btnIdx {
 	case noInstance:
 	case defaultInstance:
 		defaultBtn = activeStyle.Render(defaultBtn)
@@ -390,7 +861,7 @@ func (m sendModel) renderSelectedInstanceHeader() string {
 		Foreground(highlightColor).
 		Width(smallContainerW() - 4)
 	var s string
-	switch m.btnIdx {
+	switch m.cur().btnIdx {
 	case noInstance:
 	case defaultInstance:
 		s = "DEFAULT SERVER INSTANCE"
@@ -402,13 +873,15 @@ func (m sendModel) renderSelectedInstanceHeader() string {
 	return style.Render(s)
 }
 
-func (m *sendModel) confirmEsc() tea.Cmd {
+func (m sendModel) confirmEsc(sh shareSession) tea.Cmd {
 	selBtn := positive
 	header := "CANCEL SHARING?"
 	body := "This will delete all the processed files, and you'll return to file selection."
+	if len(m.shares) > 1 {
+		body = "This will delete all the processed files of this share, the other shares keep running."
+	}
 	positiveFunc := func() tea.Cmd {
-		m.isSelected, m.isServing = false, false
-		return tea.Batch(msgToCmd(localChildSwitchMsg{child: dirNav, focus: true}), m.deleteTempFiles())
+		return msgToCmd(shareCanceledMsg{sh.id})
 	}
 	return msgToCmd(alertDialogMsg{
 		header:         header,
@@ -420,25 +893,18 @@ func (m *sendModel) confirmEsc() tea.Cmd {
 	})
 }
 
-func (m sendModel) showShutdownReqWhenNotIdleAlert(reqBy string) tea.Cmd {
-	body := fmt.Sprintf("%q requested shutdown, but the server is currently serving files. Consider shutting down when idle.", reqBy)
-	return msgToCmd(alertDialogMsg{
-		header:        "SHUTDOWN REQUEST",
-		body:          body,
-		alertDuration: 10 * time.Second,
+// isOwnInstance reports whether one of the shares is already published as the instance.
+func (m sendModel) isOwnInstance(instance string) bool {
+	return slices.ContainsFunc(m.shares, func(sh shareSession) bool {
+		return (sh.isSelected || sh.isServing || sh.isShutdown) && sh.instance() == instance
 	})
 }
 
-func (m sendModel) getInstance() string {
-	cfg := m.getConfig()
-	switch m.selected {
-	case defaultInstance:
-		return mdns.DefaultInstance
-	case customInstance:
-		return cfg.Share.InstanceName
-	default:
-		return ""
-	}
+func (m sendModel) showOwnInstanceAlert(instance string) tea.Cmd {
+	return msgToCmd(errMsg{
+		errHeader: "INSTANCE ALREADY IN USE!",
+		errStr:    fmt.Sprintf("Another of your shares is published as %q, pick the other instance, or rename the custom one with “ctrl+p”.", instance),
+	})
 }
 
 func (m sendModel) isInstanceAvailable(instance string) bool {
@@ -450,59 +916,219 @@ func (m sendModel) getInstanceOwner(instance string) string {
 	return m.mdns.Entries()[instance].Owner
 }
 
-func (m sendModel) notifyInstanceAvailability() tea.Cmd {
-	instance := m.getInstance()
+func (m sendModel) notifyInstanceAvailability(sh shareSession) tea.Cmd {
+	instance, srv := sh.instance(), sh.server
 	return func() tea.Msg {
 		for {
 			select {
-			case <-m.server.StopCtx.Done():
+			case <-srv.StopCtx.Done():
 				return nil // server stopped, exit the loop
 			default:
 				<-m.mdns.NotifyOnChange()
 				if m.isInstanceAvailable(instance) {
-					return available
+					return instanceStateMsg{sh.id, available}
 				}
 			}
 		}
 	}
 }
 
-func (m sendModel) notifyForShutdownReqWhenNotIdle() tea.Cmd {
+// waitForStopReq waits for the next request of another user to shut down the server.
+func (m sendModel) waitForStopReq(sh shareSession) tea.Cmd {
+	srv := sh.server
 	return func() tea.Msg {
-		ch := make(chan string, 1) // server writes only once then closes the channel
-		m.server.NotifyForShutdownReqWhenNotIdle(ch)
 		select {
-		case reqBy := <-ch:
-			return shutdownReqWhenNotIdleMsg(reqBy)
-		case <-m.server.StopCtx.Done():
+		case req := <-srv.StopRequests():
+			return stopReqMsg{sh.id, req}
+		case <-srv.StopCtx.Done():
+			return nil
+		}
+	}
+}
+
+// askStop asks the host to shut down the server on behalf of the requester, dismissing the dialog declines,
+// the requester waits for the decision, if the host takes too long, the server declines on its own.
+func (m sendModel) askStop(sh shareSession, req server.StopReq) tea.Cmd {
+	reqBy := fmt.Sprintf("%q", req.ReqBy)
+	if req.ReqBy != req.IP {
+		reqBy = fmt.Sprintf("%q (%s)", req.ReqBy, req.IP)
+	}
+	body := fmt.Sprintf("%s wants to use “%s”, shut down your server instance?", reqBy, sh.instance())
+	if req.ActiveDowns > 0 {
+		body = fmt.Sprintf("%s %d active download(s) will abruptly halt.", body, req.ActiveDowns)
+	}
+	alert := msgToCmd(alertDialogMsg{
+		header:         "SHUTDOWN REQUEST",
+		body:           body,
+		positiveBtnTxt: "SHUTDOWN",
+		negativeBtnTxt: "DECLINE",
+		cursor:         negative,
+		positiveFunc: func() tea.Cmd {
+			req.Allow()
+			return nil
+		},
+		negativeFunc: func() tea.Cmd {
+			req.Deny()
+			return nil
+		},
+		escFunc: func() tea.Cmd {
+			req.Deny()
+			return nil
+		},
+	})
+	// decided by the host, or timed out on the server, either way, ask the next one
+	srv := sh.server
+	decided := func() tea.Msg {
+		select {
+		case <-req.Done():
+			return stopReqDecidedMsg{sh.id}
+		case <-srv.StopCtx.Done():
+			return nil
 		}
+	}
+	return tea.Batch(alert, decided)
+}
+
+// waitForApprovalReq waits for the next client to approve, if the server asks before sharing.
+func (m sendModel) waitForApprovalReq(sh shareSession) tea.Cmd {
+	srv := sh.server
+	ch := srv.Approvals()
+	if ch == nil {
 		return nil
 	}
+	return func() tea.Msg {
+		select {
+		case req := <-ch:
+			return approvalReqMsg{sh.id, req}
+		case <-srv.StopCtx.Done():
+			return nil
+		}
+	}
+}
+
+// askApproval asks the host to allow or deny the client, allowing once only lets the requests held through,
+// so the host is asked again on the next one, the other decisions are remembered for the rest of the session,
+// dismissing the dialog only denies the requests held.
+func (m sendModel) askApproval(sh shareSession, req server.ApprovalReq) tea.Cmd {
+	body := fmt.Sprintf("%q wants to access your shared files, allow them once or for this session?", req.ReqBy)
+	if req.ReqBy != req.IP {
+		body = fmt.Sprintf("%q (%s) wants to access your shared files, allow them once or for this session?", req.ReqBy, req.IP)
+	}
+	if len(m.shares) > 1 {
+		body = fmt.Sprintf("%s (shared as “%s”)", body, sh.instance())
+	}
+	alert := msgToCmd(alertDialogMsg{
+		header:         "ALLOW ACCESS?",
+		body:           body,
+		positiveBtnTxt: "ALWAYS ALLOW",
+		neutralBtnTxt:  "ALLOW ONCE",
+		negativeBtnTxt: "DENY",
+		cursor:         negative,
+		positiveFunc: func() tea.Cmd {
+			req.Allow(true)
+			return nil
+		},
+		neutralFunc: func() tea.Cmd {
+			req.Allow(false)
+			return nil
+		},
+		negativeFunc: func() tea.Cmd {
+			req.Deny(true)
+			return nil
+		},
+		escFunc: func() tea.Cmd {
+			req.Deny(false)
+			return nil
+		},
+	})
+	// decided by the host, or timed out on the server, either way, ask the next one
+	srv := sh.server
+	decided := func() tea.Msg {
+		select {
+		case <-req.Done():
+			return approvalDecidedMsg{sh.id}
+		case <-srv.StopCtx.Done():
+			return nil
+		}
+	}
+	return tea.Batch(alert, decided)
+}
+
+// newServer creates the server of the share as per the current share preferences,
+// and hands its log channels over to the extSendModel.
+func (m sendModel) newServer(sh *shareSession) tea.Cmd {
+	cfg := m.getConfig().Share
+	cfg.StoppableInstance = sh.stoppable
+	sh.secret = cfg.Secret
+	sh.limits = describeLimits(cfg)
+	lch, dch := make(chan server.Log, 20), make(chan int, 20)
+	sh.server = server.New(cfg, lch, dch)
+	sh.server.SetInstance(sh.instance())
+	return msgToCmd(handleExtSendCh{sh.id, sh.instance(), lch, dch, sh.server})
+}
+
+// describeLimits describes the expiry & download limits of the share, empty if it has none.
+func describeLimits(cfg config.ShareConfig) string {
+	var limits []string
+	if cfg.ExpiryMinutes > 0 {
+		expiry := time.Duration(cfg.ExpiryMinutes) * time.Minute
+		limits = append(limits, fmt.Sprintf("expires at %s", time.Now().Add(expiry).Format(time.Kitchen)))
+	}
+	if cfg.MaxDownloads > 0 {
+		limits = append(limits, fmt.Sprintf("each file can be downloaded %d time(s)", cfg.MaxDownloads))
+	}
+	if len(limits) == 0 {
+		return ""
+	}
+	return "The share " + strings.Join(limits, " & ") + ", then the server shuts down on its own"
 }
 
-func (m sendModel) publishInstanceAndStartServer() tea.Cmd {
-	instance := m.getInstance()
+func (m sendModel) publishInstanceAndStartServer(sh shareSession) tea.Cmd {
+	instance := sh.instance()
 	if !m.isInstanceAvailable(instance) {
-		return tea.Batch(instanceStateCmd(requesting), m.stopOwnedServerInstance(instance))
+		return tea.Batch(instanceStateCmd(sh.id, requesting), m.stopOwnedServerInstance(sh.id, instance))
 	}
 
-	var cmds [4]tea.Cmd
+	var cmds [5]tea.Cmd
 
 	// publish the mdns service
 	cmds[0] = func() tea.Msg {
-		var err error
 		uname := m.getConfig().Personal.Username
 
+		// the certificate is loaded (or generated) once, the server then reuses it
+		fingerprint, err := sh.server.Fingerprint()
+		if err != nil {
+			return serverStartupErrMsg{sh.id, errMsg{
+				errHeader: "TLS CERTIFICATE FAILED!",
+				errStr:    unwrapErr(err).Error(),
+			}}
+		}
+
+		ifaces, err := sh.server.Interfaces()
+		if err != nil {
+			return serverStartupErrMsg{sh.id, errMsg{
+				errHeader: "NETWORK INTERFACE UNAVAILABLE!",
+				errStr:    unwrapErr(err).Error(),
+			}}
+		}
+
+		// the port is only known once the server listens, it may have fallen back from the preferred one
+		select {
+		case <-sh.server.Listening():
+		case <-sh.server.StopCtx.Done():
+			return nil // failed to start, the server reports it
+		}
+
 		bgtask.Get().RunAndBlock(func(_ context.Context) {
 			hostname := fmt.Sprintf("%s.%s", instance, mdns.Domain)
-			err = m.mdns.Publish(m.server.StopCtx, instance, hostname, uname, uint16(server.GetPort()))
+			err = m.mdns.Publish(sh.server.StopCtx, instance, hostname, uname, fingerprint, uint16(sh.server.Port()), ifaces)
 		})
 
 		if err != nil && !errors.Is(err, context.Canceled) {
-			return serverStartupErrMsg(errMsg{
+			return serverStartupErrMsg{sh.id, errMsg{
 				errHeader: "INSTANCE PUBLISHING FAILED!",
 				errStr:    unwrapErr(err).Error(),
-			})
+			}}
 		}
 		return nil
 	}
@@ -511,7 +1137,7 @@ func (m sendModel) publishInstanceAndStartServer() tea.Cmd {
 		var err error
 
 		bgtask.Get().RunAndBlock(func(_ context.Context) {
-			err = m.server.StartServer(m.files...)
+			err = sh.server.StartServer(sh.files...)
 		})
 
 		if err != nil {
@@ -528,30 +1154,31 @@ func (m sendModel) publishInstanceAndStartServer() tea.Cmd {
 					),
 				}
 			}
-			return serverStartupErrMsg(errMsg{
+			return serverStartupErrMsg{sh.id, errMsg{
 				errHeader: "SERVER STARTUP FAILED!",
 				errStr:    unwrapErr(err).Error(),
-			})
+			}}
 		}
-		return instanceShutdownMsg{}
+		return instanceShutdownMsg{sh.id}
 	}
 
-	cmds[2] = m.notifyForShutdownReqWhenNotIdle()
-	cmds[3] = msgToCmd(instanceServingMsg{})
+	cmds[2] = m.waitForStopReq(sh)
+	cmds[3] = msgToCmd(instanceServingMsg{sh.id})
+	cmds[4] = m.waitForApprovalReq(sh)
 	return tea.Batch(cmds[:]...)
 }
 
-func (m *sendModel) shutdownServer(quitting bool) tea.Cmd {
+func (m sendModel) shutdownServer(sh shareSession, quitting bool) tea.Cmd {
 	return func() tea.Msg {
 		if quitting {
-			m.server.ShutdownServer()
-			return instanceShutdownMsg{}
+			sh.server.ShutdownServer()
+			return instanceShutdownMsg{sh.id}
 		}
 
-		if m.server.ActiveDowns > 0 {
+		if sh.server.ActiveDowns > 0 {
 			p := func() tea.Cmd {
-				m.server.ShutdownServer()
-				return msgToCmd(instanceShutdownMsg{})
+				sh.server.ShutdownServer()
+				return msgToCmd(instanceShutdownMsg{sh.id})
 			}
 			return alertDialogMsg{
 				header:         "FORCED SHUTDOWN!",
@@ -563,26 +1190,53 @@ func (m *sendModel) shutdownServer(quitting bool) tea.Cmd {
 			}
 		}
 
-		m.server.ShutdownServer()
-		return instanceShutdownMsg{}
+		sh.server.ShutdownServer()
+		return instanceShutdownMsg{sh.id}
+	}
+}
+
+// shutdownServers shuts down the servers of all the shares, as the app is quitting.
+func (m sendModel) shutdownServers() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, sh := range m.shares {
+		if sh.server != nil {
+			cmds = append(cmds, m.shutdownServer(sh, true))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// isServing reports whether any of the shares is serving.
+func (m sendModel) isServing() bool {
+	return slices.ContainsFunc(m.shares, func(sh shareSession) bool { return sh.isServing })
+}
+
+// activeDowns returns the downloads in progress, of all the shares.
+func (m sendModel) activeDowns() int {
+	var n int
+	for _, sh := range m.shares {
+		if sh.server != nil {
+			n += sh.server.ActiveDowns
+		}
 	}
+	return n
 }
 
-func (m *sendModel) stopOwnedServerInstance(instance string) tea.Cmd {
+func (m sendModel) stopOwnedServerInstance(id int, instance string) tea.Cmd {
 	return func() tea.Msg {
 		statusCode, err := m.client.StopServer(instance)
 		if err != nil {
-			return tea.Batch(instanceStateCmd(idle), msgToCmd(errMsg{err: err}))
+			return tea.Batch(instanceStateCmd(id, idle), msgToCmd(errMsg{err: err}))
 		}
 		switch statusCode {
 		case http.StatusAccepted:
-			return requestAccepted
+			return instanceStateMsg{id, requestAccepted}
 		case http.StatusForbidden:
-			return requestRejected
+			return instanceStateMsg{id, requestRejected}
 		case http.StatusConflict:
-			return serving
+			return instanceStateMsg{id, serving}
 		case http.StatusRequestTimeout:
-			return notResponding
+			return instanceStateMsg{id, notResponding}
 		default:
 			return nil
 		}
@@ -597,9 +1251,19 @@ func (m sendModel) getConfig() config.Config {
 	return cfg
 }
 
+// deleteTempFiles deletes the processed files of all the shares.
 func (m *sendModel) deleteTempFiles() tea.Cmd {
-	c := slices.Clone(m.files)
-	m.files = nil // clear the files slice to avoid deleting them again
+	var files []string
+	for i := range m.shares {
+		files = append(files, m.shares[i].files...)
+		m.shares[i].files = nil // clear the files slice to avoid deleting them again
+	}
+	return removeTempFiles(files)
+}
+
+// removeTempFiles deletes the files processed into the temp directory, e.g. the zipped ones.
+func removeTempFiles(files []string) tea.Cmd {
+	c := slices.Clone(files)
 	return func() tea.Msg {
 		for _, p := range c {
 			if strings.HasPrefix(p, os.TempDir()) {
@@ -611,7 +1275,7 @@ func (m *sendModel) deleteTempFiles() tea.Cmd {
 }
 
 func (m sendModel) grantExtSpaceSwitch() bool {
-	return m.isServing || m.isShutdown
+	return m.cur().isServing || m.cur().isShutdown
 }
 
 func customSendHelp(show bool) *lipTable.Table {
@@ -624,6 +1288,13 @@ func customSendHelp(show bool) *lipTable.Table {
 			{"←/→ OR l/h", "switch button"},
 			{"enter", "select button"},
 			{"Q/q", "shutdown server"},
+			{"a", "add more files"},
+			{"n", "new share"},
+			{"[/]", "switch share"},
+			{"m", "message receivers"},
+			{"t", "share text"},
+			{"s", "toggle stoppable"},
+			{"k", "enter stop token"},
 			{"esc", "cancel sharing"},
 			{"ctrl+r", "reload MDNS publisher"},
 			{"?", "hide help"},
@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"github.com/MuhamedUsman/letshare/internal/config"
+	"github.com/MuhamedUsman/letshare/internal/server"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSendModel_handleSnippetInput(t *testing.T) {
+	logCh := make(chan server.Log, 10)
+	srv := server.New(config.ShareConfig{}, logCh, make(chan int, 10))
+	defer srv.StopCtxCancel()
+
+	m := sendModel{snippetInput: newSnippetInput(), shares: []shareSession{{isServing: true, server: srv}}}
+	for _, tc := range []struct {
+		name string
+		keys []tea.KeyMsg
+		want string
+	}{
+		{
+			name: "typed",
+			keys: []tea.KeyMsg{
+				{Type: tea.KeyRunes, Runes: []rune("ssid: lab-5G")},
+				{Type: tea.KeyEnter, Alt: true},
+				{Type: tea.KeyRunes, Runes: []rune("password: hunter2")},
+			},
+			want: "ssid: lab-5G\npassword: hunter2",
+		},
+		{
+			name: "pasted",
+			keys: []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("git clone\n  --depth 1"), Paste: true}},
+			want: "git clone\n  --depth 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m.snippetInput.Focus()
+			for _, k := range tc.keys {
+				_ = m.handleSnippetInput(k)
+			}
+			require.Equal(t, tc.want, m.snippetInput.Value())
+			_ = m.handleSnippetInput(tea.KeyMsg{Type: tea.KeyEnter})
+			assert.False(t, m.snippetInput.Focused(), "enter must share the text")
+			require.Len(t, logCh, 1, "the text must be shared")
+			<-logCh
+
+			// the server returns the same id for the same text, without sharing it again
+			_, err := srv.AddSnippet(tc.want)
+			require.NoError(t, err)
+			assert.Empty(t, logCh, "the text must be shared with its new lines")
+		})
+	}
+	assert.Equal(t, 2, m.cur().snippets)
+}
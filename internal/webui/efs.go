@@ -4,5 +4,5 @@ import (
 	"embed"
 )
 
-//go:embed "index.tmpl.html" "static"
+//go:embed "*.tmpl.html" "static"
 var Files embed.FS
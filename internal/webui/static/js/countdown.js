@@ -0,0 +1,25 @@
+// Progressive enhancement for the share expiry, without it the expiry time is shown as is,
+// this counts down to it & reloads the page once the share expires.
+document.addEventListener("DOMContentLoaded", () => {
+  const el = document.getElementById("share-expiry");
+  if (!el) return;
+
+  const expiresAt = Date.parse(el.dataset.expiresAt);
+  if (Number.isNaN(expiresAt)) return;
+
+  const pad = (n) => String(n).padStart(2, "0");
+  const tick = () => {
+    const left = Math.max(0, Math.floor((expiresAt - Date.now()) / 1000));
+    const h = Math.floor(left / 3600);
+    const m = Math.floor((left % 3600) / 60);
+    const s = left % 60;
+    el.textContent = h > 0 ? `Expires in ${h}:${pad(m)}:${pad(s)}` : `Expires in ${pad(m)}:${pad(s)}`;
+    if (left === 0) {
+      clearInterval(timer);
+      el.textContent = "Expired";
+      setTimeout(() => location.reload(), 2000);
+    }
+  };
+  const timer = setInterval(tick, 1000);
+  tick();
+});
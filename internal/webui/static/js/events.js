@@ -0,0 +1,37 @@
+// Live updates from the host, without it the page only changes on reload,
+// this subscribes to the share's events & tells the user once the files change,
+// the host sends a message or stops sharing.
+document.addEventListener("DOMContentLoaded", () => {
+  const notice = document.getElementById("share-notice");
+  const text = document.getElementById("share-notice-text");
+  const refresh = document.getElementById("share-notice-refresh");
+  if (!notice || !window.EventSource) return;
+
+  let stale = false;
+  const show = (msg) => {
+    text.textContent = msg;
+    refresh.hidden = !stale;
+    notice.hidden = false;
+  };
+  const data = (ev) => {
+    try {
+      return JSON.parse(ev.data);
+    } catch {
+      return {};
+    }
+  };
+
+  const events = new EventSource("/events");
+  events.addEventListener("index", () => {
+    stale = true;
+    show("The host changed the shared files.");
+  });
+  events.addEventListener("message", (ev) => {
+    show(`${notice.dataset.host}: ${data(ev).message}`);
+  });
+  events.addEventListener("shutdown", (ev) => {
+    events.close(); // don't reconnect, the share is gone
+    stale = false;
+    show(data(ev).message || "The host stopped sharing.");
+  });
+});
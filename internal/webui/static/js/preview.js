@@ -0,0 +1,90 @@
+// Inline previews, without it the preview links are plain downloads,
+// images & media are loaded inline from the preview URLs, once loaded whole they count as downloads,
+// audio & video seek through Range requests, only an excerpt of the text is fetched, up to textLimit,
+// so a huge log doesn't hang the page, & it is not a download.
+document.addEventListener("DOMContentLoaded", () => {
+  const dialog = document.getElementById("preview");
+  const title = document.getElementById("preview-title");
+  const body = document.getElementById("preview-body");
+  const close = document.getElementById("preview-close");
+  if (!dialog || !dialog.showModal) return;
+
+  const textLimit = 64 * 1024;
+
+  // thumbnails of images that can't be decoded are dropped, instead of showing a broken image
+  document.querySelectorAll(".file-thumb").forEach((img) => {
+    img.addEventListener("error", () => img.remove());
+  });
+
+  const note = (msg) => {
+    const p = document.createElement("p");
+    p.className = "preview-note";
+    p.textContent = msg;
+    body.append(p);
+  };
+
+  const showText = async (url) => {
+    const pre = document.createElement("pre");
+    pre.className = "preview-text";
+    pre.textContent = "Loading…";
+    body.append(pre);
+    try {
+      const resp = await fetch(url, { headers: { Range: `bytes=0-${textLimit - 1}` } });
+      if (resp.status === 416) { // nothing to range over
+        pre.textContent = "";
+        note("The file is empty.");
+        return;
+      }
+      if (!resp.ok) throw new Error(resp.statusText);
+      pre.textContent = await resp.text();
+      const total = Number((resp.headers.get("Content-Range") || "").split("/")[1]);
+      if (resp.status === 206 && total > textLimit) {
+        note(`Showing the first ${textLimit / 1024}KB, download the file to see all of it.`);
+      }
+    } catch {
+      pre.remove();
+      note("The preview could not be loaded.");
+    }
+  };
+
+  const open = (link) => {
+    const url = link.dataset.src;
+    const kind = link.dataset.preview;
+    title.textContent = link.dataset.name;
+    body.replaceChildren();
+    if (kind === "text") {
+      showText(url);
+    } else {
+      const el = document.createElement(kind === "image" ? "img" : kind);
+      el.className = "preview-media";
+      el.src = url;
+      if (kind === "image") {
+        el.alt = link.dataset.name;
+      } else {
+        el.controls = true;
+        el.preload = "metadata";
+      }
+      el.addEventListener("error", () => {
+        el.remove();
+        note("This file can't be previewed in your browser, download it instead.");
+      });
+      body.append(el);
+    }
+    dialog.showModal();
+  };
+
+  document.querySelectorAll(".file-preview").forEach((link) => {
+    link.addEventListener("click", (ev) => {
+      ev.preventDefault();
+      open(link);
+    });
+  });
+
+  close.addEventListener("click", () => dialog.close());
+  // clicking the backdrop closes it as well
+  dialog.addEventListener("click", (ev) => {
+    if (ev.target === dialog) dialog.close();
+  });
+  // stops the media & drops the text, once closed
+  dialog.addEventListener("close", () => body.replaceChildren());
+});
@@ -0,0 +1,37 @@
+// Copy buttons of the text snippets, without it the snippets can still be selected or saved,
+// the clipboard API needs a secure context, so over plain HTTP the text is copied through a selection.
+document.addEventListener("DOMContentLoaded", () => {
+  const copyBySelection = (el) => {
+    const range = document.createRange();
+    range.selectNodeContents(el);
+    const sel = window.getSelection();
+    sel.removeAllRanges();
+    sel.addRange(range);
+    try {
+      return document.execCommand("copy");
+    } finally {
+      sel.removeAllRanges();
+    }
+  };
+
+  const copy = async (el) => {
+    if (navigator.clipboard && window.isSecureContext) {
+      try {
+        await navigator.clipboard.writeText(el.textContent);
+        return true;
+      } catch {
+        // denied, fall back to the selection
+      }
+    }
+    return copyBySelection(el);
+  };
+
+  document.querySelectorAll(".snippet-copy").forEach((btn) => {
+    const text = btn.closest(".file-item").querySelector(".snippet-text");
+    btn.hidden = false;
+    btn.addEventListener("click", async () => {
+      btn.textContent = (await copy(text)) ? "Copied" : "Select & copy it";
+      setTimeout(() => (btn.textContent = "Copy"), 2000);
+    });
+  });
+});
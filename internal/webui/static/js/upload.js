@@ -0,0 +1,59 @@
+// Progressive enhancement for the upload form, the form works without it,
+// this only reports the upload progress & keeps the user on the same page.
+document.addEventListener("DOMContentLoaded", () => {
+  const form = document.getElementById("upload-form");
+  const input = document.getElementById("upload-input");
+  const label = document.getElementById("upload-label");
+  const status = document.getElementById("upload-status");
+  if (!form || !input) return;
+
+  const initialLabel = label.textContent;
+
+  input.addEventListener("change", () => {
+    const n = input.files.length;
+    if (n === 0) label.textContent = initialLabel;
+    else if (n === 1) label.textContent = input.files[0].name;
+    else label.textContent = `${n} files selected`;
+    status.textContent = "";
+  });
+
+  form.addEventListener("submit", (e) => {
+    e.preventDefault();
+    if (input.files.length === 0) return;
+
+    const xhr = new XMLHttpRequest();
+    xhr.open("POST", form.action);
+    xhr.setRequestHeader("Accept", "application/json");
+
+    xhr.upload.addEventListener("progress", (ev) => {
+      if (ev.lengthComputable) {
+        status.textContent = `${Math.floor((ev.loaded / ev.total) * 100)}%`;
+      }
+    });
+
+    xhr.addEventListener("load", () => {
+      if (xhr.status === 201) {
+        status.textContent = "Uploaded!";
+        form.reset();
+        label.textContent = initialLabel;
+        return;
+      }
+      let msg = "Upload failed";
+      try {
+        msg = JSON.parse(xhr.responseText).errors || msg;
+      } catch (_) {}
+      status.textContent = msg;
+    });
+
+    xhr.addEventListener("error", () => {
+      status.textContent = "Upload failed, connection lost";
+    });
+
+    form.querySelector("button").disabled = true;
+    xhr.addEventListener("loadend", () => {
+      form.querySelector("button").disabled = false;
+    });
+    status.textContent = "0%";
+    xhr.send(new FormData(form));
+  });
+});
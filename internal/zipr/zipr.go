@@ -3,12 +3,15 @@ package zipr
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/MuhamedUsman/letshare/internal/bgtask"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -203,6 +206,50 @@ func (z *Zipr) CreateArchive(path, archiveName, root string, files ...string) (s
 	return archivePath, nil
 }
 
+// Entry is a file or a directory to be streamed into an archive, see Zipr.WriteArchive.
+type Entry struct {
+	// FS the entry is read from
+	FS fs.FS
+	// Path is the slash separated path of the entry in FS, "." for the whole FS
+	Path string
+	// Name is the slash separated name of the entry inside the archive,
+	// files beneath a directory entry are placed under it
+	Name string
+}
+
+// WriteArchive streams a zip archive of the entries to w, nothing is written to disk,
+// so the first bytes reach w as soon as the first file is read.
+//
+// Unlike CreateArchive, the total size is not reported on progressCh,
+// as it would take walking the entries twice, only the bytes processed are.
+//
+// Parameters:
+//   - w: The writer the archive is streamed to, e.g. an http.ResponseWriter
+//   - entries: The files/directories to zip, in the order they appear in the archive
+//
+// Returns:
+//   - An error if the operation fails, w may have received a partial archive by then
+//
+// Example:
+//
+//	// Stream a directory and a file to the client
+//	err := zipper.WriteArchive(w,
+//	    zipr.Entry{FS: os.DirFS("/home/user/documents"), Path: ".", Name: "documents"},
+//	    zipr.Entry{FS: os.DirFS("/home/user"), Path: "notes.txt", Name: "notes.txt"},
+//	)
+func (z *Zipr) WriteArchive(w io.Writer, entries ...Entry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		if err := z.writeFS(zw, e); err != nil {
+			return fmt.Errorf("zipping %q: %w", e.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finishing archive: %w", err)
+	}
+	return nil
+}
+
 // Close sends a final progress update and closes the progress channel,
 // and sets the accumulative read bytes to 0.
 //
@@ -278,15 +325,31 @@ func (z *Zipr) writeFile(w *zip.Writer, basePath, filePath string) error {
 		return fmt.Errorf("%q is a directory", filePath)
 	}
 
-	fh, err := zip.FileInfoHeader(info)
-	if err != nil {
-		return fmt.Errorf("creating file header: %w", err)
-	}
 	relativeName, err := filepath.Rel(basePath, filePath)
 	if err != nil {
 		return fmt.Errorf("determining relative path for fileheader name: %w", err)
 	}
-	fh.Name = relativeName
+	return z.copyFile(w, f, info, relativeName)
+}
+
+// copyFile writes the contents of f into the zip archive under name.
+//
+// This is an internal method shared by writeFile and writeFS, progress is tracked through newReader.
+//
+// Parameters:
+//   - w: The zip writer to write to
+//   - f: The opened file to read from
+//   - info: The file info of f, used for the file header
+//   - name: The name of the file inside the archive
+//
+// Returns:
+//   - An error if the operation fails
+func (z *Zipr) copyFile(w *zip.Writer, f io.Reader, info fs.FileInfo, name string) error {
+	fh, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return fmt.Errorf("creating file header: %w", err)
+	}
+	fh.Name = name
 	fh.Method = z.algo
 
 	var ioW io.Writer
@@ -297,11 +360,62 @@ func (z *Zipr) writeFile(w *zip.Writer, basePath, filePath string) error {
 	r := z.newReader(f)
 	buf := make([]byte, 1<<20) // 1MB buffer
 	if _, err = io.CopyBuffer(ioW, r, buf); err != nil {
-		return fmt.Errorf("copying %q to archive: %w", f.Name(), err)
+		return fmt.Errorf("copying %q to archive: %w", name, err)
 	}
 	return nil
 }
 
+// writeFS zips the entry into the archive, if the entry is a directory,
+// every file beneath it is placed under the entry name.
+//
+// This is an internal method used by WriteArchive.
+//
+// Parameters:
+//   - w: The zip writer to write to
+//   - e: The entry to zip
+//
+// Returns:
+//   - An error if the operation fails
+func (z *Zipr) writeFS(w *zip.Writer, e Entry) error {
+	return fs.WalkDir(e.FS, e.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil && p != e.Path && errors.Is(err, fs.ErrPermission) {
+			return nil // skip what we can't read, instead of failing the whole archive
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := e.Name
+		if p != e.Path {
+			rel := p
+			if e.Path != "." {
+				rel = strings.TrimPrefix(p, e.Path+"/")
+			}
+			name = path.Join(e.Name, rel)
+		}
+
+		f, err := e.FS.Open(p)
+		if err != nil {
+			if p != e.Path {
+				return nil // e.g. a symlink escaping the FS, or a file removed meanwhile
+			}
+			return fmt.Errorf("opening file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+
+		// report the file we're about to zip
+		_ = trySend(z.logCh, name)
+
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("statting file: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil // nothing worth archiving, e.g. a device or a socket
+		}
+		return z.copyFile(w, f, info, name)
+	})
+}
+
 // progressReader implements io.Reader and tracks read progress.
 //
 // progressReader wraps an underlying io.Reader and counts bytes read,
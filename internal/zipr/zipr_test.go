@@ -2,13 +2,16 @@ package zipr
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestZipr_CreateArchives(t *testing.T) {
@@ -91,6 +94,45 @@ func TestZipr_CreateArchives(t *testing.T) {
 
 }
 
+func TestZipr_WriteArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/a.txt":        {Data: []byte("a")},
+		"docs/nested/b.txt": {Data: []byte("bb")},
+		"notes.txt":         {Data: []byte("ccc")},
+	}
+	z := New(t.Context(), nil, nil, Deflate)
+
+	buf := new(bytes.Buffer)
+	err := z.WriteArchive(buf,
+		Entry{FS: fsys, Path: "docs", Name: "documents"},
+		Entry{FS: fsys, Path: "notes.txt", Name: "notes.txt"},
+		Entry{FS: fsys, Path: ".", Name: "all"},
+	)
+	assert.NoError(t, err, "writing archive should not return an error")
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err, "reading archive should not return an error")
+
+	contents := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		assert.NoError(t, err, "opening %q should not return an error", f.Name)
+		b, err := io.ReadAll(rc)
+		assert.NoError(t, err, "reading %q should not return an error", f.Name)
+		_ = rc.Close()
+		contents[f.Name] = string(b)
+	}
+	expected := map[string]string{
+		"documents/a.txt":        "a",
+		"documents/nested/b.txt": "bb",
+		"notes.txt":              "ccc",
+		"all/docs/a.txt":         "a",
+		"all/docs/nested/b.txt":  "bb",
+		"all/notes.txt":          "ccc",
+	}
+	assert.Equal(t, expected, contents)
+}
+
 func getExpectedPaths(t *testing.T, dirs []string, parent string) []string {
 	expectedPaths := make([]string, 0, len(dirs))
 	for _, dir := range dirs {
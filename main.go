@@ -7,18 +7,22 @@ import (
 	"fmt"
 	"github.com/MuhamedUsman/letshare/internal/bgtask"
 	"github.com/MuhamedUsman/letshare/internal/mdns"
+	"github.com/MuhamedUsman/letshare/internal/network"
+	"github.com/MuhamedUsman/letshare/internal/server"
 	"github.com/MuhamedUsman/letshare/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/lmittmann/tint"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 )
 
 var (
 	version     = "UNKNOWN"
 	showVersion bool
+	ifaces      string
 )
 
 func init() {
@@ -26,8 +30,10 @@ func init() {
 	h := tint.NewHandler(os.Stderr, &tint.Options{TimeFormat: time.Kitchen})
 	slog.SetDefault(slog.New(h))
 
-	flag.BoolVar(&showVersion, "version", false, "Print the version and exit") // long
-	flag.BoolVar(&showVersion, "v", false, "Print the version and exit")       // short
+	flag.BoolVar(&showVersion, "version", false, "Print the version and exit")                                       // long
+	flag.BoolVar(&showVersion, "v", false, "Print the version and exit")                                             // short
+	flag.StringVar(&ifaces, "iface", "", "Comma separated names or addresses of the network interfaces to share on") // long
+	flag.StringVar(&ifaces, "i", "", "Comma separated names or addresses of the network interfaces to share on")     // short
 	flag.Parse()
 }
 
@@ -38,6 +44,15 @@ func main() {
 			Render("Letshare ", version))
 		return
 	}
+	server.Version = version
+
+	if ifaces != "" {
+		network.Override(strings.Split(ifaces, ",")...)
+		if _, err := network.Select(nil); err != nil {
+			slog.Error("Invalid network interfaces", "iface", ifaces, "err", err)
+			os.Exit(1)
+		}
+	}
 
 	// start the discovery of mDNS services on startup
 	bgtask.Get().Run(func(shutdownCtx context.Context) {